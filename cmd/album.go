@@ -0,0 +1,366 @@
+// cmd/album.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fuzzyfinder "github.com/koki-develop/go-fzf"
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/album"
+	"ytpl/internal/player"
+	"ytpl/internal/state"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+// Orders 'album number' can number the tracks of an album in.
+const (
+	albumOrderCurrent = "current" // The album's order
+	albumOrderTitle   = "title"   // Track titles, for titles starting with their number
+	albumOrderNumber  = "number"  // Track numbers already in the library
+)
+
+// albumPlaylistPrefix starts the name shown for an album being played.
+const albumPlaylistPrefix = "album: "
+
+var (
+	albumAddArtist string
+	albumAddYear   int
+	albumNumberBy  string
+)
+
+// pickAlbumTracks shows stocked tracks in fzf and returns the indices of the chosen ones.
+// Several tracks can be chosen with tab.
+// It is a variable so that tests can pick tracks without a terminal.
+var pickAlbumTracks = func(entries []findEntry) ([]int, error) {
+	f, err := fuzzyfinder.New(
+		fuzzyfinder.WithPrompt("[ album ] > "),
+		fuzzyfinder.WithNoLimit(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize fzf: %w", err)
+	}
+	return f.Find(entries, func(i int) string { return formatFindEntry(entries[i]) })
+}
+
+// albumCmd is the parent command for managing and playing albums.
+var albumCmd = &cobra.Command{
+	Use:   "album",
+	Short: "Manage and play albums",
+}
+
+// albumLsCmd lists the albums in the library.
+var albumLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List albums",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, albums := loadAlbums()
+		if len(albums) == 0 {
+			fmt.Print("\n- no albums. add one with 'ytpl album add <album> [query]' or 'ytpl search --music'.\n\n")
+			return
+		}
+
+		sort.Slice(albums, func(i, j int) bool {
+			return strings.ToLower(albums[i].Title) < strings.ToLower(albums[j].Title)
+		})
+		fmt.Println()
+		for i, a := range albums {
+			fmt.Printf("%d. %s\n", i+1, formatAlbumName(a))
+			fmt.Printf("   tracks: %d\n", len(a.Tracks))
+			if a.Cover != "" {
+				fmt.Printf("   cover: %s\n", a.Cover)
+			}
+		}
+		fmt.Println()
+	},
+}
+
+// albumPlayCmd plays an album in track order.
+var albumPlayCmd = &cobra.Command{
+	Use:   "play <album>",
+	Short: "Play an album in track order",
+	Long: `Play an album in track order. Tracks that aren't stocked are downloaded first.
+Albums are never shuffled, not even by a shuffle setting of mpv.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, albums := loadAlbums()
+		a := findAlbum(albums, strings.Join(args, " "))
+		if a == nil {
+			return
+		}
+		playAlbumTracks(a)
+	},
+}
+
+// albumAddCmd adds stocked tracks to an album, creating it if needed.
+var albumAddCmd = &cobra.Command{
+	Use:   "add <album> [query]",
+	Short: "Add stocked tracks to an album",
+	Long: `Add stocked tracks to the end of an album, creating the album if it doesn't exist.
+The tracks matching the query are listed to choose from; choose several with tab, in
+track order. The tracks are numbered by their position on the album.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := ""
+		if len(args) == 2 {
+			query = args[1]
+		}
+		entries := findLocalTracks(query)
+		if len(entries) == 0 {
+			fmt.Print("\n- no stocked tracks match the query.\n\n")
+			return
+		}
+		idxs, err := pickAlbumTracks(entries)
+		if err != nil {
+			if err == fuzzyfinder.ErrAbort {
+				fmt.Print("\n- cancelled.\n\n")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
+			os.Exit(1)
+		}
+		if len(idxs) == 0 {
+			fmt.Print("\n- no track selected.\n\n")
+			return
+		}
+
+		path, albums := loadAlbums()
+		index := album.Find(albums, args[0])
+		if index < 0 {
+			albums = append(albums, &album.Album{Title: strings.TrimSpace(args[0])})
+			index = len(albums) - 1
+		}
+		a := albums[index]
+
+		picked := make([]yt.TrackInfo, len(idxs))
+		trackIDs := make([]string, len(idxs))
+		for i, idx := range idxs {
+			picked[i] = entries[idx].Track
+			trackIDs[i] = entries[idx].Track.ID
+		}
+		fillAlbumDetails(a, picked)
+		if albumAddArtist != "" {
+			a.Artist = albumAddArtist
+		}
+		if albumAddYear > 0 {
+			a.Year = albumAddYear
+		}
+		added := a.Add(trackIDs...)
+
+		if err := album.Save(path, albums); err != nil {
+			log.Fatalf("error saving albums: %v", err)
+		}
+		if err := numberAlbumTracks(a); err != nil {
+			log.Fatalf("error updating track numbers: %v", err)
+		}
+		fmt.Printf("\n- added %d tracks to '%s' (%d tracks).\n\n", added, a.Title, len(a.Tracks))
+	},
+}
+
+// albumNumberCmd numbers the tracks of an album.
+var albumNumberCmd = &cobra.Command{
+	Use:   "number <album>",
+	Short: "Number the tracks of an album",
+	Long: `Number the tracks of an album from 1 and record the numbers in the library.
+With --by title, the tracks are sorted by title first, for titles starting with their number;
+with --by number, by the track numbers already recorded, e.g. by 'ytpl edit'.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, albums := loadAlbums()
+		a := findAlbum(albums, strings.Join(args, " "))
+		if a == nil {
+			return
+		}
+
+		switch albumNumberBy {
+		case albumOrderCurrent:
+		case albumOrderTitle, albumOrderNumber:
+			sortAlbumTracks(a, albumNumberBy)
+		default:
+			log.Fatalf("invalid --by '%s': use one of %s, %s, %s", albumNumberBy, albumOrderCurrent, albumOrderTitle, albumOrderNumber)
+		}
+
+		if err := album.Save(path, albums); err != nil {
+			log.Fatalf("error saving albums: %v", err)
+		}
+		if err := numberAlbumTracks(a); err != nil {
+			log.Fatalf("error updating track numbers: %v", err)
+		}
+		fmt.Printf("\n- numbered %d tracks of '%s'.\n\n", len(a.Tracks), a.Title)
+	},
+}
+
+// albumsPath returns the path of the albums file, kept in the download directory next to the library.
+func albumsPath() string {
+	return filepath.Join(cfg.DownloadDir, ".albums")
+}
+
+// loadAlbums returns the path of the albums file and the albums in it.
+func loadAlbums() (string, []*album.Album) {
+	path := albumsPath()
+	albums, err := album.Load(path)
+	if err != nil {
+		log.Fatalf("error loading albums: %v", err)
+	}
+	return path, albums
+}
+
+// findAlbum returns the album with the given title, printing a message if there is none.
+func findAlbum(albums []*album.Album, title string) *album.Album {
+	index := album.Find(albums, title)
+	if index < 0 {
+		fmt.Printf("\n- album '%s' not found. see 'ytpl album ls'.\n\n", title)
+		return nil
+	}
+	return albums[index]
+}
+
+// formatAlbumName formats an album as "Title - Artist (Year)", leaving out what is unknown.
+func formatAlbumName(a *album.Album) string {
+	name := a.Title
+	if a.Artist != "" {
+		name += " - " + a.Artist
+	}
+	if a.Year > 0 {
+		name += fmt.Sprintf(" (%d)", a.Year)
+	}
+	return name
+}
+
+// fillAlbumDetails sets the artist, year and cover an album doesn't have yet from its tracks.
+func fillAlbumDetails(a *album.Album, albumTracks []yt.TrackInfo) {
+	for _, track := range albumTracks {
+		if a.Artist == "" {
+			a.Artist = strings.TrimSuffix(track.Artist, " - Topic")
+		}
+		if a.Year == 0 {
+			a.Year = track.ReleaseYear
+		}
+		if a.Cover == "" {
+			a.Cover = track.Thumbnail
+		}
+	}
+}
+
+// sortAlbumTracks orders the tracks of an album by title or by the track numbers in the library.
+// Tracks without a track number keep their order after the numbered ones.
+func sortAlbumTracks(a *album.Album, by string) {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		log.Fatalf("error initializing track manager: %v", err)
+	}
+	titles := make(map[string]string)
+	numbers := make(map[string]int)
+	for _, id := range a.Tracks {
+		if track, ok := trackManager.GetTrack(id); ok {
+			titles[id] = strings.ToLower(track.Title)
+			numbers[id] = track.TrackNumber
+		}
+	}
+
+	sort.SliceStable(a.Tracks, func(i, j int) bool {
+		first, second := a.Tracks[i], a.Tracks[j]
+		if by == albumOrderTitle {
+			return titles[first] < titles[second]
+		}
+		if numbers[first] == 0 || numbers[second] == 0 {
+			return numbers[second] == 0 && numbers[first] != 0
+		}
+		return numbers[first] < numbers[second]
+	})
+}
+
+// numberAlbumTracks records the album and the track number of each of its tracks in the library.
+func numberAlbumTracks(a *album.Album) error {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		return err
+	}
+	trackManager.BatchMode(true)
+	for i, id := range a.Tracks {
+		track, ok := trackManager.GetTrack(id)
+		if !ok {
+			continue
+		}
+		updated := *track
+		updated.Album = a.Title
+		updated.TrackNumber = i + 1
+		if updated.Artist == "" {
+			updated.Artist = a.Artist
+		}
+		if err := trackManager.AddTrack(updated); err != nil {
+			return err
+		}
+	}
+	return trackManager.SaveAll()
+}
+
+// playAlbumTracks plays an album in track order, downloading the tracks that aren't stocked first.
+// The playlist is unshuffled in case mpv is configured to shuffle.
+func playAlbumTracks(a *album.Album) {
+	if len(a.Tracks) == 0 {
+		fmt.Printf("\n- album '%s' has no tracks.\n\n", a.Title)
+		return
+	}
+
+	titles := make(map[string]string)
+	trackManager := loadLibrary()
+	var missing []string
+	for _, id := range a.Tracks {
+		if trackManager != nil {
+			if track, ok := trackManager.GetTrack(id); ok {
+				titles[id] = track.Title
+			}
+		}
+		if _, stocked := stockedTrackPath(trackManager, id); !stocked {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		downloadMissingTracks(a.Tracks, titles, nil)
+		trackManager = loadLibrary()
+	}
+
+	var ids, paths []string
+	for _, id := range a.Tracks {
+		if path, stocked := stockedTrackPath(trackManager, id); stocked {
+			ids = append(ids, id)
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Printf("\n- none of the tracks of '%s' could be downloaded.\n\n", a.Title)
+		return
+	}
+
+	if err := player.LoadPlaylistIntoPlayer(cfg, appState, paths, 0); err != nil {
+		log.Fatalf("error loading album into player: %v", err)
+	}
+	// Ignore error: mpv reports one if the playlist wasn't shuffled
+	_ = player.SendCommand(appState, []interface{}{"playlist-unshuffle"})
+
+	appState.CurrentTrackID = ids[0]
+	appState.CurrentTrackTitle = titles[ids[0]]
+	appState.DownloadedFilePath = paths[0]
+	appState.IsPlaying = true
+	appState.CurrentPlaylist = albumPlaylistPrefix + a.Title
+	appState.LastPlayedTrackIndex = 0
+
+	// Ignore error when saving state
+	_ = state.SaveState()
+
+	ShowStatus()
+}
+
+func init() {
+	albumAddCmd.Flags().StringVar(&albumAddArtist, "artist", "", "artist of the album")
+	albumAddCmd.Flags().IntVar(&albumAddYear, "year", 0, "release year of the album")
+	albumNumberCmd.Flags().StringVar(&albumNumberBy, "by", albumOrderCurrent, "order to number the tracks in: current, title or number")
+}
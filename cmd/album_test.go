@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/album"
+	"ytpl/internal/state"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+func TestAlbumCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { albumAddArtist, albumAddYear, albumNumberBy = "", 0, albumOrderCurrent })
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "02 Second", Uploader: "Band", ReleaseYear: 1999, Duration: 1},
+		{ID: "bbbbbbbbbbb", Title: "01 First", Uploader: "Band", Thumbnail: "https://i.ytimg.com/vi/bbbbbbbbbbb/hq.jpg", Duration: 1},
+		{ID: "ccccccccccc", Title: "Unrelated", Uploader: "Someone", Duration: 1},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(downloadDir, track.ID+".mp3"), []byte("audio"), 0644))
+		require.NoError(t, library.AddTrack(track))
+	}
+
+	var shown []findEntry
+	previous := pickAlbumTracks
+	pickAlbumTracks = func(entries []findEntry) ([]int, error) {
+		shown = entries
+		return []int{1, 0}, nil // Picked in the order "02 Second", "01 First"
+	}
+	t.Cleanup(func() { pickAlbumTracks = previous })
+
+	trackNumbers := func(t *testing.T) map[string]int {
+		library := tracks.New(filepath.Join(downloadDir, ".tracks"))
+		require.NoError(t, library.Load())
+		numbers := make(map[string]int)
+		for _, track := range library.List() {
+			numbers[track.ID] = track.TrackNumber
+			if track.TrackNumber > 0 {
+				assert.Equal(t, "Debut", track.Album)
+			}
+		}
+		return numbers
+	}
+
+	t.Run("adds picked tracks and numbers them", func(t *testing.T) {
+		runCommand(t, "album", "add", "Debut", "band", "--artist", "The Band")
+
+		require.Len(t, shown, 2, "only matching tracks are listed")
+		albums, err := album.Load(albumsPath())
+		require.NoError(t, err)
+		require.Len(t, albums, 1)
+		assert.Equal(t, &album.Album{
+			Title:  "Debut",
+			Artist: "The Band",
+			Year:   1999,
+			Tracks: []string{"aaaaaaaaaaa", "bbbbbbbbbbb"},
+			Cover:  "https://i.ytimg.com/vi/bbbbbbbbbbb/hq.jpg",
+		}, albums[0])
+		assert.Equal(t, map[string]int{"aaaaaaaaaaa": 1, "bbbbbbbbbbb": 2, "ccccccccccc": 0}, trackNumbers(t))
+	})
+
+	t.Run("numbers tracks by title", func(t *testing.T) {
+		runCommand(t, "album", "number", "debut", "--by", "title")
+
+		albums, err := album.Load(albumsPath())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bbbbbbbbbbb", "aaaaaaaaaaa"}, albums[0].Tracks)
+		assert.Equal(t, map[string]int{"aaaaaaaaaaa": 2, "bbbbbbbbbbb": 1, "ccccccccccc": 0}, trackNumbers(t))
+	})
+
+	t.Run("plays the album in track order", func(t *testing.T) {
+		runCommand(t, "album", "play", "Debut")
+
+		current := state.GetState()
+		assert.Equal(t, albumPlaylistPrefix+"Debut", current.CurrentPlaylist)
+		assert.Equal(t, "bbbbbbbbbbb", current.CurrentTrackID)
+		assert.Equal(t, filepath.Join(downloadDir, "bbbbbbbbbbb.mp3"), current.DownloadedFilePath)
+	})
+}
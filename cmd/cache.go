@@ -0,0 +1,65 @@
+// cmd/cache.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/config"
+	"ytpl/internal/searchcache"
+	"ytpl/internal/util"
+)
+
+// cacheCmd is the parent command for managing the search result cache.
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the search result cache",
+}
+
+// cacheClearCmd removes all cached search results.
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached search results",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, err := searchCache().Clear()
+		if err != nil {
+			log.Fatalf("error clearing search cache: %v", err)
+		}
+		fmt.Printf("\n- removed %d cached searches.\n\n", removed)
+	},
+}
+
+// cacheStatsCmd shows what the search cache holds.
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the search result cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		stats, err := searchCache().Stats()
+		if err != nil {
+			log.Fatalf("error reading search cache: %v", err)
+		}
+
+		ttl := cfg.SearchCacheTTL
+		if cfg.SearchCacheMaxAge() == 0 {
+			ttl = "disabled"
+		}
+		fmt.Printf("\n- cached searches: %d (%d expired), %s\n", stats.Entries, stats.Expired, util.FormatBytes(float64(stats.Bytes)))
+		fmt.Printf("- cache ttl: %s\n", ttl)
+		if stats.Entries > 0 {
+			fmt.Printf("- oldest: %s, newest: %s\n",
+				stats.Oldest.Local().Format("2006-01-02 15:04"), stats.Newest.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("- location: %s\n", config.GetSearchCacheDir())
+
+		if path, err := config.GetSearchHistoryPath(); err == nil {
+			if history, err := searchcache.LoadHistory(path); err == nil {
+				fmt.Printf("- search history: %d queries\n", len(history))
+			}
+		}
+		fmt.Println()
+	},
+}
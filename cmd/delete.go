@@ -24,6 +24,9 @@ type trackItem struct {
 	DisplayText  string
 }
 
+// delLabelFilter picks the tracks to delete by their tags.
+var delLabelFilter labelFilter
+
 var delCmd = &cobra.Command{
 	Use:   "del [query]",
 	Short: "Delete a downloaded track",
@@ -50,10 +53,13 @@ var delCmd = &cobra.Command{
 
 		var selectableTracks []trackItem
 		for i, track := range trackList {
-			trackPath := filepath.Join(cfg.DownloadDir, track.ID+".mp3")
+			trackPath, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
 
 			// Skip if the file doesn't exist
-			if _, err := os.Stat(trackPath); os.IsNotExist(err) {
+			if !stocked {
+				continue
+			}
+			if !delLabelFilter.matches(&track) {
 				continue
 			}
 
@@ -70,6 +76,11 @@ var delCmd = &cobra.Command{
 			})
 		}
 
+		if len(selectableTracks) == 0 && delLabelFilter.active() {
+			fmt.Print("\n- no local songs found with the given tags.\n\n")
+			return
+		}
+
 		// Show warning message
 		fmt.Println(util.Red("⚠️  warning: this will permanently delete the selected track."))
 		fmt.Println()
@@ -115,7 +126,7 @@ var delCmd = &cobra.Command{
 			os.Exit(1)
 		}
 		if !confirmed {
-			fmt.Println("\n- deletion cancelled\n")
+			fmt.Print("\n- deletion cancelled\n\n")
 			return
 		}
 
@@ -157,10 +168,11 @@ var delCmd = &cobra.Command{
 		}
 
 		// Delete files
+		basePath := filepath.Join(cfg.DownloadDir, yt.FileStem(selected.Info.ID))
 		filesToDelete := []string{
 			selected.Path,
-			strings.TrimSuffix(selected.Path, ".mp3") + ".info.json",
-			strings.TrimSuffix(selected.Path, ".mp3") + ".jpg",
+			basePath + ".info.json",
+			basePath + ".jpg",
 		}
 
 		var deletedFiles []string
@@ -183,3 +195,7 @@ var delCmd = &cobra.Command{
 		}
 	},
 }
+
+func init() {
+	delLabelFilter.addFlags(delCmd)
+}
@@ -0,0 +1,404 @@
+// cmd/download.go
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/config"
+	"ytpl/internal/queue"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var dlCancelAll bool
+
+// The background worker waits longer after each failed run of the queue, and gives up after
+// workerMaxErrors failures in a row. A dead worker is unregistered by the next queue update.
+const (
+	workerMaxErrors  = 5
+	workerRetryDelay = 2 * time.Second
+)
+
+var dlCmd = &cobra.Command{
+	Use:   "dl",
+	Short: "Manage the background download queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		dlLsCmd.Run(dlLsCmd, args)
+	},
+}
+
+var dlAddCmd = &cobra.Command{
+	Use:   "add <url|id|query>",
+	Short: "Queue a track for download in the background",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Every argument is a URL or a track ID, or all arguments form one search query
+		var trackIDs []string
+		for _, arg := range args {
+			id, ok := yt.ParseTrackRef(arg)
+			if !ok {
+				trackIDs = nil
+				break
+			}
+			trackIDs = append(trackIDs, id)
+		}
+
+		titles := make(map[string]string)
+		if trackIDs == nil {
+			query := strings.Join(args, " ")
+			searchSpinner := util.NewSpinnerWithStyle(fmt.Sprintf("searching '%s'...", query), util.StyleLine)
+			results, err := yt.SearchYouTube(cfg, query)
+			searchSpinner.Stop("")
+			if err != nil {
+				exitWithYtError("searching YouTube", err)
+			}
+			if len(results) == 0 || results[0].ID == "" {
+				fmt.Print("\n- no results found.\n\n")
+				return
+			}
+			trackIDs = []string{results[0].ID}
+			titles[results[0].ID] = results[0].Title
+		}
+
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+
+		trackManager := loadLibrary()
+		fmt.Println()
+		for _, id := range trackIDs {
+			title := titles[id]
+			if title == "" {
+				title = id
+			}
+			if _, stocked := stockedTrackPath(trackManager, id); stocked {
+				fmt.Printf("- '%s' is already stocked locally.\n", title)
+				continue
+			}
+			added, err := q.Add(id, titles[id])
+			if err != nil {
+				log.Fatalf("error adding '%s' to the download queue: %v", title, err)
+			}
+			if added {
+				fmt.Printf("- queued '%s' for download.\n", title)
+			} else {
+				fmt.Printf("- '%s' is already in the download queue.\n", title)
+			}
+		}
+		fmt.Println()
+
+		if err := ensureDownloadWorker(q); err != nil {
+			log.Fatalf("error starting download worker: %v", err)
+		}
+	},
+}
+
+var dlLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Show the download queue with progress",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		items, err := q.List()
+		if err != nil {
+			log.Fatalf("error reading download queue: %v", err)
+		}
+
+		if len(items) == 0 {
+			fmt.Print("\n- download queue is empty.\n\n")
+			return
+		}
+
+		fmt.Println()
+		for _, item := range items {
+			fmt.Println(formatQueueItem(item))
+		}
+
+		running, _ := q.WorkerRunning()
+		if running {
+			fmt.Print("\n- background worker is running.\n\n")
+		} else {
+			fmt.Print("\n- background worker is not running.\n\n")
+		}
+	},
+}
+
+var dlRetryCmd = &cobra.Command{
+	Use:   "retry [track_id...]",
+	Short: "Retry failed downloads (or the given failed/cancelled ones)",
+	Run: func(cmd *cobra.Command, args []string) {
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		count, err := q.Retry(args...)
+		if err != nil {
+			log.Fatalf("error retrying downloads: %v", err)
+		}
+		fmt.Printf("\n- queued %d download(s) again.\n\n", count)
+
+		// Also resumes downloads left pending by an interrupted worker
+		if err := ensureDownloadWorker(q); err != nil {
+			log.Fatalf("error starting download worker: %v", err)
+		}
+	},
+}
+
+var dlCancelCmd = &cobra.Command{
+	Use:   "cancel <track_id>... | --all",
+	Short: "Cancel pending or running downloads",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && !dlCancelAll {
+			fmt.Print("\n- specify track IDs to cancel or use --all.\n\n")
+			return
+		}
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		count, err := q.Cancel(args...)
+		if err != nil {
+			log.Fatalf("error cancelling downloads: %v", err)
+		}
+		fmt.Printf("\n- cancelled %d download(s).\n\n", count)
+	},
+}
+
+// dlWorkerCmd processes the download queue in the background until it is empty.
+// It is started by ensureDownloadWorker.
+var dlWorkerCmd = &cobra.Command{
+	Use:    "worker",
+	Short:  "Process the download queue (used internally)",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		registered, err := q.RegisterWorker(os.Getpid())
+		if err != nil || !registered {
+			return // Another worker is already processing the queue
+		}
+
+		pool := newDownloadPool(q, nil)
+		failures := 0
+		for {
+			if err := pool.Run(context.Background()); err != nil {
+				failures++
+				log.Printf("error processing download queue: %v", err)
+				if failures >= workerMaxErrors {
+					log.Printf("giving up after %d errors in a row", failures)
+					return
+				}
+				time.Sleep(time.Duration(failures) * workerRetryDelay)
+			} else {
+				failures = 0
+			}
+			// Keep going if new items were queued in the meantime
+			if done, err := q.UnregisterWorker(os.Getpid()); err != nil || done {
+				return
+			}
+		}
+	},
+}
+
+// downloadQueue opens the persistent download queue.
+func downloadQueue() (*queue.Queue, error) {
+	path, err := config.GetQueuePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue file path: %w", err)
+	}
+	return queue.Open(path), nil
+}
+
+// newDownloadPool returns a worker pool for the download queue.
+func newDownloadPool(q *queue.Queue, onUpdate func(queue.Item)) *queue.Pool {
+	return &queue.Pool{
+		Queue:    q,
+		Workers:  cfg.DownloadConcurrency,
+		Download: downloadQueueItem,
+		OnUpdate: onUpdate,
+	}
+}
+
+// downloadNow queues a track and downloads it in this process, showing its progress in a spinner.
+// If another ytpl process is already downloading the track, it waits for that download instead.
+func downloadNow(trackID, title string) (queue.Item, error) {
+	q, err := downloadQueue()
+	if err != nil {
+		return queue.Item{}, err
+	}
+	if _, err := q.Add(trackID, title); err != nil {
+		return queue.Item{}, fmt.Errorf("failed to add track to the download queue: %w", err)
+	}
+
+	sanitizedTitle := strings.ReplaceAll(title, "\n", " ")
+	// Use line style for download
+	downloadSpinner := util.NewSpinnerWithStyle(
+		fmt.Sprintf("downloading '%s'...", sanitizedTitle),
+		util.StyleLine,
+	)
+
+	var result queue.Item
+	pool := newDownloadPool(q, func(item queue.Item) {
+		if item.Finished() {
+			result = item
+			return
+		}
+		if item.Status == queue.StatusActive && item.Percent >= 0 {
+			downloadSpinner.UpdateMessage(fmt.Sprintf("downloading '%s'... %.1f%%", sanitizedTitle, item.Percent))
+		}
+	})
+	if err := pool.Run(context.Background(), trackID); err != nil {
+		downloadSpinner.StopWithError(fmt.Sprintf("failed to download '%s'", sanitizedTitle))
+		return queue.Item{}, err
+	}
+
+	switch result.Status {
+	case queue.StatusDone:
+		downloadSpinner.Stop("")
+		return result, nil
+	case queue.StatusCancelled:
+		downloadSpinner.StopWithError(fmt.Sprintf("download of '%s' was cancelled", sanitizedTitle))
+		return result, fmt.Errorf("download cancelled")
+	default:
+		downloadSpinner.StopWithError(fmt.Sprintf("failed to download '%s'", sanitizedTitle))
+		return result, result.Err()
+	}
+}
+
+// ensureDownloadWorker starts a background worker for the queue unless one is running.
+func ensureDownloadWorker(q *queue.Queue) error {
+	running, err := q.WorkerRunning()
+	if err != nil || running {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find ytpl executable: %w", err)
+	}
+	worker := exec.Command(executable, "dl", "worker")
+	// Detach the worker so that it keeps running after ytpl exits
+	worker.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true, // Create a new process group
+	}
+	if err := worker.Start(); err != nil {
+		return fmt.Errorf("failed to start download worker: %w", err)
+	}
+	return worker.Process.Release()
+}
+
+// downloadQueueItem downloads a queued track and adds it to the library.
+// Transient yt-dlp failures are retried by the yt package.
+func downloadQueueItem(ctx context.Context, item queue.Item, onProgress func(yt.DownloadProgress)) (string, string, error) {
+	path, info, err := yt.DownloadTrackWithProgress(ctx, cfg, item.TrackID, onProgress)
+	if err != nil {
+		return "", "", err
+	}
+
+	title := ""
+	if info != nil {
+		tidyNewTrack(info)
+		title = info.Title
+		if err := addToLibrary(info); err != nil {
+			log.Printf("warning: failed to add track %s to library: %v", info.ID, err)
+		}
+	}
+	return path, title, nil
+}
+
+// addToLibrary adds a downloaded track to the library.
+// The library is loaded again right before the update, as other ytpl processes may have changed it.
+func addToLibrary(info *yt.TrackInfo) error {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		return err
+	}
+	track := *info
+	track.Description = "" // Only needed for the search preview; keep the library small
+	return trackManager.AddTrack(track)
+}
+
+// localTrackPath returns the path of a stocked track and whether it exists.
+func localTrackPath(trackID string) (string, bool) {
+	return stockedTrackPath(loadLibrary(), trackID)
+}
+
+// loadLibrary loads the library to look up the paths of stocked tracks.
+// A library that can't be loaded is reported and returned as nil, so tracks are still found by ID.
+func loadLibrary() *tracks.Manager {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		log.Printf("warning: failed to load the library: %v", err)
+		return nil
+	}
+	return trackManager
+}
+
+// stockedTrackPath returns the path of a stocked track and whether it exists.
+// The path recorded in the library comes first, as 'rebuild' may have moved or renamed the file.
+func stockedTrackPath(trackManager *tracks.Manager, trackID string) (string, bool) {
+	storedPath := ""
+	if trackManager != nil {
+		if track, found := trackManager.GetTrack(trackID); found {
+			storedPath = track.FilePath
+		}
+	}
+	return yt.ResolveTrackPath(cfg, trackID, storedPath)
+}
+
+// formatQueueItem formats a queue item as a line for 'dl ls'.
+func formatQueueItem(item queue.Item) string {
+	title := item.Title
+	if title == "" {
+		title = "ID: " + item.TrackID
+	}
+
+	switch item.Status {
+	case queue.StatusActive:
+		return fmt.Sprintf("  %-9s %s (%s)", item.Status, formatDownloadProgress(item.Percent, item.Speed, item.ETA, title), item.TrackID)
+	case queue.StatusFailed:
+		return util.Red(fmt.Sprintf("  %-9s %s (%s): %s", item.Status, title, item.TrackID, describeYtError(item.Err())))
+	case queue.StatusDone:
+		return util.Green(fmt.Sprintf("  %-9s %s (%s)", item.Status, title, item.TrackID))
+	default:
+		return fmt.Sprintf("  %-9s %s (%s)", item.Status, title, item.TrackID)
+	}
+}
+
+// formatDownloadProgress formats download progress as "percent speed ETA title".
+func formatDownloadProgress(percent, speed float64, eta int, title string) string {
+	percentStr := "  ?  "
+	if percent >= 0 {
+		percentStr = fmt.Sprintf("%5.1f%%", percent)
+	}
+	speedStr := "--"
+	if speed > 0 {
+		speedStr = util.FormatBytes(speed) + "/s"
+	}
+	etaStr := "--:--"
+	if eta >= 0 {
+		etaStr = util.FormatDuration(float64(eta))
+	}
+	return fmt.Sprintf("%s %11s ETA %5s  %s", percentStr, speedStr, etaStr, title)
+}
+
+func init() {
+	dlCancelCmd.Flags().BoolVar(&dlCancelAll, "all", false, "cancel all unfinished downloads")
+}
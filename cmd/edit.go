@@ -3,13 +3,13 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
-	"ytpl/internal/playertags"
 	"ytpl/internal/tracks"
 	"ytpl/internal/util"
 	"ytpl/internal/yt"
@@ -18,154 +18,384 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// editField is a metadata field the edit form and the edit flags can change.
+type editField struct {
+	name  string // Label in the form and name of the flag
+	usage string // Help of the flag
+	bulk  bool   // Whether the field can be set for several tracks at once
+	get   func(track *yt.TrackInfo) string
+	set   func(track *yt.TrackInfo, value string) error
+}
+
+// editFields are the fields of the edit form, in form order.
+var editFields = []editField{
+	{
+		name: "title", usage: "set the title",
+		get: func(track *yt.TrackInfo) string { return track.Title },
+		set: func(track *yt.TrackInfo, value string) error {
+			if value == "" {
+				return fmt.Errorf("the title can't be empty")
+			}
+			track.Title = value
+			return nil
+		},
+	},
+	{
+		name: "artist", usage: "set the artist", bulk: true,
+		get: func(track *yt.TrackInfo) string { return track.Artist },
+		set: func(track *yt.TrackInfo, value string) error { track.Artist = value; return nil },
+	},
+	{
+		name: "album", usage: "set the album", bulk: true,
+		get: func(track *yt.TrackInfo) string { return track.Album },
+		set: func(track *yt.TrackInfo, value string) error { track.Album = value; return nil },
+	},
+	{
+		name: "year", usage: "set the release year", bulk: true,
+		get: func(track *yt.TrackInfo) string { return formatEditNumber(track.ReleaseYear) },
+		set: func(track *yt.TrackInfo, value string) error {
+			year, err := parseEditNumber(value)
+			if err != nil || year > 9999 {
+				return fmt.Errorf("the year must be a number such as 1999")
+			}
+			track.ReleaseYear = year
+			return nil
+		},
+	},
+	{
+		name: "genre", usage: "set the genre", bulk: true,
+		get: func(track *yt.TrackInfo) string { return track.Genre },
+		set: func(track *yt.TrackInfo, value string) error { track.Genre = value; return nil },
+	},
+	{
+		name: "track-number", usage: "set the track number on the album",
+		get: func(track *yt.TrackInfo) string { return formatEditNumber(track.TrackNumber) },
+		set: func(track *yt.TrackInfo, value string) error {
+			number, err := parseEditNumber(value)
+			if err != nil {
+				return fmt.Errorf("the track number must be a positive number")
+			}
+			track.TrackNumber = number
+			return nil
+		},
+	},
+	{
+		name: "comment", usage: "set a free-text comment", bulk: true,
+		get: func(track *yt.TrackInfo) string { return track.Comment },
+		set: func(track *yt.TrackInfo, value string) error { track.Comment = value; return nil },
+	},
+}
+
+// editClearValue clears a field in the edit form, as an empty answer keeps the current value.
+const editClearValue = "-"
+
+// editFlagValues holds the values of the edit flags, by field name.
+var editFlagValues = make(map[string]*string)
+
+// editLabelFilter picks the tracks to edit by their tags.
+var editLabelFilter labelFilter
+
 var editCmd = &cobra.Command{
-	Use:   "edit [query]",
+	Use:   "edit [query | track IDs...]",
 	Short: "Edit track metadata",
-	Args:  cobra.MaximumNArgs(1), // Optional query for filtering
-	Run: func(cmd *cobra.Command, args []string) {
-		filterQuery := ""
-		if len(args) > 0 {
-			filterQuery = strings.ToLower(args[0])
-		}
+	Long: `Edit the title, artist, album, year, genre, track number and comment of tracks.
+The tracks matching the query are listed to choose from; choose several with tab to set
+their shared fields at once, and use --tag and --not-tag to only list the tracks with or
+without a tag. Each field is asked for in turn: enter keeps the current value and "-" clears it.
+The changes are also written into the tags of the audio files, and cleared fields are removed
+from them.
 
+With field flags, the tracks with the given IDs are edited without asking, e.g.
+  ytpl edit --artist "Artist" --album "Album" dQw4w9WgXcQ
+
+With --bulk, all tracks matching the query are written to a tab-separated file with their ID,
+title, artist, album, year and tags, which is opened in $EDITOR. Invalid rows are reported by
+line number and the file can be edited again; the changes are shown as a diff before applying.`,
+	Run: func(cmd *cobra.Command, args []string) {
 		// Initialize track manager
 		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
 		if err != nil {
 			log.Fatalf("failed to initialize track manager: %v", err)
 		}
 
-		// Get all tracks from the track manager and sort by title
-		trackList := trackManager.ListTracks()
-		if len(trackList) == 0 {
-			log.Fatal("no tracks found in .tracks file. Please run 'rebuild' command first.")
+		// Edit the given tracks without asking if field flags are set
+		changes := make(map[string]string)
+		for _, field := range editFields {
+			if cmd.Flags().Changed(field.name) {
+				changes[field.name] = *editFlagValues[field.name]
+			}
 		}
-
-		sort.Slice(trackList, func(i, j int) bool {
-			return strings.ToLower(trackList[i].Title) < strings.ToLower(trackList[j].Title)
-		})
-
-		// Format tracks for display
-		displayItems := make([]struct {
-			Info         *yt.TrackInfo
-			Path         string
-			Audio        *playertags.AudioInfo
-			DisplayTitle string
-			DisplayText  string
-		}, 0, len(trackList))
-
-		for _, track := range trackList {
-			trackPath := filepath.Join(cfg.DownloadDir, track.ID+".mp3")
-
-			// Check if the file exists
-			if _, err := os.Stat(trackPath); os.IsNotExist(err) {
-				log.Printf("warning: track file not found: %s", trackPath)
-				continue
+		if editBulk {
+			if len(changes) > 0 {
+				log.Fatal("--bulk can't be combined with field flags")
 			}
-
-			// Use track title from .tracks file
-			displayTitle := track.Title
-
-			// Skip if filter query doesn't match
-			if filterQuery != "" {
-				if !strings.Contains(strings.ToLower(displayTitle), strings.ToLower(filterQuery)) &&
-					!strings.Contains(strings.ToLower(track.ID), strings.ToLower(filterQuery)) {
-					continue
-				}
+			bulkEditTracks(trackManager, strings.Join(args, " "), editLabelFilter)
+			return
+		}
+		if len(changes) > 0 {
+			if len(args) == 0 {
+				log.Fatal("no track IDs given to edit")
 			}
-
-			durationStr := strings.Trim(util.FormatDuration(track.Duration), "[]")
-			displayText := fmt.Sprintf("[%s] - %s", durationStr, displayTitle)
-			displayItems = append(displayItems, struct {
-				Info         *yt.TrackInfo
-				Path         string
-				Audio        *playertags.AudioInfo
-				DisplayTitle string
-				DisplayText  string
-			}{
-				Info:         &track,
-				Path:         trackPath,
-				Audio:        nil, // MP3タグは読み取らない
-				DisplayTitle: displayTitle,
-				DisplayText:  displayText,
-			})
-		}
-
-		if len(displayItems) == 0 {
-			if filterQuery != "" {
-				fmt.Printf("\n- no local songs found matching \"%s\".\n", filterQuery)
-			} else {
-				fmt.Println("\n- no local songs found. use 'ytpl search' to download some.\n")
+			var selected []*yt.TrackInfo
+			for _, id := range args {
+				track, ok := trackManager.GetTrack(id)
+				if !ok {
+					log.Fatalf("track not found: %s", id)
+				}
+				selected = append(selected, track)
 			}
+			saveTrackEdits(trackManager, selected, changes)
 			return
 		}
 
-		// Initialize fzf
-		f, err := fuzzyfinder.New(fuzzyfinder.WithPrompt("[ edit ] > "))
+		selected := pickTracksToEdit(trackManager, strings.Join(args, " "), editLabelFilter)
+		if len(selected) == 0 {
+			return
+		}
+		changes, err = editForm(bufio.NewReader(os.Stdin), os.Stdout, selected)
 		if err != nil {
-			log.Fatalf("Error initializing fuzzy finder: %v", err)
+			log.Fatalf("Error reading input: %v", err)
 		}
+		saveTrackEdits(trackManager, selected, changes)
+	},
+}
 
-		// Show track selection
-		idxs, err := f.Find(displayItems, func(i int) string {
-			return displayItems[i].DisplayText
-		})
+// pickTracksToEdit lists the stocked tracks matching the query and the tag filter in fzf and
+// returns the chosen ones. Several tracks can be chosen with tab.
+func pickTracksToEdit(trackManager *tracks.Manager, filterQuery string, labels labelFilter) []*yt.TrackInfo {
+	editable := editableTracks(trackManager, filterQuery, labels)
+	if len(editable) == 0 {
+		return nil
+	}
 
-		if err != nil {
-			if err == fuzzyfinder.ErrAbort {
-				fmt.Println("\n- selection cancelled.\n")
-				return
-			}
-			log.Fatalf("Error selecting track: %v", err)
+	// Format tracks for display
+	var displayTexts []string
+	for _, track := range editable {
+		durationStr := strings.Trim(util.FormatDuration(track.Duration), "[]")
+		displayText := fmt.Sprintf("[%s] - %s", durationStr, track.Title)
+		if track.Artist != "" {
+			displayText += "  " + track.Artist
 		}
+		displayTexts = append(displayTexts, displayText)
+	}
+
+	// Initialize fzf
+	f, err := fuzzyfinder.New(
+		fuzzyfinder.WithPrompt("[ edit ] > "),
+		fuzzyfinder.WithNoLimit(true),
+	)
+	if err != nil {
+		log.Fatalf("Error initializing fuzzy finder: %v", err)
+	}
 
-		if len(idxs) == 0 {
-			log.Fatalf("no track selected")
+	// Show track selection
+	idxs, err := f.Find(editable, func(i int) string {
+		return displayTexts[i]
+	})
+	if err != nil {
+		if err == fuzzyfinder.ErrAbort {
+			fmt.Print("\n- selection cancelled.\n\n")
+			return nil
 		}
+		log.Fatalf("Error selecting track: %v", err)
+	}
 
-		selectedItem := displayItems[idxs[0]]
-		currentTitle := selectedItem.Info.Title
+	if len(idxs) == 0 {
+		log.Fatalf("no track selected")
+	}
 
-		// Show current title and get new title
-		fmt.Println()
-		fmt.Printf("- current: %s\n", currentTitle)
-		fmt.Printf("      new: ")
+	selected := make([]*yt.TrackInfo, len(idxs))
+	for i, idx := range idxs {
+		selected[i] = editable[idx]
+	}
+	return selected
+}
 
-		// Read new title from user input
-		reader := bufio.NewReader(os.Stdin)
-		newTitle, err := reader.ReadString('\n')
-		if err != nil {
-			log.Fatalf("Error reading input: %v", err)
+// editableTracks returns the stocked tracks matching the query and the tag filter, sorted by title.
+// A message is printed if there are none.
+func editableTracks(trackManager *tracks.Manager, filterQuery string, labels labelFilter) []*yt.TrackInfo {
+	filterQuery = strings.ToLower(filterQuery)
+
+	// Get all tracks from the track manager and sort by title
+	trackList := trackManager.ListTracks()
+	if len(trackList) == 0 {
+		log.Fatal("no tracks found in .tracks file. Please run 'rebuild' command first.")
+	}
+
+	sort.Slice(trackList, func(i, j int) bool {
+		return strings.ToLower(trackList[i].Title) < strings.ToLower(trackList[j].Title)
+	})
+
+	var editable []*yt.TrackInfo
+	for i := range trackList {
+		track := &trackList[i]
+		trackPath, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
+
+		// Check if the file exists
+		if !stocked {
+			log.Printf("warning: track file not found: %s", trackPath)
+			continue
 		}
 
-		// Trim whitespace and check if empty
-		newTitle = strings.TrimSpace(newTitle)
-		if newTitle == "" {
-			fmt.Println("\nNo changes made.")
-			return
+		// Skip if filter query doesn't match
+		if filterQuery != "" {
+			if !strings.Contains(strings.ToLower(track.Title), filterQuery) &&
+				!strings.Contains(strings.ToLower(track.Artist), filterQuery) &&
+				!strings.Contains(strings.ToLower(track.ID), filterQuery) {
+				continue
+			}
+		}
+		if !labels.matches(track) {
+			continue
 		}
 
-		// If title hasn't changed, return early
-		if newTitle == currentTitle {
-			fmt.Println("\nNo changes made.")
-			return
+		editable = append(editable, track)
+	}
+
+	if len(editable) == 0 {
+		if filterQuery != "" {
+			fmt.Printf("\n- no local songs found matching \"%s\".\n", filterQuery)
+		} else if labels.active() {
+			fmt.Print("\n- no local songs found with the given tags.\n\n")
+		} else {
+			fmt.Print("\n- no local songs found. use 'ytpl search' to download some.\n\n")
+		}
+	}
+	return editable
+}
+
+// editForm asks for the new value of each field of the selected tracks and returns the changed
+// fields. An empty answer keeps the current value and "-" clears it; invalid values are asked
+// for again. With several tracks, only the fields they can share are asked for, and the current
+// value is shown if all of them have the same.
+func editForm(in *bufio.Reader, out io.Writer, selected []*yt.TrackInfo) (map[string]string, error) {
+	fmt.Fprintln(out)
+	if len(selected) == 1 {
+		fmt.Fprintf(out, "- editing '%s' (%s)\n", selected[0].Title, selected[0].ID)
+	} else {
+		fmt.Fprintf(out, "- editing %d tracks\n", len(selected))
+	}
+	fmt.Fprintf(out, "  (enter keeps the current value, \"%s\" clears it)\n\n", editClearValue)
+
+	changes := make(map[string]string)
+	for _, field := range editFields {
+		if len(selected) > 1 && !field.bulk {
+			continue
+		}
+		current := field.get(selected[0])
+		for _, track := range selected[1:] {
+			if field.get(track) != current {
+				current = "<various>"
+				break
+			}
 		}
 
-		// Update title if changed
-		if newTitle != "" && newTitle != currentTitle {
-			selectedItem.Info.Title = newTitle
-			if err := trackManager.UpdateTrack(selectedItem.Info); err != nil {
-				log.Fatalf("Error updating track: %v", err)
+		for {
+			fmt.Fprintf(out, "%14s [%s]: ", field.name, current)
+			answer, err := in.ReadString('\n')
+			if err == io.EOF && answer == "" {
+				// The input ended: keep the remaining fields
+				return changes, nil
 			}
-			if err := trackManager.SaveAll(); err != nil {
-				log.Fatalf("Error saving tracks: %v", err)
+			if err != nil && err != io.EOF {
+				return nil, err
 			}
-			fmt.Println("\nTitle updated.\n")
-		} else if newTitle == "" {
-			fmt.Println("\nEdit cancelled.\n")
-		} else {
-			fmt.Println("\nNo changes made.\n")
+			answer = strings.TrimSpace(answer)
+			if answer == "" {
+				break
+			}
+			if answer == editClearValue {
+				answer = ""
+			}
+			// Check the value before accepting it
+			check := *selected[0]
+			if err := field.set(&check, answer); err != nil {
+				fmt.Fprintf(out, "  %s\n", util.Red(err.Error()))
+				continue
+			}
+			changes[field.name] = answer
+			break
 		}
-	},
+	}
+	return changes, nil
+}
+
+// applyTrackEdits sets the changed fields of a track.
+func applyTrackEdits(track *yt.TrackInfo, changes map[string]string) error {
+	for _, field := range editFields {
+		value, changed := changes[field.name]
+		if !changed {
+			continue
+		}
+		if err := field.set(track, value); err != nil {
+			return fmt.Errorf("invalid %s '%s': %w", field.name, value, err)
+		}
+	}
+	return nil
+}
+
+// saveTrackEdits applies the changed fields to the tracks, saves them in the library and writes
+// them into the tags of the audio files.
+func saveTrackEdits(trackManager *tracks.Manager, selected []*yt.TrackInfo, changes map[string]string) {
+	if len(changes) == 0 {
+		fmt.Print("\nNo changes made.\n\n")
+		return
+	}
+
+	trackManager.BatchMode(true)
+	var previousTracks, updatedTracks []yt.TrackInfo
+	for _, track := range selected {
+		previousTracks = append(previousTracks, *track)
+		updated := *track
+		if err := applyTrackEdits(&updated, changes); err != nil {
+			log.Fatalf("Error updating track %s: %v", track.ID, err)
+		}
+		if err := trackManager.UpdateTrack(&updated); err != nil {
+			log.Fatalf("Error updating track: %v", err)
+		}
+		updatedTracks = append(updatedTracks, updated)
+	}
+	if err := trackManager.SaveAll(); err != nil {
+		log.Fatalf("Error saving tracks: %v", err)
+	}
+	// Keep the tags of the audio files in line with the library
+	for i := range updatedTracks {
+		writeTrackTags(previousTracks[i], updatedTracks[i])
+	}
+	if len(selected) == 1 {
+		fmt.Print("\nTrack updated.\n\n")
+	} else {
+		fmt.Printf("\n%d tracks updated.\n\n", len(selected))
+	}
+}
+
+// formatEditNumber formats a number field of the edit form, leaving 0 empty.
+func formatEditNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// parseEditNumber parses a number field of the edit form; an empty value is 0.
+func parseEditNumber(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("not a positive number: %s", value)
+	}
+	return n, nil
+}
+
+func init() {
+	for _, field := range editFields {
+		editFlagValues[field.name] = new(string)
+		editCmd.Flags().StringVar(editFlagValues[field.name], field.name, "", field.usage)
+	}
+	editCmd.Flags().BoolVar(&editBulk, "bulk", false, "edit the tracks matching the query as a table in $EDITOR")
+	editLabelFilter.addFlags(editCmd)
 }
 
 // Note: editCmd is added to rootCmd in root.go
@@ -0,0 +1,273 @@
+// cmd/edit_bulk.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// editBulk makes edit write the matching tracks to a file edited in $EDITOR.
+var editBulk bool
+
+// runEditor opens a file in the user's editor and waits for it to be closed.
+// It is replaced in tests.
+var runEditor = func(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may come with arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// confirmEdit asks a yes/no question before going on with a bulk edit. It is replaced in tests.
+var confirmEdit = util.Confirm
+
+// bulkEditFields are the columns of the bulk edit file after the track ID.
+var bulkEditFields = []editField{
+	findEditField("title"),
+	findEditField("artist"),
+	findEditField("album"),
+	findEditField("year"),
+	{
+		name: "tags",
+		get:  func(track *yt.TrackInfo) string { return strings.Join(track.Labels, ", ") },
+		set:  func(track *yt.TrackInfo, value string) error { track.Labels = parseLabels(value); return nil },
+	},
+}
+
+// bulkEditHeader explains the bulk edit file to the user.
+const bulkEditHeader = `# Edit the tracks below and save the file to apply the changes after a review.
+# Columns are separated by tabs; tags are separated by commas. Lines starting with # are ignored,
+# and removing the line of a track leaves it unchanged. Don't change the IDs.
+`
+
+// findEditField returns the edit field with the given name.
+func findEditField(name string) editField {
+	for _, field := range editFields {
+		if field.name == name {
+			return field
+		}
+	}
+	panic("unknown edit field " + name)
+}
+
+// bulkEditChange is a track changed in the bulk edit file.
+type bulkEditChange struct {
+	before, after yt.TrackInfo
+}
+
+// bulkEditTracks edits the stocked tracks matching the query and the tag filter as a table in
+// $EDITOR. Invalid rows are reported and the file can be edited again; the changes are shown as
+// a diff and saved at once after confirmation.
+func bulkEditTracks(trackManager *tracks.Manager, filterQuery string, labels labelFilter) {
+	selected := editableTracks(trackManager, filterQuery, labels)
+	if len(selected) == 0 {
+		return
+	}
+
+	file, err := os.CreateTemp("", "ytpl-edit-*.tsv")
+	if err != nil {
+		log.Fatalf("Error creating the edit file: %v", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+	_, err = file.WriteString(formatBulkEdit(selected))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("Error writing the edit file: %v", err)
+	}
+
+	var changes []bulkEditChange
+	for {
+		if err := runEditor(path); err != nil {
+			log.Fatalf("Error running the editor: %v", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Error reading the edit file: %v", err)
+		}
+		var problems []string
+		changes, problems = parseBulkEdit(string(content), selected)
+		if len(problems) == 0 {
+			break
+		}
+
+		fmt.Println()
+		for _, problem := range problems {
+			fmt.Println(util.Red(problem))
+		}
+		fmt.Println()
+		again, err := confirmEdit("- edit the file again?")
+		if err != nil {
+			log.Fatalf("Error getting confirmation: %v", err)
+		}
+		if !again {
+			fmt.Print("\n- bulk edit cancelled.\n\n")
+			return
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Print("\nNo changes made.\n\n")
+		return
+	}
+
+	fmt.Println()
+	for _, change := range changes {
+		printBulkEditDiff(change)
+	}
+	confirmed, err := confirmEdit(fmt.Sprintf("- apply the changes to %d tracks?", len(changes)))
+	if err != nil {
+		log.Fatalf("Error getting confirmation: %v", err)
+	}
+	if !confirmed {
+		fmt.Print("\n- bulk edit cancelled.\n\n")
+		return
+	}
+
+	trackManager.BatchMode(true)
+	for i := range changes {
+		if err := trackManager.UpdateTrack(&changes[i].after); err != nil {
+			log.Fatalf("Error updating track: %v", err)
+		}
+	}
+	if err := trackManager.SaveAll(); err != nil {
+		log.Fatalf("Error saving tracks: %v", err)
+	}
+	for _, change := range changes {
+		writeTrackTags(change.before, change.after)
+	}
+	fmt.Printf("\n%d tracks updated.\n\n", len(changes))
+}
+
+// formatBulkEdit returns the bulk edit file of the tracks: the header and one tab-separated
+// row per track. Tabs and line breaks in values are replaced with spaces.
+func formatBulkEdit(selected []*yt.TrackInfo) string {
+	var b strings.Builder
+	b.WriteString(bulkEditHeader)
+	columns := []string{"id"}
+	for _, field := range bulkEditFields {
+		columns = append(columns, field.name)
+	}
+	b.WriteString("# " + strings.Join(columns, "\t") + "\n")
+
+	for _, track := range selected {
+		row := []string{track.ID}
+		for _, field := range bulkEditFields {
+			row = append(row, bulkEditValue(field, track))
+		}
+		b.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	return b.String()
+}
+
+// bulkEditValue returns the value of a field in the bulk edit file, on a single line.
+func bulkEditValue(field editField, track *yt.TrackInfo) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(field.get(track))
+}
+
+// parseBulkEdit parses an edited bulk edit file of the selected tracks and returns the changed
+// tracks, or the problems of the invalid rows by line number.
+func parseBulkEdit(content string, selected []*yt.TrackInfo) ([]bulkEditChange, []string) {
+	byID := make(map[string]*yt.TrackInfo, len(selected))
+	for _, track := range selected {
+		byID[track.ID] = track
+	}
+
+	var changes []bulkEditChange
+	var problems []string
+	seen := make(map[string]int)
+	for i, line := range strings.Split(content, "\n") {
+		lineNumber := i + 1
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		if len(values) != 1+len(bulkEditFields) {
+			problems = append(problems, fmt.Sprintf("line %d: expected %d tab-separated columns, found %d",
+				lineNumber, 1+len(bulkEditFields), len(values)))
+			continue
+		}
+		id := strings.TrimSpace(values[0])
+		track, ok := byID[id]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: unknown track ID '%s'", lineNumber, id))
+			continue
+		}
+		if previous, ok := seen[id]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: track %s is already on line %d", lineNumber, id, previous))
+			continue
+		}
+		seen[id] = lineNumber
+
+		updated := *track
+		changed, valid := false, true
+		for j, field := range bulkEditFields {
+			value := strings.TrimSpace(values[j+1])
+			if value == strings.TrimSpace(bulkEditValue(field, track)) {
+				continue
+			}
+			if err := field.set(&updated, value); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: invalid %s '%s': %v", lineNumber, field.name, value, err))
+				valid = false
+				continue
+			}
+			// Values such as tags may be written differently but parse the same
+			changed = changed || field.get(&updated) != field.get(track)
+		}
+		if valid && changed {
+			changes = append(changes, bulkEditChange{before: *track, after: updated})
+		}
+	}
+	return changes, problems
+}
+
+// printBulkEditDiff prints the changed fields of a track.
+func printBulkEditDiff(change bulkEditChange) {
+	fmt.Println(util.Bold(change.before.ID))
+	for _, field := range bulkEditFields {
+		before, after := field.get(&change.before), field.get(&change.after)
+		if before == after {
+			continue
+		}
+		label := fmt.Sprintf("%-8s", field.name+":")
+		if before != "" {
+			fmt.Println(util.Red("- " + label + before))
+		}
+		if after != "" {
+			fmt.Println(util.Green("+ " + label + after))
+		}
+	}
+	fmt.Println()
+}
+
+// parseLabels parses comma-separated user tags, dropping empty and repeated ones.
+func parseLabels(value string) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, label := range strings.Split(value, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[strings.ToLower(label)] {
+			continue
+		}
+		seen[strings.ToLower(label)] = true
+		labels = append(labels, label)
+	}
+	return labels
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/audiotag"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+func TestParseBulkEdit(t *testing.T) {
+	first := &yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "First", Artist: "Band", Labels: []string{"chill"}}
+	second := &yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Second\tPart", ReleaseYear: 1999}
+	selected := []*yt.TrackInfo{first, second}
+
+	content := formatBulkEdit(selected)
+	assert.Contains(t, content, "# id\ttitle\tartist\talbum\tyear\ttags\n")
+	assert.Contains(t, content, "aaaaaaaaaaa\tFirst\tBand\t\t\tchill\n")
+	assert.Contains(t, content, "bbbbbbbbbbb\tSecond Part\t\t\t1999\t\n")
+
+	t.Run("returns the changed tracks", func(t *testing.T) {
+		edited := strings.Replace(content, "First\tBand\t\t\tchill", "First\tThe Band\tDebut\t2001\tchill, work, Chill", 1)
+		changes, problems := parseBulkEdit(edited, selected)
+		require.Empty(t, problems)
+		require.Len(t, changes, 1)
+		assert.Equal(t, *first, changes[0].before)
+		assert.Equal(t, yt.TrackInfo{
+			ID: "aaaaaaaaaaa", Title: "First", Artist: "The Band", Album: "Debut", ReleaseYear: 2001, Labels: []string{"chill", "work"},
+		}, changes[0].after)
+	})
+
+	t.Run("reports invalid rows by line number", func(t *testing.T) {
+		edited := strings.Join([]string{
+			"# comment",
+			"aaaaaaaaaaa\t\tBand\t\t\tchill",
+			"bbbbbbbbbbb\tSecond\t\t\tlast year\t",
+			"ccccccccccc\tThird\t\t\t\t",
+			"aaaaaaaaaaa\tFirst\tBand",
+			"bbbbbbbbbbb\tSecond\t\t\t1999\t",
+		}, "\n")
+		changes, problems := parseBulkEdit(edited, selected)
+		assert.Equal(t, []string{
+			"line 2: invalid title '': the title can't be empty",
+			"line 3: invalid year 'last year': the year must be a number such as 1999",
+			"line 4: unknown track ID 'ccccccccccc'",
+			"line 5: expected 6 tab-separated columns, found 3",
+			"line 6: track bbbbbbbbbbb is already on line 3",
+		}, problems)
+		assert.Empty(t, changes)
+	})
+}
+
+func TestEditBulkCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	resetFlags(t, editCmd)
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "First", Artist: "Band"},
+		{ID: "bbbbbbbbbbb", Title: "Second", Artist: "Band"},
+		{ID: "ccccccccccc", Title: "Other", Artist: "Someone"},
+	} {
+		track.FilePath = filepath.Join(downloadDir, track.ID+".flac")
+		writeTestFLAC(t, track.FilePath)
+		require.NoError(t, library.AddTrack(track))
+	}
+
+	// The first edit has an invalid year, fixed when the file is edited again
+	var edits []string
+	previousEditor := runEditor
+	runEditor = func(path string) error {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		edits = append(edits, string(content))
+		year := "someday"
+		if len(edits) > 1 {
+			year = "2001"
+		}
+		edited := strings.NewReplacer(
+			"aaaaaaaaaaa\tFirst\tBand\t\t\t", "aaaaaaaaaaa\tFirst Song\tBand\tDebut\t"+year+"\tchill",
+			"bbbbbbbbbbb\tSecond\tBand\t\t\t", "bbbbbbbbbbb\tSecond\tThe Band\t\t\t",
+			"aaaaaaaaaaa\tFirst Song\tBand\tDebut\tsomeday\tchill", "aaaaaaaaaaa\tFirst Song\tBand\tDebut\t2001\tchill",
+		).Replace(string(content))
+		return os.WriteFile(path, []byte(edited), 0644)
+	}
+	t.Cleanup(func() { runEditor = previousEditor })
+	var questions []string
+	previousConfirm := confirmEdit
+	confirmEdit = func(question string) (bool, error) {
+		questions = append(questions, question)
+		return true, nil
+	}
+	t.Cleanup(func() { confirmEdit = previousConfirm })
+
+	runCommand(t, "edit", "--bulk", "band")
+
+	require.Len(t, edits, 2)
+	assert.NotContains(t, edits[0], "ccccccccccc", "only the matching tracks are listed")
+	assert.Contains(t, edits[1], "someday", "the file keeps the previous edit")
+	assert.Equal(t, []string{"- edit the file again?", "- apply the changes to 2 tracks?"}, questions)
+
+	library, err = tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	first, ok := library.GetTrack("aaaaaaaaaaa")
+	require.True(t, ok)
+	assert.Equal(t, "First Song", first.Title)
+	assert.Equal(t, "Debut", first.Album)
+	assert.Equal(t, 2001, first.ReleaseYear)
+	assert.Equal(t, []string{"chill"}, first.Labels)
+	second, ok := library.GetTrack("bbbbbbbbbbb")
+	require.True(t, ok)
+	assert.Equal(t, "The Band", second.Artist)
+
+	tags, err := audiotag.Read(first.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "First Song", tags.Title)
+	assert.Equal(t, "Debut", tags.Album)
+}
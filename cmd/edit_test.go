@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+// resetFlags restores the flags of a command to their defaults after a test, as they keep their
+// values between runs.
+func resetFlags(t *testing.T, cmd *cobra.Command) {
+	t.Cleanup(func() {
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if slice, ok := f.Value.(pflag.SliceValue); ok {
+				_ = slice.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	})
+}
+
+func TestEditCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	resetFlags(t, editCmd)
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "First", Album: "Old Album", Genre: "Rock"},
+		{ID: "bbbbbbbbbbb", Title: "Second", Album: "Old Album", ReleaseYear: 1999},
+	} {
+		require.NoError(t, library.AddTrack(track))
+	}
+
+	runCommand(t, "edit", "--artist", "The Band", "--album", "Debut", "--comment", "", "aaaaaaaaaaa", "bbbbbbbbbbb")
+
+	library, err = tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	first, ok := library.GetTrack("aaaaaaaaaaa")
+	require.True(t, ok)
+	assert.Equal(t, yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "First", Artist: "The Band", Album: "Debut", Genre: "Rock"}, *first)
+	second, ok := library.GetTrack("bbbbbbbbbbb")
+	require.True(t, ok)
+	assert.Equal(t, yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Second", Artist: "The Band", Album: "Debut", ReleaseYear: 1999}, *second)
+}
+
+func TestEditForm(t *testing.T) {
+	first := &yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "First", Album: "Album", Genre: "Rock"}
+	second := &yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Second", Album: "Album", Genre: "Pop"}
+
+	t.Run("edits every field of a track", func(t *testing.T) {
+		// title, artist, album, year (invalid, then valid), genre, track number, comment
+		input := "New Title\n\n-\nnineteen\n2001\n\n3\nsome note\n"
+		var out bytes.Buffer
+		changes, err := editForm(bufio.NewReader(strings.NewReader(input)), &out, []*yt.TrackInfo{first})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"title": "New Title", "album": "", "year": "2001", "track-number": "3", "comment": "some note"}, changes)
+		assert.Contains(t, out.String(), "the year must be a number")
+		assert.Contains(t, out.String(), "genre [Rock]")
+
+		track := *first
+		require.NoError(t, applyTrackEdits(&track, changes))
+		assert.Equal(t, yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "New Title", ReleaseYear: 2001, Genre: "Rock", TrackNumber: 3, Comment: "some note"}, track)
+	})
+
+	t.Run("only asks for shared fields of several tracks", func(t *testing.T) {
+		// artist, album, year, genre, comment; the input ends before the comment
+		input := "The Band\n\n\nJazz\n"
+		var out bytes.Buffer
+		changes, err := editForm(bufio.NewReader(strings.NewReader(input)), &out, []*yt.TrackInfo{first, second})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"artist": "The Band", "genre": "Jazz"}, changes)
+		assert.NotContains(t, out.String(), "title [")
+		assert.NotContains(t, out.String(), "track-number [")
+		assert.Contains(t, out.String(), "album [Album]")
+		assert.Contains(t, out.String(), "genre [<various>]")
+	})
+}
@@ -0,0 +1,93 @@
+// cmd/errors.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"ytpl/internal/yt"
+)
+
+// Exit codes for yt-dlp failures, so that scripts can tell the causes apart.
+const (
+	exitYtUnknown           = 1
+	exitYtUnavailable       = 10
+	exitYtPrivate           = 11
+	exitYtAgeRestricted     = 12
+	exitYtGeoBlocked        = 13
+	exitYtRateLimited       = 14
+	exitYtNetwork           = 15
+	exitYtExtractorOutdated = 16
+)
+
+// ytErrorExitCode returns the exit code for a yt-dlp failure.
+func ytErrorExitCode(err error) int {
+	switch yt.ErrorKindOf(err) {
+	case yt.KindUnavailable:
+		return exitYtUnavailable
+	case yt.KindPrivate:
+		return exitYtPrivate
+	case yt.KindAgeRestricted:
+		return exitYtAgeRestricted
+	case yt.KindGeoBlocked:
+		return exitYtGeoBlocked
+	case yt.KindRateLimited:
+		return exitYtRateLimited
+	case yt.KindNetwork:
+		return exitYtNetwork
+	case yt.KindExtractorOutdated:
+		return exitYtExtractorOutdated
+	default:
+		return exitYtUnknown
+	}
+}
+
+// describeYtError returns a message explaining a yt-dlp failure to the user.
+// Unrecognized failures are described by yt-dlp's own error line.
+func describeYtError(err error) string {
+	switch yt.ErrorKindOf(err) {
+	case yt.KindUnavailable:
+		return "the video is unavailable or has been removed"
+	case yt.KindPrivate:
+		return "the video is private"
+	case yt.KindAgeRestricted:
+		if cfg != nil && cfg.CookieBrowser != "" {
+			return fmt.Sprintf("the video is age-restricted and the cookies from '%s' were not accepted; make sure you are logged in to YouTube in that browser", cfg.CookieBrowser)
+		}
+		return "the video is age-restricted; set cookie_browser in config.toml to use the login cookies of your browser"
+	case yt.KindGeoBlocked:
+		return "the video is not available in your country"
+	case yt.KindRateLimited:
+		return "YouTube is rate limiting requests (HTTP 429); wait a while and try again"
+	case yt.KindNetwork:
+		return "could not connect to YouTube; check your network connection"
+	case yt.KindExtractorOutdated:
+		return "yt-dlp could not read the YouTube page and may be outdated; update it with 'yt-dlp -U' or your package manager"
+	default:
+		return summarizeDownloadError(err)
+	}
+}
+
+// summarizeDownloadError returns the most relevant line of a yt-dlp error.
+func summarizeDownloadError(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if idx := strings.Index(lines[i], "ERROR:"); idx >= 0 {
+			return strings.TrimSpace(lines[i][idx:])
+		}
+	}
+	return lines[0]
+}
+
+// exitWithYtError prints a yt-dlp failure and exits with the code of its kind.
+func exitWithYtError(action string, err error) {
+	fmt.Fprintf(os.Stderr, "Error %s: %s\n", action, describeYtError(err))
+	var ytErr *yt.Error
+	if errors.As(err, &ytErr) && ytErr.Kind == yt.KindUnknown && ytErr.Stderr != "" {
+		// Show yt-dlp's output for failures ytpl doesn't know about
+		fmt.Fprintf(os.Stderr, "\n%s\n", strings.TrimSpace(ytErr.Stderr))
+	}
+	os.Exit(ytErrorExitCode(err))
+}
@@ -0,0 +1,175 @@
+// cmd/find.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	fuzzyfinder "github.com/koki-develop/go-fzf"
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/playlist"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// findEntry is a local track or a YouTube search result in the find picker.
+type findEntry struct {
+	Track     yt.TrackInfo
+	LocalPath string   // Path of the stocked audio file, "" for YouTube results
+	Playlists []string // Playlists containing a local track
+}
+
+// pickFindResult shows the find entries in fzf and returns the index of the chosen one.
+// YouTube results are appended to entries while the picker is open, with mu held.
+// It is a variable so that tests can pick entries without a terminal.
+var pickFindResult = func(entries *[]findEntry, mu *sync.Mutex) (int, error) {
+	f, err := fuzzyfinder.New(
+		fuzzyfinder.WithPrompt("[ find ] > "),
+		fuzzyfinder.WithHotReload(mu),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize fzf: %w", err)
+	}
+	idxs, err := f.Find(
+		entries,
+		func(i int) string {
+			return formatFindEntry((*entries)[i])
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if len(idxs) == 0 {
+		return 0, fuzzyfinder.ErrAbort
+	}
+	return idxs[0], nil
+}
+
+var findCmd = &cobra.Command{
+	Use:   "find <query>",
+	Short: "Find a song in the local library and on YouTube",
+	Long: `Find a song in the local library and on YouTube at once.
+Stocked tracks whose title, artist or playlist names match the query are listed first,
+tagged "local", and play right away without network access. YouTube results are added
+below them as soon as the search finishes; choosing one downloads and plays it.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+		recordSearchQuery(query)
+
+		entries := findLocalTracks(query)
+		localIDs := make(map[string]bool)
+		for _, entry := range entries {
+			localIDs[entry.Track.ID] = true
+		}
+
+		// Search YouTube while the picker is open
+		var (
+			mu        sync.Mutex
+			searchErr error
+		)
+		go func() {
+			results, err := cachedSearch(query, yt.SearchOptions{}, false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				searchErr = err
+				return
+			}
+			for _, track := range results {
+				if track.ID != "" && !localIDs[track.ID] {
+					entries = append(entries, findEntry{Track: track})
+				}
+			}
+		}()
+
+		idx, err := pickFindResult(&entries, &mu)
+		if err != nil {
+			if err == fuzzyfinder.ErrAbort {
+				mu.Lock()
+				defer mu.Unlock()
+				if searchErr != nil {
+					fmt.Println(util.Red(fmt.Sprintf("\n- YouTube search failed: %s", describeYtError(searchErr))))
+				}
+				fmt.Print("\n- search cancelled.\n\n")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
+			os.Exit(1)
+		}
+
+		mu.Lock()
+		chosen := entries[idx]
+		mu.Unlock()
+
+		if chosen.LocalPath != "" {
+			playTrackFile(&chosen.Track, chosen.LocalPath)
+			return
+		}
+		playSearchResult(chosen.Track)
+	},
+}
+
+// findLocalTracks returns the stocked tracks matching every word of query in their title,
+// artist or the names of the playlists containing them, sorted by title.
+func findLocalTracks(query string) []findEntry {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		return nil
+	}
+	inPlaylists := make(map[string][]string)
+	if names, err := playlist.ListAllPlaylists(); err == nil {
+		for _, name := range names {
+			p, err := playlist.LoadPlaylist(name)
+			if err != nil {
+				continue
+			}
+			for _, track := range p.Tracks {
+				inPlaylists[track.ID] = append(inPlaylists[track.ID], name)
+			}
+		}
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	var entries []findEntry
+	for _, track := range trackManager.ListTracks() {
+		path, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
+		if !stocked {
+			continue
+		}
+		haystack := strings.ToLower(strings.Join(append([]string{track.Title, track.Artist, track.Uploader, track.ID}, inPlaylists[track.ID]...), " "))
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(haystack, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			entries = append(entries, findEntry{Track: track, LocalPath: path, Playlists: inPlaylists[track.ID]})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Track.Title) < strings.ToLower(entries[j].Track.Title)
+	})
+	return entries
+}
+
+// formatFindEntry formats a find entry as a picker line tagged with its source.
+func formatFindEntry(entry findEntry) string {
+	source := "youtube"
+	if entry.LocalPath != "" {
+		source = "local  "
+	}
+	durationStr := strings.Trim(util.FormatDuration(entry.Track.Duration), "[]")
+	line := fmt.Sprintf("%s [%s] %s  %s", source, durationStr, util.FitWidth(entry.Track.Title, 50), entry.Track.Uploader)
+	if len(entry.Playlists) > 0 {
+		line += " @ " + strings.Join(entry.Playlists, ", ")
+	}
+	return line
+}
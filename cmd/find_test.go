@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/state"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+// waitForFindEntries waits until the find picker holds more than n entries.
+func waitForFindEntries(t *testing.T, entries *[]findEntry, mu *sync.Mutex, n int) {
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(*entries) > n
+	}, 5*time.Second, 10*time.Millisecond, "YouTube results never arrived")
+}
+
+func TestFindCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	require.NoError(t, fake.AddSearchFixture("never gonna", filepath.Join("..", "internal", "yt", "testdata", "search.jsonl")))
+	fake.AddVideo(ytfake.Video{Info: yt.TrackInfo{ID: "yPYZpwSpKmA", Title: "Rick Astley - Together Forever (Official Video)", Duration: 3}})
+
+	// Stock the first search result, plus a track that doesn't match the query
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up", Uploader: "Rick Astley", Duration: 213},
+		{ID: "aaaaaaaaaaa", Title: "Something Else", Uploader: "Someone", Duration: 100},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(downloadDir, track.ID+".mp3"), []byte("audio"), 0644))
+		require.NoError(t, library.AddTrack(track))
+	}
+
+	var seen []findEntry
+	pickEntry := func(pick func(entries []findEntry) int) {
+		previous := pickFindResult
+		pickFindResult = func(entries *[]findEntry, mu *sync.Mutex) (int, error) {
+			waitForFindEntries(t, entries, mu, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append([]findEntry(nil), *entries...)
+			return pick(seen), nil
+		}
+		t.Cleanup(func() { pickFindResult = previous })
+	}
+
+	t.Run("plays a local hit without network access", func(t *testing.T) {
+		pickEntry(func(entries []findEntry) int { return 0 })
+		runCommand(t, "find", "never", "gonna")
+
+		require.Len(t, seen, 2)
+		assert.Equal(t, "dQw4w9WgXcQ", seen[0].Track.ID)
+		assert.Equal(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"), seen[0].LocalPath)
+		assert.Equal(t, "yPYZpwSpKmA", seen[1].Track.ID, "the stocked track is not listed twice")
+		assert.Empty(t, seen[1].LocalPath)
+
+		assert.Equal(t, []string{"search:never gonna"}, fake.Calls())
+		current := state.GetState()
+		assert.Equal(t, "dQw4w9WgXcQ", current.CurrentTrackID)
+		assert.Equal(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"), current.DownloadedFilePath)
+	})
+
+	t.Run("downloads and plays a YouTube result", func(t *testing.T) {
+		pickEntry(func(entries []findEntry) int { return len(entries) - 1 })
+		runCommand(t, "find", "never", "gonna")
+
+		assert.Equal(t, []string{"search:never gonna", "download:yPYZpwSpKmA"}, fake.Calls(), "the second search is served from the cache")
+		assert.FileExists(t, filepath.Join(downloadDir, "yPYZpwSpKmA.mp3"))
+		assert.Equal(t, "yPYZpwSpKmA", state.GetState().CurrentTrackID)
+	})
+}
@@ -0,0 +1,195 @@
+// cmd/import.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/tracklist"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// defaultImportMinScore is the match score below which an imported track is left for review.
+const defaultImportMinScore = 55
+
+var (
+	importListInto     string
+	importListReport   string
+	importListMinScore int
+)
+
+// importCmd is the parent command for importing tracks from other music services.
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import tracks from other music services",
+}
+
+// importListCmd resolves the tracks of a track list on YouTube and downloads them into a playlist.
+var importListCmd = &cobra.Command{
+	Use:   "list <file>",
+	Short: "Import a track list from a text or CSV file into a playlist",
+	Long: `Import a track list exported from another music service into a playlist.
+
+The file holds one "Artist - Title" per line, or, if its name ends in .csv, CSV columns
+for artist, title and duration. A header row naming the columns is optional; common
+export formats such as "Track Name", "Artist Name(s)" and "Duration (ms)" are recognized.
+
+Each track is searched on YouTube and the result best matching its title, artist and
+duration is downloaded and added to the playlist. Matches scoring below --min-score are
+left out and listed as uncertain in a CSV report, next to the file by default. The report
+has the resolved video IDs in its id column and the best result of uncertain matches in
+its candidate column. Review it, copy the candidates you accept to the id column, and
+import the report again: tracks with an id are used as they are, the others are searched again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		entries, err := tracklist.Load(path)
+		if err != nil {
+			log.Fatalf("error reading track list: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Print("\n- the track list is empty.\n\n")
+			return
+		}
+
+		reportPath := importListReport
+		if reportPath == "" {
+			reportPath = importReportPath(path)
+		}
+
+		rows := resolveTrackList(entries)
+
+		// Add the matches to the playlist in the order of the list, then download them
+		var trackIDs []string
+		titles := make(map[string]string)
+		usedIn := make(map[string][]string)
+		for _, row := range rows {
+			if row.Status != tracklist.StatusMatched {
+				continue
+			}
+			if _, ok := titles[row.ID]; !ok {
+				trackIDs = append(trackIDs, row.ID)
+				usedIn[row.ID] = []string{importListInto}
+			}
+			titles[row.ID] = row.YouTubeTitle
+			if titles[row.ID] == "" {
+				titles[row.ID] = row.Query()
+			}
+		}
+		added, err := appendToPlaylist(importListInto, trackIDs)
+		if err != nil {
+			log.Fatalf("error saving playlist '%s': %v", importListInto, err)
+		}
+
+		if len(trackIDs) > 0 {
+			unavailable, failedIDs := downloadMissingTracks(trackIDs, titles, usedIn)
+			failed := make(map[string]string)
+			for _, id := range unavailable {
+				failed[id] = tracklist.StatusUnavailable
+			}
+			for _, id := range failedIDs {
+				failed[id] = tracklist.StatusFailed
+			}
+			for i := range rows {
+				if rows[i].Status != tracklist.StatusMatched {
+					continue
+				}
+				if status, ok := failed[rows[i].ID]; ok {
+					rows[i].Status = status
+					rows[i].Note = "download failed"
+				} else if info, err := yt.GetLocalTrackInfo(cfg, rows[i].ID); err == nil {
+					rows[i].YouTubeTitle = info.Title
+				}
+			}
+		}
+
+		if err := tracklist.WriteReport(reportPath, rows); err != nil {
+			log.Fatalf("error writing import report: %v", err)
+		}
+
+		counts := make(map[string]int)
+		for _, row := range rows {
+			counts[row.Status]++
+		}
+		fmt.Printf("\n- imported %d of %d tracks into '%s' (%d added): %d uncertain, %d not found, %d failed.\n",
+			counts[tracklist.StatusMatched], len(rows), importListInto, added,
+			counts[tracklist.StatusUncertain], counts[tracklist.StatusNotFound],
+			counts[tracklist.StatusFailed]+counts[tracklist.StatusUnavailable])
+		fmt.Printf("- report written to %s\n", reportPath)
+		if len(rows) > counts[tracklist.StatusMatched] {
+			fmt.Printf("- review it and run 'ytpl import list %s --into %s' to retry.\n", reportPath, importListInto)
+		}
+		fmt.Println()
+	},
+}
+
+// resolveTrackList finds the YouTube video of each entry without a video ID, by searching
+// for it and scoring the results against its title, artist and duration.
+func resolveTrackList(entries []tracklist.Entry) []tracklist.ReportRow {
+	fmt.Println()
+	rows := make([]tracklist.ReportRow, len(entries))
+	for i, entry := range entries {
+		row := tracklist.ReportRow{Entry: entry, Score: -1}
+		progress := fmt.Sprintf("[%d/%d]", i+1, len(entries))
+
+		if entry.ID != "" {
+			row.Status = tracklist.StatusMatched
+			fmt.Printf("- %s ✓ %s (%s)\n", progress, entry.Query(), entry.ID)
+			rows[i] = row
+			continue
+		}
+
+		searchSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("%s searching '%s'...", progress, entry.Query()),
+			util.StyleLine,
+		)
+		results, err := cachedSearch(entry.Query(), yt.SearchOptions{}, false)
+		searchSpinner.Stop("")
+
+		switch {
+		case err != nil:
+			row.Status = tracklist.StatusFailed
+			row.Note = describeYtError(err)
+			fmt.Println(util.Red(fmt.Sprintf("- %s ✗ %s: %s", progress, entry.Query(), row.Note)))
+		case len(results) == 0:
+			row.Status = tracklist.StatusNotFound
+			fmt.Println(util.Red(fmt.Sprintf("- %s ✗ %s: no results", progress, entry.Query())))
+		default:
+			best, score := yt.BestMatch(results, yt.MatchQuery{Query: entry.Query(), Duration: entry.Duration})
+			row.Score = score
+			row.YouTubeTitle = results[best].Title
+			if score < importListMinScore {
+				row.Status = tracklist.StatusUncertain
+				row.Candidate = results[best].ID
+				fmt.Println(util.Yellow(fmt.Sprintf("- %s ? %s → %s (score %d)", progress, entry.Query(), row.YouTubeTitle, score)))
+			} else {
+				row.Status = tracklist.StatusMatched
+				row.ID = results[best].ID
+				fmt.Printf("- %s ✓ %s → %s (score %d)\n", progress, entry.Query(), row.YouTubeTitle, score)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// importReportPath returns the default report path of a track list, next to it.
+// Importing a report again updates it in place.
+func importReportPath(path string) string {
+	if strings.HasSuffix(path, ".report.csv") {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".report.csv"
+}
+
+func init() {
+	importListCmd.Flags().StringVar(&importListInto, "into", "", "playlist to add the tracks to")
+	importListCmd.Flags().StringVar(&importListReport, "report", "", "path of the CSV report (default: <file>.report.csv)")
+	importListCmd.Flags().IntVar(&importListMinScore, "min-score", defaultImportMinScore, "lowest match score (0-100) to accept without review")
+	importListCmd.MarkFlagRequired("into")
+}
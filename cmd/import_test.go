@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/tracklist"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func TestImportListCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { importListInto, importListReport, importListMinScore = "", "", defaultImportMinScore })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	live := yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "Artist - Song (Live)", Uploader: "Artist", Duration: 320}
+	original := yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Song", Uploader: "Artist - Topic", Duration: 201}
+	unrelated := yt.TrackInfo{ID: "ccccccccccc", Title: "Something Else Entirely", Uploader: "Channel", Duration: 90}
+	fake.AddSearchResults("Artist - Song", live, original)
+	fake.AddSearchResults("Other - Tune", unrelated)
+	for _, track := range []yt.TrackInfo{live, original, unrelated} {
+		fake.AddVideo(ytfake.Video{Info: track})
+	}
+
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "old.report.csv")
+	report := func(t *testing.T) []tracklist.Entry {
+		entries, err := tracklist.Load(reportPath)
+		require.NoError(t, err)
+		return entries
+	}
+
+	t.Run("downloads the best matches and reports the others", func(t *testing.T) {
+		listPath := filepath.Join(dir, "old.txt")
+		require.NoError(t, os.WriteFile(listPath, []byte("Artist - Song\n# exported playlist\nNobody - Nothing\n\nOther - Tune\n"), 0644))
+
+		runCommand(t, "import", "list", listPath, "--into", "migrated")
+
+		assert.Equal(t, []string{"bbbbbbbbbbb"}, playlistIDs(t, "migrated"))
+		assert.FileExists(t, filepath.Join(downloadDir, "bbbbbbbbbbb.mp3"))
+		assert.NoFileExists(t, filepath.Join(downloadDir, "ccccccccccc.mp3"))
+
+		data, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, "artist,title,duration,id,status,score,candidate,youtube_title,note", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "Artist,Song,,bbbbbbbbbbb,matched,"), lines[1])
+		assert.Equal(t, "Nobody,Nothing,,,not found,,,,", lines[2])
+		assert.True(t, strings.HasPrefix(lines[3], "Other,Tune,,,uncertain,"), lines[3])
+		assert.Contains(t, lines[3], ",ccccccccccc,Something Else Entirely,")
+	})
+
+	t.Run("a reviewed report can be imported again", func(t *testing.T) {
+		// Accept the uncertain candidate
+		data, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		edited := strings.Replace(string(data), "Other,Tune,,,uncertain", "Other,Tune,,ccccccccccc,uncertain", 1)
+		require.NoError(t, os.WriteFile(reportPath, []byte(edited), 0644))
+		calls := len(fake.Calls())
+
+		runCommand(t, "import", "list", reportPath, "--into", "migrated")
+
+		assert.Equal(t, []string{"bbbbbbbbbbb", "ccccccccccc"}, playlistIDs(t, "migrated"))
+		assert.FileExists(t, filepath.Join(downloadDir, "ccccccccccc.mp3"))
+		assert.Equal(t, []string{"download:ccccccccccc"}, fake.Calls()[calls:], "resolved tracks are not searched again")
+
+		entries := report(t)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "bbbbbbbbbbb", entries[0].ID)
+		assert.Equal(t, "", entries[1].ID)
+		assert.Equal(t, "ccccccccccc", entries[2].ID)
+	})
+
+	t.Run("reads CSV columns with durations", func(t *testing.T) {
+		csvPath := filepath.Join(dir, "export.csv")
+		require.NoError(t, os.WriteFile(csvPath, []byte("Track Name,Artist Name(s),Duration (ms)\nSong,Artist,201000\n"), 0644))
+
+		entries, err := tracklist.Load(csvPath)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, tracklist.Entry{Line: 2, Artist: "Artist", Title: "Song", Duration: 201}, entries[0])
+
+		require.NoError(t, os.WriteFile(csvPath, []byte("Artist,Song,3:21\n"), 0644))
+		entries, err = tracklist.Load(csvPath)
+		require.NoError(t, err)
+		assert.Equal(t, tracklist.Entry{Line: 1, Artist: "Artist", Title: "Song", Duration: 201}, entries[0])
+
+		runCommand(t, "import", "list", csvPath, "--into", "from-csv")
+		assert.Equal(t, []string{"bbbbbbbbbbb"}, playlistIDs(t, "from-csv"))
+		assert.FileExists(t, filepath.Join(dir, "export.report.csv"))
+	})
+}
@@ -2,11 +2,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,6 +15,7 @@ import (
 
 	"ytpl/internal/player"
 	"ytpl/internal/playlist"
+	"ytpl/internal/queue"
 	"ytpl/internal/state"
 	"ytpl/internal/tracks"
 	"ytpl/internal/util"
@@ -33,7 +34,7 @@ var listCmd = &cobra.Command{
 			}
 
 			if len(playlistNames) == 0 {
-				fmt.Println("\n- no playlists found. use 'ytpl list create <name>' to create one.\n")
+				fmt.Print("\n- no playlists found. use 'ytpl list create <name>' to create one.\n\n")
 				return
 			}
 
@@ -44,7 +45,7 @@ var listCmd = &cobra.Command{
 			)
 			if err != nil {
 				if strings.Contains(err.Error(), "cancelled") {
-					fmt.Println("\n- playlist selection cancelled.\n")
+					fmt.Print("\n- playlist selection cancelled.\n\n")
 					return
 				}
 				log.Fatalf("error selecting playlist: %v", err)
@@ -57,7 +58,7 @@ var listCmd = &cobra.Command{
 			)
 			if err != nil {
 				if strings.Contains(err.Error(), "cancelled") {
-					fmt.Println("\n- action cancelled.\n")
+					fmt.Print("\n- action cancelled.\n\n")
 					return
 				}
 				log.Fatalf("error selecting action: %v", err)
@@ -102,7 +103,7 @@ var listAddCmd = &cobra.Command{
 		playlistName := args[0]
 
 		if appState.CurrentTrackID == "" {
-			fmt.Println("\n- no song is currently playing to add to a playlist.\n")
+			fmt.Print("\n- no song is currently playing to add to a playlist.\n\n")
 			return
 		}
 
@@ -119,6 +120,8 @@ var listAddCmd = &cobra.Command{
 		trackTitle := appState.CurrentTrackID // Fallback to ID if track not found
 		if exists && track != nil {
 			trackTitle = track.Title
+		} else if appState.CurrentTrackTitle != "" {
+			trackTitle = appState.CurrentTrackTitle // e.g. a streamed track
 		}
 
 		err = playlist.AddTrack(playlistName, trackToAdd)
@@ -142,6 +145,11 @@ var listAddCmd = &cobra.Command{
 		} else {
 			fmt.Printf("\n- added track '%s' to playlist '%s'\n\n", trackTitle, playlistName)
 		}
+
+		// A streamed track isn't stocked yet; offer to keep it
+		if player.IsStream(appState.DownloadedFilePath) {
+			offerStreamDownload(appState.CurrentTrackID, trackTitle)
+		}
 	},
 }
 
@@ -153,7 +161,7 @@ var listRemoveCmd = &cobra.Command{
 		playlistName := args[0]
 
 		if appState.CurrentTrackID == "" {
-			fmt.Println("\n- no song is currently playing to remove from a playlist.\n")
+			fmt.Print("\n- no song is currently playing to remove from a playlist.\n\n")
 			return
 		}
 
@@ -172,7 +180,7 @@ var listDelCmd = &cobra.Command{
 		name := args[0]
 		confirm, err := util.Confirm(fmt.Sprintf("\n- delete '%s'?", name))
 		if err != nil || !confirm {
-			fmt.Println("\n- playlist deletion cancelled.\n")
+			fmt.Print("\n- playlist deletion cancelled.\n\n")
 			return
 		}
 
@@ -183,9 +191,14 @@ var listDelCmd = &cobra.Command{
 	},
 }
 
+// listShowLabelFilter picks the playlist tracks to show by their tags.
+var listShowLabelFilter labelFilter
+
 var listShowCmd = &cobra.Command{
 	Use:   "show <playlist_name>",
 	Short: "Show contents of a playlist with fzf interface",
+	Long: `Show the tracks of a playlist in fzf and play the chosen one.
+With --tag and --not-tag, only the tracks with or without the given tags are shown.`,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -223,6 +236,9 @@ var listShowCmd = &cobra.Command{
 
 		for i, track := range p.Tracks {
 			trackInfo, found := trackManager.GetTrack(track.ID)
+			if listShowLabelFilter.active() && (!found || !listShowLabelFilter.matches(trackInfo)) {
+				continue
+			}
 			
 			var displayText string
 			if found {
@@ -242,6 +258,10 @@ var listShowCmd = &cobra.Command{
 			})
 		}
 
+		if len(displayItems) == 0 && listShowLabelFilter.active() {
+			fmt.Printf("\n- no tracks in playlist '%s' have the given tags.\n\n", name)
+			return
+		}
 
 		// Create fzf instance with custom prompt
 		prompt := fmt.Sprintf("[ play from %s ]", name)
@@ -260,27 +280,29 @@ var listShowCmd = &cobra.Command{
 
 		if err != nil {
 			if strings.Contains(err.Error(), "cancelled") {
-				fmt.Println("\n- selection cancelled.\n")
+				fmt.Print("\n- selection cancelled.\n\n")
 				return
 			}
 			log.Fatalf("error selecting track: %v", err)
 		}
 
 		if len(idxs) == 0 {
-			fmt.Println("\n- no track selected.\n")
+			fmt.Print("\n- no track selected.\n\n")
 			return
 		}
 
 		// Play the selected track
 		selected := displayItems[idxs[0]]
-		trackPath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", selected.TrackID))
 
 		// Get track info for display
 		trackInfo, found := trackManager.GetTrack(selected.TrackID)
 		trackTitle := ""
+		storedPath := ""
 		if found {
 			trackTitle = trackInfo.Title
+			storedPath = trackInfo.FilePath
 		}
+		trackPath, _ := yt.ResolveTrackPath(cfg, selected.TrackID, storedPath)
 
 		// Start playing the selected track
 		if err := player.StartPlayer(cfg, appState, trackPath); err != nil {
@@ -315,68 +337,7 @@ var listPlayCmd = &cobra.Command{
 			return
 		}
 
-		// Initialize track manager to get metadata
-		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
-		if err != nil {
-			log.Fatalf("error initializing track manager: %v", err)
-		}
-
-		var tracksToPlay []playlist.TrackInfo // Keep track info for state update
-		var playlistFilePaths []string        // Paths to pass to mpv
-
-		// Collect all file paths and ensure they are downloaded
-		for i, track := range p.Tracks {
-			trackInfo, found := trackManager.GetTrack(track.ID)
-			trackTitle := fmt.Sprintf("ID: %s", track.ID)
-			if found {
-				trackTitle = trackInfo.Title
-			}
-
-			downloadedFilePath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", track.ID))
-			if _, err := os.Stat(downloadedFilePath); os.IsNotExist(err) {
-				fmt.Printf("\n- track %d/%d \"%s\" is not stocked locally. downloading...\n", i+1, len(p.Tracks), trackTitle)
-				stopSpinner := util.StartSpinner(fmt.Sprintf("\n- downloading \"%s\"", trackTitle))
-				_, _, downloadErr := yt.DownloadTrack(cfg, track.ID) // yt.DownloadTrack returns (filePath, TrackInfo, error)
-				util.StopSpinner(stopSpinner)
-				if downloadErr != nil {
-					fmt.Printf("\n- warning: failed to download track \"%s\": %v. skipping from playlist.\n", trackTitle, downloadErr)
-					continue // Skip this track if download fails
-				}
-				fmt.Printf("\n- downloaded \"%s\" to %s.\n", trackTitle, downloadedFilePath)
-			}
-			tracksToPlay = append(tracksToPlay, track)
-			playlistFilePaths = append(playlistFilePaths, downloadedFilePath)
-		}
-
-		if len(playlistFilePaths) == 0 {
-			fmt.Printf("\n- no playable songs found in playlist '%s' after checking local stock.\n", playlistName)
-			return
-		}
-
-		// Load the entire playlist into mpv using LoadPlaylistIntoPlayer
-		if err := player.LoadPlaylistIntoPlayer(cfg, appState, playlistFilePaths, 0); err != nil { // Start from index 0
-			log.Fatalf("error loading playlist into player: %v", err)
-		}
-
-		// Update state for the first track in the playlist
-		firstTrack := tracksToPlay[0]
-		firstTrackInfo, found := trackManager.GetTrack(firstTrack.ID)
-		firstTrackTitle := fmt.Sprintf("ID: %s", firstTrack.ID)
-		if found {
-			firstTrackTitle = firstTrackInfo.Title
-		}
-
-		appState.CurrentTrackID = firstTrack.ID
-		appState.CurrentTrackTitle = firstTrackTitle
-		appState.DownloadedFilePath = playlistFilePaths[0]
-		appState.IsPlaying = true
-		appState.CurrentPlaylist = playlistName
-		appState.LastPlayedTrackIndex = 0
-
-		state.SaveState()
-
-		// Show status instead of custom message
-		ShowStatus()
+		playPlaylistTracks(playlistName, p.Tracks)
 	},
 }
 
@@ -386,7 +347,6 @@ var listShuffleCmd = &cobra.Command{
 	Short: "Shuffle and play songs from a playlist",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		// Remove the "Preparing playlist..." message
 		playlistName := args[0]
 		p, err := playlist.LoadPlaylist(playlistName)
 		if err != nil {
@@ -398,74 +358,196 @@ var listShuffleCmd = &cobra.Command{
 			return
 		}
 
-		// Initialize track manager to get metadata
-		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
-		if err != nil {
-			log.Fatalf("error initializing track manager: %v", err)
-		}
-
-		var tracksToPlay []playlist.TrackInfo
-		var playlistFilePaths []string
+		// Shuffle the whole playlist up front so that tracks downloaded later
+		// are still inserted at their shuffled position
+		tracksToPlay := make([]playlist.TrackInfo, len(p.Tracks))
+		copy(tracksToPlay, p.Tracks)
+		rand.Seed(time.Now().UnixNano()) // Seed for randomness
+		rand.Shuffle(len(tracksToPlay), func(i, j int) {
+			tracksToPlay[i], tracksToPlay[j] = tracksToPlay[j], tracksToPlay[i]
+		})
 
-		// Collect all file paths and ensure they are downloaded
-		for i, track := range p.Tracks {
-			trackInfo, found := trackManager.GetTrack(track.ID)
-			trackTitle := fmt.Sprintf("ID: %s", track.ID)
-			if found {
-				trackTitle = trackInfo.Title
-			}
+		playPlaylistTracks(playlistName, tracksToPlay)
+	},
+}
 
-			downloadedFilePath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", track.ID))
-			if _, err := os.Stat(downloadedFilePath); os.IsNotExist(err) {
-				fmt.Printf("\n- track %d/%d \"%s\" is not stocked locally. downloading...\n", i+1, len(p.Tracks), trackTitle)
-				stopSpinner := util.StartSpinner(fmt.Sprintf("\n- downloading \"%s\"", trackTitle))
-				_, _, downloadErr := yt.DownloadTrack(cfg, track.ID)
-				util.StopSpinner(stopSpinner)
-				if downloadErr != nil {
-					fmt.Printf("\n- warning: failed to download track \"%s\": %v. skipping from playlist.\n", trackTitle, downloadErr)
-					continue
-				}
-				fmt.Printf("\n- downloaded \"%s\" to %s.\n", trackTitle, downloadedFilePath)
+// playPlaylistTracks plays the given tracks in order.
+// Tracks that are already stocked start playing right away, while missing tracks are
+// sent through the download queue, downloaded in parallel (limited by cfg.DownloadConcurrency)
+// and inserted into the running mpv playlist at their position as soon as each download finishes.
+// If nothing is stocked yet, playback starts with the first track that finishes downloading.
+func playPlaylistTracks(playlistName string, tracksToPlay []playlist.TrackInfo) {
+	// Initialize track manager to get metadata
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		log.Fatalf("error initializing track manager: %v", err)
+	}
+
+	titles := make([]string, len(tracksToPlay))
+	paths := make([]string, len(tracksToPlay))
+	loaded := make([]bool, len(tracksToPlay)) // Whether the track is in the mpv playlist
+	indexOf := make(map[string][]int) // Playlist positions of each missing track
+	var missing []string
+
+	for i, track := range tracksToPlay {
+		titles[i] = fmt.Sprintf("ID: %s", track.ID)
+		if trackInfo, found := trackManager.GetTrack(track.ID); found {
+			titles[i] = trackInfo.Title
+		}
+
+		var stocked bool
+		paths[i], stocked = stockedTrackPath(trackManager, track.ID)
+		if !stocked {
+			if _, queued := indexOf[track.ID]; !queued {
+				missing = append(missing, track.ID)
 			}
-			tracksToPlay = append(tracksToPlay, track)
-			playlistFilePaths = append(playlistFilePaths, downloadedFilePath)
+			indexOf[track.ID] = append(indexOf[track.ID], i)
+			continue
 		}
+		loaded[i] = true
+	}
 
-		if len(playlistFilePaths) == 0 {
-			fmt.Printf("\n- no playable songs found in playlist '%s' after checking local stock.\n", playlistName)
-			return
+	// Download the missing tracks through the queue in the background
+	results := make(chan queue.Item)
+	if len(missing) > 0 {
+		fmt.Printf("\n- %d of %d tracks are not stocked locally. downloading in the background...\n", len(missing), len(tracksToPlay))
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		for _, id := range missing {
+			if _, err := q.Add(id, titles[indexOf[id][0]]); err != nil {
+				log.Fatalf("error adding '%s' to the download queue: %v", titles[indexOf[id][0]], err)
+			}
 		}
 
-		// Shuffle the collected file paths and corresponding track infos
-		rand.Seed(time.Now().UnixNano()) // Seed for randomness
-		rand.Shuffle(len(playlistFilePaths), func(i, j int) {
-			playlistFilePaths[i], playlistFilePaths[j] = playlistFilePaths[j], playlistFilePaths[i]
-			tracksToPlay[i], tracksToPlay[j] = tracksToPlay[j], tracksToPlay[i] // Keep tracksToPlay in sync
+		pool := newDownloadPool(q, func(item queue.Item) {
+			if item.Finished() {
+				results <- item
+			}
 		})
-
-		// Load the shuffled playlist into mpv
-		if err := player.LoadPlaylistIntoPlayer(cfg, appState, playlistFilePaths, 0); err != nil {
-			log.Fatalf("error loading shuffled playlist into player: %v", err)
+		go func() {
+			if err := pool.Run(context.Background(), missing...); err != nil {
+				log.Printf("warning: error processing download queue: %v", err)
+			}
+			close(results)
+		}()
+	} else {
+		close(results)
+	}
+
+	playing := false
+	startPlayback := func() {
+		var filePaths []string
+		first := -1
+		for i := range tracksToPlay {
+			if loaded[i] {
+				if first < 0 {
+					first = i
+				}
+				filePaths = append(filePaths, paths[i])
+			}
 		}
 
-		// Update state for the first track in the shuffled playlist
-		firstTrack := tracksToPlay[0]
-		firstTrackInfo, found := trackManager.GetTrack(firstTrack.ID)
-		firstTrackTitle := fmt.Sprintf("ID: %s", firstTrack.ID)
-		if found {
-			firstTrackTitle = firstTrackInfo.Title
+		// Load the available part of the playlist into mpv
+		if err := player.LoadPlaylistIntoPlayer(cfg, appState, filePaths, 0); err != nil { // Start from index 0
+			log.Fatalf("error loading playlist into player: %v", err)
 		}
 
-		appState.CurrentTrackID = firstTrack.ID
-		appState.CurrentTrackTitle = firstTrackTitle
-		appState.DownloadedFilePath = playlistFilePaths[0]
+		appState.CurrentTrackID = tracksToPlay[first].ID
+		appState.CurrentTrackTitle = titles[first]
+		appState.DownloadedFilePath = paths[first]
 		appState.IsPlaying = true
 		appState.CurrentPlaylist = playlistName
 		appState.LastPlayedTrackIndex = 0
 
 		state.SaveState()
+		playing = true
 
 		// Show status instead of custom message
 		ShowStatus()
-	},
+	}
+
+	if len(missing) < len(tracksToPlay) {
+		startPlayback()
+	}
+
+	var stopSpinner chan struct{}
+	if !playing {
+		stopSpinner = util.StartSpinner("\n- waiting for the first track to download")
+	}
+
+	var failed []queue.Item
+	downloaded := 0
+	for item := range results {
+		indices := indexOf[item.TrackID]
+		if item.Status != queue.StatusDone {
+			failed = append(failed, item)
+			continue
+		}
+		downloaded++
+
+		for _, index := range indices {
+			if item.Title != "" {
+				titles[index] = item.Title
+			}
+			if item.FilePath != "" {
+				paths[index] = item.FilePath
+			}
+		}
+
+		if !playing {
+			util.StopSpinner(stopSpinner)
+			for _, index := range indices {
+				loaded[index] = true
+			}
+			startPlayback()
+			continue
+		}
+
+		for _, index := range indices {
+			// Insert the track right after the loaded tracks that precede it in the playlist
+			position, playlistLen := 0, 0
+			for i := range tracksToPlay {
+				if loaded[i] {
+					playlistLen++
+					if i < index {
+						position++
+					}
+				}
+			}
+			if err := player.InsertFile(appState, paths[index], position, playlistLen); err != nil {
+				fmt.Printf("- downloaded \"%s\" but could not add it to the player: %v\n", titles[index], err)
+				break
+			}
+			loaded[index] = true
+		}
+		fmt.Printf("- downloaded \"%s\" (%d/%d)\n", titles[indices[0]], downloaded+len(failed), len(missing))
+	}
+
+	if !playing {
+		util.StopSpinner(stopSpinner)
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	// Summarize the background downloads
+	fmt.Printf("\n- background downloads finished: %d downloaded, %d failed.\n", downloaded, len(failed))
+	for _, item := range failed {
+		reason := "download cancelled"
+		if item.Status == queue.StatusFailed {
+			reason = describeYtError(item.Err())
+		}
+		fmt.Printf("  - %s: %s\n", titles[indexOf[item.TrackID][0]], reason)
+	}
+	if !playing {
+		fmt.Printf("\n- no playable songs found in playlist '%s'.\n", playlistName)
+	}
+	fmt.Println()
+}
+
+func init() {
+	listShowLabelFilter.addFlags(listShowCmd)
 }
@@ -0,0 +1,153 @@
+// cmd/list_import.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/playlist"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var (
+	listImportDownload bool
+	listImportPrune    bool
+)
+
+// listImportCmd creates or extends a playlist from a YouTube playlist or channel.
+var listImportCmd = &cobra.Command{
+	Use:   "import <playlist_name> <youtube_playlist_url>",
+	Short: "Import a YouTube playlist or channel into a playlist",
+	Long: `Import the videos of a YouTube playlist or channel into a playlist, in the same order.
+The playlist is created if it doesn't exist. Re-importing adds only the new entries,
+and --prune drops the entries it imported that are no longer in the YouTube playlist;
+tracks added to the playlist in other ways are kept.
+Tracks that aren't stocked locally are queued for download in the background,
+or downloaded right away with --download.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		playlistName, playlistURL := args[0], args[1]
+
+		listSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("listing '%s'...", playlistURL),
+			util.StyleLine,
+		)
+		entries, err := yt.FetchPlaylist(cfg, playlistURL)
+		listSpinner.Stop("")
+		if err != nil {
+			exitWithYtError("listing YouTube playlist", err)
+		}
+		if len(entries) == 0 {
+			fmt.Print("\n- the YouTube playlist has no playable videos.\n\n")
+			return
+		}
+
+		p, err := playlist.LoadPlaylist(playlistName)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Fatalf("error loading playlist '%s': %v", playlistName, err)
+			}
+			p = &playlist.Playlist{Name: playlistName}
+		}
+		imports, err := playlist.LoadImports(playlistName)
+		if err != nil {
+			log.Fatalf("error loading import record of playlist '%s': %v", playlistName, err)
+		}
+
+		// Add new entries at the end, in the order of the YouTube playlist
+		existing := make(map[string]bool)
+		for _, track := range p.Tracks {
+			existing[track.ID] = true
+		}
+		remote := make(map[string]bool)
+		titles := make(map[string]string)
+		var added []string
+		for _, entry := range entries {
+			remote[entry.ID] = true
+			titles[entry.ID] = entry.Title
+			if !existing[entry.ID] {
+				p.Tracks = append(p.Tracks, playlist.TrackInfo{ID: entry.ID})
+				existing[entry.ID] = true
+				added = append(added, entry.ID)
+			}
+		}
+
+		imports.Record(playlistURL, added...)
+
+		// Drop the imported entries removed upstream if requested
+		removed := 0
+		if listImportPrune {
+			kept := p.Tracks[:0]
+			for _, track := range p.Tracks {
+				if remote[track.ID] || !imports.Added(playlistURL, track.ID) {
+					kept = append(kept, track)
+				} else {
+					removed++
+				}
+			}
+			p.Tracks = kept
+			imports.Keep(p)
+		}
+
+		if len(added) > 0 || removed > 0 {
+			if err := playlist.SavePlaylist(p); err != nil {
+				log.Fatalf("error saving playlist '%s': %v", playlistName, err)
+			}
+			if err := playlist.SaveImports(playlistName, imports); err != nil {
+				log.Fatalf("error saving import record of playlist '%s': %v", playlistName, err)
+			}
+		}
+		fmt.Printf("\n- imported %d videos into '%s': %d added, %d already in the playlist",
+			len(entries), playlistName, len(added), len(entries)-len(added))
+		if listImportPrune {
+			fmt.Printf(", %d removed", removed)
+		}
+		fmt.Print(".\n")
+
+		// Fetch the tracks we don't have yet
+		var trackIDs []string
+		usedIn := make(map[string][]string)
+		for _, entry := range entries {
+			trackIDs = append(trackIDs, entry.ID)
+			usedIn[entry.ID] = []string{playlistName}
+		}
+		if listImportDownload {
+			downloadMissingTracks(trackIDs, titles, usedIn)
+			return
+		}
+
+		q, err := downloadQueue()
+		if err != nil {
+			log.Fatalf("error opening download queue: %v", err)
+		}
+		trackManager := loadLibrary()
+		queued := 0
+		for _, id := range trackIDs {
+			if _, stocked := stockedTrackPath(trackManager, id); stocked {
+				continue
+			}
+			if _, err := q.Add(id, titles[id]); err != nil {
+				log.Fatalf("error adding '%s' to the download queue: %v", titles[id], err)
+			}
+			queued++
+		}
+		if queued == 0 {
+			fmt.Print("- all tracks are stocked locally.\n\n")
+			return
+		}
+		if err := ensureDownloadWorker(q); err != nil {
+			log.Fatalf("error starting download worker: %v", err)
+		}
+		fmt.Printf("- queued %d tracks for download. check progress with 'ytpl dl ls'.\n\n", queued)
+	},
+}
+
+func init() {
+	listImportCmd.Flags().BoolVar(&listImportDownload, "download", false, "download missing tracks right away instead of in the background")
+	listImportCmd.Flags().BoolVar(&listImportPrune, "prune", false, "remove imported entries that are no longer in the YouTube playlist")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/playlist"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func playlistIDs(t *testing.T, name string) []string {
+	p, err := playlist.LoadPlaylist(name)
+	require.NoError(t, err)
+	var ids []string
+	for _, track := range p.Tracks {
+		ids = append(ids, track.ID)
+	}
+	return ids
+}
+
+func TestListImportCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { listImportDownload, listImportPrune = false, false })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	const url = "https://www.youtube.com/playlist?list=PLtest"
+	videos := []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "First", Duration: 1},
+		{ID: "bbbbbbbbbbb", Title: "Second", Duration: 1},
+		{ID: "ccccccccccc", Title: "Third", Duration: 1},
+	}
+	for _, video := range videos {
+		fake.AddVideo(ytfake.Video{Info: video})
+	}
+	fake.SetPlaylist(url, videos[0], videos[1], yt.TrackInfo{ID: "ddddddddddd", Title: "[Private video]"}, videos[2])
+
+	t.Run("creates the playlist in order and downloads missing tracks", func(t *testing.T) {
+		runCommand(t, "list", "import", "imported", url, "--download")
+
+		assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}, playlistIDs(t, "imported"))
+		for _, video := range videos {
+			assert.FileExists(t, filepath.Join(downloadDir, video.ID+".mp3"))
+		}
+	})
+
+	t.Run("re-import adds only new entries", func(t *testing.T) {
+		newVideo := yt.TrackInfo{ID: "eeeeeeeeeee", Title: "Fourth", Duration: 1}
+		fake.AddVideo(ytfake.Video{Info: newVideo})
+		fake.SetPlaylist(url, newVideo, videos[0], videos[2])
+
+		runCommand(t, "list", "import", "imported", url, "--download")
+
+		assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "eeeeeeeeeee"}, playlistIDs(t, "imported"))
+		assert.FileExists(t, filepath.Join(downloadDir, "eeeeeeeeeee.mp3"))
+	})
+
+	t.Run("prune drops entries removed upstream", func(t *testing.T) {
+		runCommand(t, "list", "import", "imported", url, "--download", "--prune")
+
+		assert.Equal(t, []string{"aaaaaaaaaaa", "ccccccccccc", "eeeeeeeeeee"}, playlistIDs(t, "imported"))
+	})
+
+	t.Run("prune keeps tracks added by hand", func(t *testing.T) {
+		require.NoError(t, playlist.AddTrack("imported", playlist.TrackInfo{ID: "fffffffffff"}))
+		fake.SetPlaylist(url, videos[0], videos[2])
+
+		runCommand(t, "list", "import", "imported", url, "--download", "--prune")
+
+		assert.Equal(t, []string{"aaaaaaaaaaa", "ccccccccccc", "fffffffffff"}, playlistIDs(t, "imported"))
+	})
+
+	t.Run("finds renamed tracks through the library", func(t *testing.T) {
+		renamed := filepath.Join(downloadDir, "First.mp3")
+		require.NoError(t, os.Rename(filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"), renamed))
+		library, err := tracks.NewManager("", downloadDir)
+		require.NoError(t, err)
+		require.NoError(t, library.AddTrack(yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "First", FilePath: renamed}))
+		calls := len(fake.Calls())
+
+		runCommand(t, "list", "import", "imported", url, "--download")
+
+		assert.NotContains(t, fake.Calls()[calls:], "download:aaaaaaaaaaa")
+		assert.NoFileExists(t, filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"))
+	})
+}
@@ -0,0 +1,192 @@
+// cmd/list_sync.go
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/playlist"
+	"ytpl/internal/queue"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var listSyncAll bool
+
+// listSyncCmd downloads every missing track of one or more playlists so they can be played offline.
+var listSyncCmd = &cobra.Command{
+	Use:   "sync <playlist_name>... | --all",
+	Short: "Download all missing tracks of playlists for offline playback",
+	Run: func(cmd *cobra.Command, args []string) {
+		playlistNames := args
+		if listSyncAll {
+			names, err := playlist.ListAllPlaylists()
+			if err != nil {
+				log.Fatalf("error loading playlists: %v", err)
+			}
+			playlistNames = names
+		}
+		if len(playlistNames) == 0 {
+			fmt.Print("\n- specify a playlist name or use --all.\n\n")
+			return
+		}
+
+		// Collect the unique tracks of all playlists, remembering where each one is used
+		var trackIDs []string
+		usedIn := make(map[string][]string)
+		for _, name := range playlistNames {
+			p, err := playlist.LoadPlaylist(name)
+			if err != nil {
+				log.Fatalf("error loading playlist '%s': %v", name, err)
+			}
+			for _, track := range p.Tracks {
+				if _, seen := usedIn[track.ID]; !seen {
+					trackIDs = append(trackIDs, track.ID)
+				}
+				usedIn[track.ID] = append(usedIn[track.ID], name)
+			}
+		}
+
+		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+		if err != nil {
+			log.Fatalf("error initializing track manager: %v", err)
+		}
+
+		titles := make(map[string]string)
+		for _, id := range trackIDs {
+			titles[id] = fmt.Sprintf("ID: %s", id)
+			if trackInfo, found := trackManager.GetTrack(id); found {
+				titles[id] = trackInfo.Title
+			}
+		}
+
+		downloadMissingTracks(trackIDs, titles, usedIn)
+	},
+}
+
+// downloadMissingTracks downloads the tracks that aren't stocked locally through the download queue,
+// showing their progress, and reports which tracks failed and in which playlists they are used.
+// It returns the tracks that are unavailable upstream and those that failed for another reason.
+func downloadMissingTracks(trackIDs []string, titles map[string]string, usedIn map[string][]string) (unavailable, failedIDs []string) {
+	trackManager := loadLibrary()
+	var missing []string
+	for _, id := range trackIDs {
+		if _, stocked := stockedTrackPath(trackManager, id); !stocked {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) == 0 {
+		fmt.Printf("\n- all %d tracks are stocked locally. nothing to download.\n\n", len(trackIDs))
+		return nil, nil
+	}
+	fmt.Printf("\n- %d of %d tracks are not stocked locally. downloading...\n\n", len(missing), len(trackIDs))
+
+	q, err := downloadQueue()
+	if err != nil {
+		log.Fatalf("error opening download queue: %v", err)
+	}
+	for _, id := range missing {
+		if _, err := q.Add(id, titles[id]); err != nil {
+			log.Fatalf("error adding '%s' to the download queue: %v", titles[id], err)
+		}
+	}
+
+	board := util.NewProgressBoard(cfg.DownloadConcurrency)
+	boardSlots := make([]string, cfg.DownloadConcurrency) // Track shown on each board line
+	var (
+		done   int
+		failed = make(map[string]error)
+	)
+
+	pool := newDownloadPool(q, func(item queue.Item) {
+		title := titles[item.TrackID]
+		slot := -1
+		for i, id := range boardSlots {
+			if id == item.TrackID {
+				slot = i
+				break
+			}
+		}
+
+		if !item.Finished() {
+			if item.Status != queue.StatusActive {
+				return
+			}
+			// Show the progress on the track's line, taking a free line if it has none yet
+			for i := 0; slot < 0 && i < len(boardSlots); i++ {
+				if boardSlots[i] == "" {
+					slot = i
+					boardSlots[i] = item.TrackID
+				}
+			}
+			if slot >= 0 {
+				board.Set(slot, "  "+formatDownloadProgress(item.Percent, item.Speed, item.ETA, title))
+			}
+			return
+		}
+
+		if slot >= 0 {
+			board.Set(slot, "")
+			boardSlots[slot] = ""
+		}
+		done++
+		if item.Status != queue.StatusDone {
+			itemErr := item.Err()
+			if item.Status == queue.StatusCancelled {
+				itemErr = errors.New("download cancelled")
+			}
+			if kind := yt.ErrorKindOf(itemErr); kind == yt.KindUnavailable || kind == yt.KindPrivate {
+				unavailable = append(unavailable, item.TrackID)
+			} else {
+				failed[item.TrackID] = itemErr
+				failedIDs = append(failedIDs, item.TrackID)
+			}
+			board.Println(util.Red(fmt.Sprintf("- [%d/%d] ✗ %s", done, len(missing), title)))
+			return
+		}
+
+		if item.Title != "" {
+			titles[item.TrackID] = item.Title
+		}
+		board.Println(fmt.Sprintf("- [%d/%d] ✓ %s", done, len(missing), titles[item.TrackID]))
+	})
+	if err := pool.Run(context.Background(), missing...); err != nil {
+		log.Printf("warning: error processing download queue: %v", err)
+	}
+	board.Close()
+
+	// Report the results
+	downloaded := len(missing) - len(unavailable) - len(failedIDs)
+	fmt.Printf("\n- sync finished: %d already stocked, %d downloaded, %d failed.\n",
+		len(trackIDs)-len(missing), downloaded, len(unavailable)+len(failedIDs))
+
+	if len(unavailable) > 0 {
+		fmt.Println(util.Yellow("\n- unavailable upstream:"))
+		for _, id := range unavailable {
+			if len(usedIn[id]) == 0 {
+				fmt.Printf("  - %s (%s)\n", titles[id], id)
+				continue
+			}
+			fmt.Printf("  - %s (%s) in %s\n", titles[id], id, strings.Join(usedIn[id], ", "))
+		}
+	}
+	if len(failedIDs) > 0 {
+		fmt.Println(util.Red("\n- failed to download:"))
+		for _, id := range failedIDs {
+			fmt.Printf("  - %s: %s\n", titles[id], describeYtError(failed[id]))
+		}
+	}
+	fmt.Println()
+	return unavailable, failedIDs
+}
+
+func init() {
+	listSyncCmd.Flags().BoolVar(&listSyncAll, "all", false, "sync all playlists")
+}
@@ -4,7 +4,7 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"time"
 
 	"ytpl/internal/player"
@@ -22,11 +22,12 @@ var nextCmd = &cobra.Command{
 			return
 		}
 		if appState.CurrentPlaylist == "" && len(appState.ShuffleQueue) == 0 {
-			fmt.Println("\n- no active playlist or shuffle queue to advance.\n")
+			fmt.Print("\n- no active playlist or shuffle queue to advance.\n\n")
 			return
 		}
 
 		if appState.CurrentPlaylist != "" {
+			recordSkippedTrack()
 			err := player.Next(appState)
 			if err != nil {
 				// Error sending next command to player
@@ -38,8 +39,8 @@ var nextCmd = &cobra.Command{
 				nextIndex := appState.LastPlayedTrackIndex + 1
 				nextTrackID := appState.ShuffleQueue[nextIndex]
 
-				nextFilePath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", nextTrackID))
-				if _, err := os.Stat(nextFilePath); os.IsNotExist(err) {
+				nextFilePath, stocked := localTrackPath(nextTrackID)
+				if !stocked {
 					return
 				}
 
@@ -65,23 +66,33 @@ var nextCmd = &cobra.Command{
 				return
 			}
 		} else {
-			fmt.Println("\n- no next song available.\n")
+			fmt.Print("\n- no next song available.\n\n")
 			return
 		}
 		statusCmd.Run(statusCmd, []string{}) // Call status command
 	},
 }
 
+// recordSkippedTrack remembers the track being skipped, so that the radio doesn't queue it again.
+func recordSkippedTrack() {
+	updateAppStateFromMpvStatus()
+	if appState.CurrentTrackID == "" || slices.Contains(appState.SkippedTracks, appState.CurrentTrackID) {
+		return
+	}
+	appState.SkippedTracks = append(appState.SkippedTracks, appState.CurrentTrackID)
+	_ = state.SaveState()
+}
+
 var prevCmd = &cobra.Command{
 	Use:   "prev",
 	Short: "Play the previous song in the current playlist or shuffled queue",
 	Run: func(cmd *cobra.Command, args []string) {
 		if appState.PID == 0 {
-			fmt.Println("\n- player is not running.\n")
+			fmt.Print("\n- player is not running.\n\n")
 			return
 		}
 		if appState.CurrentPlaylist == "" && len(appState.ShuffleQueue) == 0 {
-			fmt.Println("\n- no active playlist or shuffle queue to go back.\n")
+			fmt.Print("\n- no active playlist or shuffle queue to go back.\n\n")
 			return
 		}
 
@@ -97,8 +108,8 @@ var prevCmd = &cobra.Command{
 				prevIndex := appState.LastPlayedTrackIndex - 1
 				prevTrackID := appState.ShuffleQueue[prevIndex]
 
-				prevFilePath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", prevTrackID))
-				if _, err := os.Stat(prevFilePath); os.IsNotExist(err) {
+				prevFilePath, stocked := localTrackPath(prevTrackID)
+				if !stocked {
 					return
 				}
 
@@ -115,11 +126,11 @@ var prevCmd = &cobra.Command{
 				time.Sleep(200 * time.Millisecond) // Short delay
 				// No direct display here. statusCmd.Run() will handle it.
 			} else {
-				fmt.Println("\n- beginning of shuffle queue. no previous songs.\n")
+				fmt.Print("\n- beginning of shuffle queue. no previous songs.\n\n")
 				return
 			}
 		} else {
-			fmt.Println("\n- no previous song available.\n")
+			fmt.Print("\n- no previous song available.\n\n")
 		}
 		statusCmd.Run(statusCmd, []string{}) // Call status command
 	},
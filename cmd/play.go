@@ -4,7 +4,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -19,6 +18,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// playLabelFilter picks the tracks to play by their tags.
+var playLabelFilter labelFilter
+
 var playCmd = &cobra.Command{
 	Use:   "play [query]",
 	Short: "Play a locally stocked song",
@@ -57,10 +59,10 @@ var playCmd = &cobra.Command{
 		}, 0, len(trackList))
 
 		for _, track := range trackList {
-			trackPath := filepath.Join(cfg.DownloadDir, track.ID+".mp3")
+			trackPath, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
 
 			// Check if the file exists
-			if _, err := os.Stat(trackPath); os.IsNotExist(err) {
+			if !stocked {
 				// Track file not found
 				continue
 			}
@@ -71,10 +73,14 @@ var playCmd = &cobra.Command{
 			// Skip if filter query doesn't match
 			if filterQuery != "" {
 				if !strings.Contains(strings.ToLower(displayTitle), strings.ToLower(filterQuery)) &&
+					!strings.Contains(strings.ToLower(track.Artist), strings.ToLower(filterQuery)) &&
 					!strings.Contains(strings.ToLower(track.ID), strings.ToLower(filterQuery)) {
 					continue
 				}
 			}
+			if !playLabelFilter.matches(&track) {
+				continue
+			}
 
 			durationStr := strings.Trim(util.FormatDuration(track.Duration), "[]")
 			displayText := fmt.Sprintf("[%s] - %s", durationStr, displayTitle)
@@ -96,6 +102,8 @@ var playCmd = &cobra.Command{
 		if len(displayItems) == 0 {
 			if filterQuery != "" {
 				fmt.Printf("No local songs found matching '%s'\n", filterQuery)
+			} else if playLabelFilter.active() {
+				fmt.Println("No local songs found with the given tags")
 			} else {
 				fmt.Println("No local songs found. Use 'ytpl search' to download some")
 			}
@@ -118,7 +126,7 @@ var playCmd = &cobra.Command{
 		)
 		if err != nil {
 			if err == fuzzyfinder.ErrAbort {
-				fmt.Println("\n- selection cancelled.\n")
+				fmt.Print("\n- selection cancelled.\n\n")
 				return
 			}
 			fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
@@ -153,3 +161,6 @@ var playCmd = &cobra.Command{
 
 // init initializes the play command
 // Note: playCmd is added to rootCmd in root.go
+func init() {
+	playLabelFilter.addFlags(playCmd)
+}
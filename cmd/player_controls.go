@@ -16,7 +16,7 @@ var pauseCmd = &cobra.Command{
 	Short: "Pause the currently playing song",
 	Run: func(cmd *cobra.Command, args []string) {
 		if appState.PID == 0 {
-			fmt.Println("\n- no song is currently playing.\n")
+			fmt.Print("\n- no song is currently playing.\n\n")
 			return
 		}
 		if appState.IsPlaying {
@@ -34,15 +34,15 @@ var resumeCmd = &cobra.Command{
 	Short: "Resume the paused song",
 	Run: func(cmd *cobra.Command, args []string) {
 		if appState.PID == 0 {
-			fmt.Println("\n- no song is currently playing or paused\n")
+			fmt.Print("\n- no song is currently playing or paused\n\n")
 			return
 		}
 		if !appState.IsPlaying {
 			// Ignore error when resuming player
 			_ = player.Resume(appState)
-			fmt.Println("\n- resumed\n")
+			fmt.Print("\n- resumed\n\n")
 		} else {
-			fmt.Println("\nalready playing\n")
+			fmt.Print("\nalready playing\n\n")
 		}
 	},
 }
@@ -52,12 +52,14 @@ var stopCmd = &cobra.Command{
 	Short: "Stop the currently playing song",
 	Run: func(cmd *cobra.Command, args []string) {
 		if appState.PID == 0 {
-			fmt.Println("\n- no song is currently playing.\n")
+			fmt.Print("\n- no song is currently playing.\n\n")
 			return
 		}
+		// Tracks skipped on the radio are remembered until the player is stopped
+		appState.SkippedTracks = nil
 		// Ignore error when stopping player
 		_ = player.StopPlayer(appState)
-		fmt.Println("\n- stopped\n")
+		fmt.Print("\n- stopped\n\n")
 	},
 }
 
@@ -67,7 +69,7 @@ var volCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if appState.PID == 0 {
-			fmt.Println("\n- no song is currently playing to set volume.\n")
+			fmt.Print("\n- no song is currently playing to set volume.\n\n")
 			return
 		}
 
@@ -0,0 +1,310 @@
+// cmd/radio.go
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/config"
+	"ytpl/internal/player"
+	"ytpl/internal/playlist"
+	"ytpl/internal/state"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// radioPlaylistPrefix starts the name shown for the radio, followed by the title of its seed track.
+const radioPlaylistPrefix = "radio: "
+
+// radioPollInterval is how often the radio feeder checks how many tracks are left in the queue.
+var radioPollInterval = 5 * time.Second
+
+// startRadioFeeder starts the background process that keeps the radio's queue filled.
+// It is a variable so that tests can replace it.
+var startRadioFeeder = func(seedID string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find ytpl executable: %w", err)
+	}
+	feeder := exec.Command(executable, "radio", "feed", seedID)
+	// Detach the feeder so that it keeps running after ytpl exits
+	feeder.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true, // Create a new process group
+	}
+	if err := feeder.Start(); err != nil {
+		return fmt.Errorf("failed to start radio feeder: %w", err)
+	}
+	return feeder.Process.Release()
+}
+
+// radioCmd plays YouTube's mix of a track.
+var radioCmd = &cobra.Command{
+	Use:   "radio [track]",
+	Short: "Play YouTube's mix of a track, adding more tracks as it plays",
+	Long: `Play the mix YouTube generates for a track, the endless list of related videos behind
+"Mix" on the watch page. The track is a YouTube URL or video ID, or the track playing now if omitted.
+
+The first radio_queue_size tracks of the mix are queued, and more are added in the background
+as the queue drains, from the mix of the last queued track. Depending on radio_mode, the tracks
+are streamed or downloaded to the stock first. Tracks skipped with 'ytpl next' are not queued
+again until the player is stopped.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if appState.PID == 0 {
+			// A new session; forget the tracks skipped in the last one
+			appState.SkippedTracks = nil
+		}
+
+		exclude := make(map[string]bool)
+		for _, id := range appState.SkippedTracks {
+			exclude[id] = true
+		}
+
+		var seedID string
+		if len(args) == 1 {
+			id, ok := yt.ParseTrackRef(args[0])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "\n- '%s' is not a YouTube URL or video ID.\n\n", args[0])
+				os.Exit(1)
+			}
+			seedID = id
+			// Play the seed first even if it was skipped before
+			delete(exclude, seedID)
+		} else {
+			if appState.PID != 0 {
+				updateAppStateFromMpvStatus()
+			}
+			if appState.CurrentTrackID == "" {
+				fmt.Print("\n- nothing is playing. give a track to start the radio from.\n\n")
+				return
+			}
+			// The current track keeps playing until the radio starts, so don't play it again
+			seedID = appState.CurrentTrackID
+			exclude[seedID] = true
+		}
+		if yt.SourceName(seedID) != "YouTube" {
+			fmt.Printf("\n- the radio plays YouTube mixes, but '%s' is from %s.\n\n", seedID, yt.SourceName(seedID))
+			return
+		}
+
+		mixSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("fetching the mix of '%s'...", seedID),
+			util.StyleLine,
+		)
+		mix, err := nextRadioTracks(seedID, exclude, cfg.RadioQueueSize)
+		mixSpinner.Stop("")
+		if err != nil {
+			exitWithYtError("fetching the mix", err)
+		}
+		if len(mix) == 0 {
+			fmt.Print("\n- the mix has no tracks left to play.\n\n")
+			return
+		}
+
+		seedTitle := appState.CurrentTrackTitle
+		if len(args) == 1 || seedTitle == "" {
+			seedTitle = mix[0].Title
+		}
+		playRadio(radioPlaylistPrefix+seedTitle, mix)
+
+		if err := startRadioFeeder(seedID); err != nil {
+			log.Printf("warning: more tracks won't be added to the radio: %v", err)
+		}
+	},
+}
+
+// radioFeedCmd keeps the radio's queue filled while it plays. It is started by 'radio'.
+var radioFeedCmd = &cobra.Command{
+	Use:    "feed <track_id>",
+	Short:  "Add tracks to the radio as its queue drains",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		feedRadio(args[0])
+	},
+}
+
+// nextRadioTracks returns the first n tracks of the mix of a video that are not excluded.
+func nextRadioTracks(seedID string, exclude map[string]bool, n int) ([]yt.TrackInfo, error) {
+	mix, err := yt.FetchMix(cfg, seedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var picked []yt.TrackInfo
+	for _, track := range mix {
+		if len(picked) == n {
+			break
+		}
+		if exclude[track.ID] {
+			continue
+		}
+		exclude[track.ID] = true
+		picked = append(picked, track)
+	}
+	return picked, nil
+}
+
+// playRadio starts playing the radio's first tracks, streamed or downloaded depending on radio_mode.
+func playRadio(name string, mix []yt.TrackInfo) {
+	if cfg.RadioMode == config.RadioDownload {
+		tracksToPlay := make([]playlist.TrackInfo, len(mix))
+		for i, track := range mix {
+			tracksToPlay[i] = playlist.TrackInfo{ID: track.ID}
+		}
+		playPlaylistTracks(name, tracksToPlay)
+		return
+	}
+
+	paths := make([]string, len(mix))
+	for i, track := range mix {
+		paths[i] = radioStreamPath(track.ID)
+	}
+	if err := player.LoadPlaylistIntoPlayer(cfg, appState, paths, 0); err != nil {
+		log.Fatalf("error loading radio into player: %v", err)
+	}
+
+	appState.CurrentTrackID = mix[0].ID
+	appState.CurrentTrackTitle = mix[0].Title
+	appState.CurrentTrackDuration = mix[0].Duration
+	appState.DownloadedFilePath = paths[0]
+	appState.IsPlaying = true
+	appState.CurrentPlaylist = name
+	appState.LastPlayedTrackIndex = 0
+
+	// Ignore error when saving state
+	_ = state.SaveState()
+
+	ShowStatus()
+}
+
+// radioStreamPath returns the local file of a stocked track, or the URL to stream it from.
+func radioStreamPath(trackID string) string {
+	if path, stocked := localTrackPath(trackID); stocked {
+		return path
+	}
+	return yt.TrackURL(trackID)
+}
+
+// feedRadio adds tracks to the end of the radio's queue whenever fewer than half of
+// radio_queue_size are left, until the player stops or plays something else.
+func feedRadio(seedID string) {
+	pid := appState.PID
+	exhausted := make(map[string]bool) // Tracks whose mix had nothing new to add
+	for {
+		time.Sleep(radioPollInterval)
+
+		current, err := state.LoadState(cfg)
+		if err != nil || current.PID != pid || !strings.HasPrefix(current.CurrentPlaylist, radioPlaylistPrefix) {
+			return
+		}
+		appState = current
+
+		queued, pos, err := radioQueue()
+		if err != nil || pos < 0 {
+			return // The player has stopped or played the whole queue
+		}
+		remaining := len(queued) - pos - 1
+		if remaining >= (cfg.RadioQueueSize+1)/2 {
+			continue
+		}
+
+		// Continue from the mix of the last queued track, which leads further away from
+		// the seed than the seed's own mix does
+		from := seedID
+		for i := len(queued) - 1; i >= 0; i-- {
+			if yt.SourceName(queued[i]) == "YouTube" && !exhausted[queued[i]] {
+				from = queued[i]
+				break
+			}
+		}
+		if exhausted[from] {
+			continue
+		}
+
+		exclude := make(map[string]bool)
+		for _, id := range slices.Concat(queued, appState.SkippedTracks) {
+			exclude[id] = true
+		}
+		more, err := nextRadioTracks(from, exclude, cfg.RadioQueueSize-remaining)
+		if err != nil {
+			log.Printf("warning: failed to fetch the mix of %s: %v", from, err)
+			continue
+		}
+		if len(more) == 0 {
+			exhausted[from] = true
+			continue
+		}
+
+		count := len(queued)
+		for _, track := range more {
+			path, ok := radioTrackPath(track)
+			if !ok {
+				continue
+			}
+			if err := player.InsertFile(appState, path, count, count); err != nil {
+				return // The player has stopped
+			}
+			count++
+		}
+	}
+}
+
+// radioQueue returns the track IDs in the player's playlist and the position of the playing one.
+func radioQueue() ([]string, int, error) {
+	pos, err := player.GetProperty(appState, "playlist-pos")
+	if err != nil {
+		return nil, 0, err
+	}
+	position, _ := pos.(float64)
+
+	entries, err := player.GetProperty(appState, "playlist")
+	if err != nil {
+		return nil, 0, err
+	}
+	list, _ := entries.([]interface{})
+	ids := make([]string, 0, len(list))
+	for _, entry := range list {
+		fields, _ := entry.(map[string]interface{})
+		filename, _ := fields["filename"].(string)
+		if player.IsStream(filename) {
+			id, _ := yt.ParseTrackRef(filename)
+			ids = append(ids, id)
+		} else {
+			ids = append(ids, yt.TrackIDFromFileName(filename))
+		}
+	}
+	return ids, int(position), nil
+}
+
+// radioTrackPath returns the path or URL a radio track is played from. In download mode the
+// track is downloaded first; the second return value is false if that fails.
+func radioTrackPath(track yt.TrackInfo) (string, bool) {
+	if cfg.RadioMode != config.RadioDownload {
+		return radioStreamPath(track.ID), true
+	}
+	if path, stocked := localTrackPath(track.ID); stocked {
+		return path, true
+	}
+
+	q, err := downloadQueue()
+	if err != nil {
+		return "", false
+	}
+	if _, err := q.Add(track.ID, track.Title); err != nil {
+		return "", false
+	}
+	if err := newDownloadPool(q, nil).Run(context.Background(), track.ID); err != nil {
+		log.Printf("warning: failed to download %s: %v", track.ID, err)
+	}
+	return localTrackPath(track.ID)
+}
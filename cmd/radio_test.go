@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/state"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func TestRadioCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	configPath := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "ytpl", "config.toml")
+	setRadioConfig := func(t *testing.T, settings string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+download_dir = "`+downloadDir+`"
+player_path = "true"
+radio_queue_size = 2
+`+settings), 0644))
+	}
+	setRadioConfig(t, "")
+
+	var feederSeeds []string
+	previous := startRadioFeeder
+	startRadioFeeder = func(seedID string) error {
+		feederSeeds = append(feederSeeds, seedID)
+		return nil
+	}
+	t.Cleanup(func() { startRadioFeeder = previous })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	mix := []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "Seed Song", Uploader: "Artist", Duration: 1},
+		{ID: "bbbbbbbbbbb", Title: "Related", Uploader: "Artist", Duration: 1},
+		{ID: "ccccccccccc", Title: "Also Related", Uploader: "Other", Duration: 1},
+		{ID: "ddddddddddd", Title: "Further Away", Uploader: "Other", Duration: 1},
+	}
+	fake.SetPlaylist(yt.MixURL("aaaaaaaaaaa"), mix...)
+	for _, track := range mix {
+		fake.AddVideo(ytfake.Video{Info: track})
+	}
+
+	t.Run("streams the first tracks of the mix", func(t *testing.T) {
+		runCommand(t, "radio", "https://www.youtube.com/watch?v=aaaaaaaaaaa")
+
+		assert.Equal(t, []string{"playlist:" + yt.MixURL("aaaaaaaaaaa")}, fake.Calls())
+		assert.Equal(t, []string{"aaaaaaaaaaa"}, feederSeeds)
+		assert.NoFileExists(t, filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"))
+
+		current := state.GetState()
+		assert.Equal(t, radioPlaylistPrefix+"Seed Song", current.CurrentPlaylist)
+		assert.Equal(t, "aaaaaaaaaaa", current.CurrentTrackID)
+		assert.Equal(t, yt.TrackURL("aaaaaaaaaaa"), current.DownloadedFilePath)
+	})
+
+	t.Run("leaves out queued and skipped tracks", func(t *testing.T) {
+		exclude := map[string]bool{"aaaaaaaaaaa": true, "bbbbbbbbbbb": true}
+		more, err := nextRadioTracks("aaaaaaaaaaa", exclude, 5)
+		require.NoError(t, err)
+		require.Len(t, more, 2)
+		assert.Equal(t, "ccccccccccc", more[0].ID)
+		assert.Equal(t, "ddddddddddd", more[1].ID)
+	})
+
+	t.Run("downloads the tracks in download mode", func(t *testing.T) {
+		setRadioConfig(t, `radio_mode = "download"`)
+		// Skip a track while a player is running
+		player := exec.Command("sleep", "30")
+		require.NoError(t, player.Start())
+		t.Cleanup(func() { player.Process.Kill(); player.Wait() })
+		current := state.GetState()
+		current.PID = player.Process.Pid
+		current.SkippedTracks = []string{"bbbbbbbbbbb"}
+		require.NoError(t, state.SaveState())
+
+		runCommand(t, "radio", "aaaaaaaaaaa")
+
+		assert.FileExists(t, filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"))
+		assert.FileExists(t, filepath.Join(downloadDir, "ccccccccccc.mp3"))
+		assert.NoFileExists(t, filepath.Join(downloadDir, "bbbbbbbbbbb.mp3"))
+		assert.Equal(t, radioPlaylistPrefix+"Seed Song", state.GetState().CurrentPlaylist)
+		assert.Equal(t, []string{"bbbbbbbbbbb"}, state.GetState().SkippedTracks)
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 
 	"ytpl/internal/tracks"
@@ -15,9 +14,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// rebuildRefetchMetadata makes rebuild fetch the metadata of every track again.
+var rebuildRefetchMetadata bool
+
 var rebuildCmd = &cobra.Command{
 	Use:   "rebuild",
 	Short: "Rebuild the .tracks file from existing downloads",
+	Long: `Rebuild the .tracks file from the audio files and .info.json files in the download directory.
+With --refetch-metadata, the metadata of every track is fetched again without downloading it,
+restoring the fields of metadata_fields that were removed from older .info.json files.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Initialize track manager
 		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
@@ -37,7 +42,7 @@ var rebuildCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Scan download directory for MP3 files
+		// Scan download directory for audio files
 		// Scanning download directory
 		files, err := ioutil.ReadDir(cfg.DownloadDir)
 		if err != nil {
@@ -51,10 +56,12 @@ var rebuildCmd = &cobra.Command{
 		errChan := make(chan error, 1)
 		sem := make(chan struct{}, 10) // Limit concurrent goroutines
 		processed := 0
+		var refetchFailed []string
+		var refetchMu sync.Mutex
 
 		for _, file := range files {
-			// Skip non-MP3 files and directories
-			if file.IsDir() || !strings.HasSuffix(strings.ToLower(file.Name()), ".mp3") {
+			// Skip directories and files that aren't audio files of a supported format
+			if file.IsDir() || !yt.IsAudioFile(file.Name()) {
 				continue
 			}
 
@@ -65,6 +72,16 @@ var rebuildCmd = &cobra.Command{
 				sem <- struct{}{} // Acquire semaphore
 				defer func() { <-sem }() // Release semaphore
 
+				// Fetch the metadata again before reading it
+				if rebuildRefetchMetadata {
+					trackID := yt.TrackIDFromFileName(f.Name())
+					if err := yt.RefetchInfoJSON(cfg, trackID); err != nil {
+						refetchMu.Lock()
+						refetchFailed = append(refetchFailed, fmt.Sprintf("%s: %v", trackID, err))
+						refetchMu.Unlock()
+					}
+				}
+
 				// Process the file
 				if err := processFile(f, trackManager); err != nil {
 					// Non-fatal error, just log it
@@ -98,14 +115,20 @@ var rebuildCmd = &cobra.Command{
 		}
 
 		fmt.Fprintf(os.Stdout, "rebuild completed. Processed %d files.\n", processed)
+		if len(refetchFailed) > 0 {
+			fmt.Fprintf(os.Stderr, "failed to refetch the metadata of %d tracks, their existing metadata was kept:\n", len(refetchFailed))
+			for _, failure := range refetchFailed {
+				fmt.Fprintf(os.Stderr, "  %s\n", failure)
+			}
+		}
 	},
 }
 
 // processFile processes a single file and adds it to the track manager
 func processFile(file os.FileInfo, trackManager *tracks.Manager) error {
 	// Extract video ID from filename
-	videoID := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
-	infoPath := filepath.Join(cfg.DownloadDir, videoID+".info.json")
+	videoID := yt.TrackIDFromFileName(file.Name())
+	infoPath := filepath.Join(cfg.DownloadDir, yt.FileStem(videoID)+".info.json")
 
 	// Processing file: %s
 
@@ -147,6 +170,9 @@ func processFile(file os.FileInfo, trackManager *tracks.Manager) error {
 	}
 	// Adding track: %s - %s
 
+	// Record where the audio file is, as its extension depends on the audio format
+	trackInfo.FilePath = filepath.Join(cfg.DownloadDir, file.Name())
+
 	// Add track to library
 	if err := trackManager.AddTrack(trackInfo); err != nil {
 		return fmt.Errorf("failed to add track %s: %w", videoID, err)
@@ -156,5 +182,6 @@ func processFile(file os.FileInfo, trackManager *tracks.Manager) error {
 }
 
 func init() {
+	rebuildCmd.Flags().BoolVar(&rebuildRefetchMetadata, "refetch-metadata", false, "fetch the metadata of every track again without downloading it")
 	rootCmd.AddCommand(rebuildCmd)
 }
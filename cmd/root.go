@@ -57,6 +57,8 @@ func init() {
 	// Add subcommands
 	rootCmd.AddCommand(playCmd)
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(findCmd)
+	rootCmd.AddCommand(streamCmd)
 	rootCmd.AddCommand(volCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(delCmd)
@@ -67,6 +69,7 @@ func init() {
 	rootCmd.AddCommand(nextCmd)
 	rootCmd.AddCommand(prevCmd)
 	rootCmd.AddCommand(editCmd) // NEW: Added edit command
+	rootCmd.AddCommand(tidyCmd)
 
 	// List command and its subcommands
 	rootCmd.AddCommand(listCmd)
@@ -77,6 +80,53 @@ func init() {
 	listCmd.AddCommand(listShowCmd)
 	listCmd.AddCommand(listPlayCmd)
 	listCmd.AddCommand(listShuffleCmd) // NEW: Subcommand for shuffling a specific playlist
+	listCmd.AddCommand(listSyncCmd)
+	listCmd.AddCommand(listImportCmd)
+
+	// Album command and its subcommands
+	rootCmd.AddCommand(albumCmd)
+	albumCmd.AddCommand(albumLsCmd)
+	albumCmd.AddCommand(albumPlayCmd)
+	albumCmd.AddCommand(albumAddCmd)
+	albumCmd.AddCommand(albumNumberCmd)
+
+	// Tags command and its subcommands
+	rootCmd.AddCommand(tagsCmd)
+	tagsCmd.AddCommand(tagsSyncCmd)
+
+	// User tag command and its subcommands
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	tagCmd.AddCommand(tagLsCmd)
+
+	// Radio command and its feeder
+	rootCmd.AddCommand(radioCmd)
+	radioCmd.AddCommand(radioFeedCmd)
+
+	// Download queue command and its subcommands
+	rootCmd.AddCommand(dlCmd)
+	dlCmd.AddCommand(dlAddCmd)
+	dlCmd.AddCommand(dlLsCmd)
+	dlCmd.AddCommand(dlRetryCmd)
+	dlCmd.AddCommand(dlCancelCmd)
+	dlCmd.AddCommand(dlWorkerCmd)
+
+	// Subscriptions and their sync
+	rootCmd.AddCommand(subCmd)
+	subCmd.AddCommand(subAddCmd)
+	subCmd.AddCommand(subLsCmd)
+	subCmd.AddCommand(subRmCmd)
+	rootCmd.AddCommand(syncCmd)
+
+	// Search cache command and its subcommands
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+
+	// Import command and its subcommands
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importListCmd)
 
 	// Setup signal handling for graceful shutdown (e.g., Ctrl+C)
 	c := make(chan os.Signal, 1)
@@ -3,11 +3,16 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"ytpl/internal/config"
 	"ytpl/internal/player"
+	"ytpl/internal/playlist"
+	"ytpl/internal/searchcache"
 	"ytpl/internal/state"
 	trackpkg "ytpl/internal/tracks"
 	"ytpl/internal/util"
@@ -18,56 +23,272 @@ import (
 )
 
 
+// loadMoreLabel is the picker entry that fetches the next page of search results.
+const loadMoreLabel = "▼ load more results"
+
+// pickSearchResult shows the search results in fzf and returns the indices of the chosen tracks.
+// Each result shows its match score against match. Several results can be chosen with tab.
+// If loadMore is true, a "load more results" entry follows the results; choosing it returns
+// the index len(tracks).
+// It is a variable so that tests can pick results without a terminal.
+var pickSearchResult = func(tracks []yt.TrackInfo, match yt.MatchQuery, loadMore bool) ([]int, error) {
+	f, err := fuzzyfinder.New(
+		fuzzyfinder.WithPrompt("[ search ] > "),
+		fuzzyfinder.WithNoLimit(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize fzf: %w", err)
+	}
+
+	entries := len(tracks)
+	if loadMore {
+		entries++
+	}
+	marks := markSearchResults(tracks, match)
+
+	// Show fzf prompt with the results' metadata and a preview of the highlighted one
+	return f.Find(
+		make([]struct{}, entries),
+		func(i int) string {
+			if i == len(tracks) {
+				return loadMoreLabel
+			}
+			return formatSearchResult(i, tracks[i], marks)
+		},
+		fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+			if i < 0 || i >= len(tracks) {
+				return "fetch the next page of results"
+			}
+			return searchResultPreview(tracks[i], marks, width)
+		}),
+	)
+}
+
+// searchResultMarks records which search results are already stocked or in playlists,
+// and how well they match the search.
+type searchResultMarks struct {
+	stocked   map[string]string   // Track ID -> path of the local file
+	playlists map[string][]string // Track ID -> names of the playlists containing it
+	scores    map[string]int      // Track ID -> match score from 0 to 100
+}
+
+// markSearchResults finds the search results that are already stocked or in playlists,
+// and scores them against match.
+func markSearchResults(tracks []yt.TrackInfo, match yt.MatchQuery) searchResultMarks {
+	marks := searchResultMarks{
+		stocked:   make(map[string]string),
+		playlists: make(map[string][]string),
+		scores:    make(map[string]int),
+	}
+	trackManager := loadLibrary()
+	for _, track := range tracks {
+		if path, stocked := stockedTrackPath(trackManager, track.ID); stocked {
+			marks.stocked[track.ID] = path
+		}
+		marks.scores[track.ID] = yt.ScoreMatch(track, match)
+	}
+
+	names, err := playlist.ListAllPlaylists()
+	if err != nil {
+		return marks
+	}
+	for _, name := range names {
+		p, err := playlist.LoadPlaylist(name)
+		if err != nil {
+			continue
+		}
+		for _, track := range p.Tracks {
+			marks.playlists[track.ID] = append(marks.playlists[track.ID], name)
+		}
+	}
+	return marks
+}
+
+// formatSearchResult formats a search result as a picker line with its metadata in columns.
+// The line starts with ✓ if the track is stocked and ♪ if it's in a playlist,
+// and the match score follows the duration.
+func formatSearchResult(i int, track yt.TrackInfo, marks searchResultMarks) string {
+	stockedMark, playlistMark := " ", " "
+	if _, ok := marks.stocked[track.ID]; ok {
+		stockedMark = "✓"
+	}
+	if len(marks.playlists[track.ID]) > 0 {
+		playlistMark = "♪"
+	}
+
+	views := ""
+	if track.ViewCount > 0 {
+		views = util.FormatCount(track.ViewCount)
+	}
+	durationStr := strings.Trim(util.FormatDuration(track.Duration), "[]")
+	// Format index with leading zeros (e.g., 01, 02, ..., 10, 11, ...)
+	return fmt.Sprintf("%s%s %02d:[%s] %3d  %s  %s  %6s  %s",
+		stockedMark, playlistMark, i+1, durationStr, marks.scores[track.ID],
+		util.FitWidth(track.Title, 50), util.FitWidth(track.Uploader, 20),
+		views, util.FormatUploadDate(track.UploadDate))
+}
+
+// searchResultPreview returns the preview pane content of a search result.
+func searchResultPreview(track yt.TrackInfo, marks searchResultMarks, width int) string {
+	url := track.WebpageURL
+	if url == "" {
+		url = yt.TrackURL(track.ID)
+	}
+
+	var b strings.Builder
+	b.WriteString(util.Wrap(track.Title, width) + "\n\n")
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(util.Wrap(fmt.Sprintf("%-10s %s", name+":", value), width) + "\n")
+		}
+	}
+	field("channel", track.Uploader)
+	field("duration", strings.Trim(util.FormatDuration(track.Duration), "[]"))
+	field("match", fmt.Sprintf("%d/100", marks.scores[track.ID]))
+	if track.ViewCount > 0 {
+		field("views", fmt.Sprintf("%d", track.ViewCount))
+	}
+	field("uploaded", util.FormatUploadDate(track.UploadDate))
+	field("url", url)
+	if path, ok := marks.stocked[track.ID]; ok {
+		field("stocked", path)
+	}
+	field("playlists", strings.Join(marks.playlists[track.ID], ", "))
+	if track.Description != "" {
+		b.WriteString("\n" + util.Wrap(track.Description, width) + "\n")
+	}
+	return b.String()
+}
+
+// pickSearchHistory shows the recent queries in fzf and returns the index of the chosen one.
+// It is a variable so that tests can pick a query without a terminal.
+var pickSearchHistory = func(history []searchcache.HistoryEntry) (int, error) {
+	f, err := fuzzyfinder.New(fuzzyfinder.WithPrompt("[ search history ] > "))
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize fzf: %w", err)
+	}
+	idxs, err := f.Find(
+		history,
+		func(i int) string {
+			return fmt.Sprintf("%s  %s", history[i].Searched.Local().Format("2006-01-02 15:04"), history[i].Query)
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if len(idxs) == 0 {
+		return 0, fuzzyfinder.ErrAbort
+	}
+	return idxs[0], nil
+}
+
+var (
+	searchRefresh       bool
+	searchStream        bool
+	searchMinDuration   string
+	searchMaxDuration   string
+	searchChannel       string
+	searchExclude       string
+	searchAfter         string
+	searchSort          string
+	searchBest          bool
+	searchDuration      string
+	searchMusicMode     bool
+	searchAlbumPlaylist bool
+)
+
 var searchCmd = &cobra.Command{
-	Use:   "search <query>",
+	Use:   "search [query]",
 	Short: "Search YouTube for music",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Search YouTube for music and play the chosen result.
+The result is downloaded to the stock, or streamed without downloading with --stream.
+
+Results can be narrowed down by duration (e.g. "2m", "1h", or seconds), channel name,
+a regular expression on titles to exclude, and upload date, and sorted by views or date.
+--after fetches the full metadata of each result, which makes the search slower.
+Choose "load more results" in the list to fetch the next page of results.
+
+Each result is scored from 0 to 100 by how well it matches the query: query words in the
+title or channel, "official audio" titles and "Topic" channels count for it, live versions,
+covers, loops and slowed or sped up edits against it, unless the query asks for them.
+--duration adds the closeness to an expected duration; --best plays the top-scoring
+result without showing the list.
+
+Results are cached for search_cache_ttl; use --refresh to search again anyway.
+Without a query, a list of recent queries is shown to pick from.
+
+A URL instead of a query plays that track: a YouTube video, or a track from another site
+yt-dlp supports, such as SoundCloud, Bandcamp or Vimeo.
+
+--music searches YouTube Music instead, listing songs, albums and artists. A chosen album is
+downloaded as a whole, with its name and track numbers recorded in the library, and played in
+album order; --album-playlist also saves it as a playlist. Choosing an artist lists their songs
+and albums. The other filters don't apply to YouTube Music searches.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		query := strings.Join(args, " ")
-		// Show searching spinner with query
-		sanitizedQuery := strings.ReplaceAll(query, "\n", " ")
-		// Use line style for search
-		searchSpinner := util.NewSpinnerWithStyle(
-			fmt.Sprintf("searching '%s'...", sanitizedQuery),
-			util.StyleLine,
-		)
-		tracks, err := yt.SearchYouTube(cfg, query)
-		searchSpinner.Stop("")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error searching YouTube: %v\n", err)
-			os.Exit(1)
+		if strings.TrimSpace(query) == "" {
+			query = queryFromHistory()
+			if query == "" {
+				return
+			}
+		}
+		if player.IsStream(strings.TrimSpace(query)) {
+			playTrackURL(strings.TrimSpace(query))
+			return
+		}
+		recordSearchQuery(query)
+		if searchMusicMode {
+			searchMusic(query)
+			return
 		}
 
+		opts := searchOptionsFromFlags()
+		match := yt.MatchQuery{Query: query}
+		if searchDuration != "" {
+			seconds, err := parseDurationFlag(searchDuration)
+			if err != nil {
+				log.Fatalf("invalid --duration: %v", err)
+			}
+			match.Duration = seconds
+		}
+		tracks := fetchSearchPage(query, opts)
 		if len(tracks) == 0 {
-			fmt.Println("\n- no results found.\n")
+			fmt.Print("\n- no results found.\n\n")
 			return
 		}
 
-		// Initialize fzf
-		f, err := fuzzyfinder.New()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error initializing fzf: %v\n", err)
-			os.Exit(1)
+		if searchBest {
+			best, score := yt.BestMatch(tracks, match)
+			fmt.Printf("\n- best match (score %d): %s\n", score, tracks[best].Title)
+			playSearchResult(tracks[best])
+			return
 		}
 
-		// Show fzf prompt with basic info
-		idxs, err := f.Find(
-			tracks,
-			func(i int) string {
-				// Format index with leading zeros (e.g., 01, 02, ..., 10, 11, ...)
-				indexStr := fmt.Sprintf("%02d", i+1)
-				durationStr := strings.Trim(util.FormatDuration(tracks[i].Duration), "[]")
-				return fmt.Sprintf("%s:[%s] - %s", indexStr, durationStr, tracks[i].Title)
-			},
-		)
+		// Let the user choose a track, fetching more results on request
+		loadMore := true
+		var idxs []int
+		for {
+			var err error
+			idxs, err = pickSearchResult(tracks, match, loadMore)
+			if err != nil {
+				if err == fuzzyfinder.ErrAbort {
+					fmt.Print("\n- search cancelled.\n\n")
+					return
+				}
+				fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
+				os.Exit(1)
+			}
+			if len(idxs) == 0 || idxs[0] < len(tracks) {
+				break
+			}
 
-		if err != nil {
-			if err == fuzzyfinder.ErrAbort {
-				fmt.Println("\n- search cancelled.\n")
-				return
+			opts.Page++
+			more := newSearchResults(tracks, fetchSearchPage(query, opts))
+			if len(more) == 0 {
+				loadMore = false
+				fmt.Print("\n- no more results.\n")
 			}
-			fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
-			os.Exit(1)
+			tracks = append(tracks, more...)
 		}
 
 		// Get the selected track
@@ -75,64 +296,288 @@ var searchCmd = &cobra.Command{
 			fmt.Fprintln(os.Stderr, "No track selected")
 			os.Exit(1)
 		}
-		selectedTrack := tracks[idxs[0]]
+		if len(idxs) > 1 {
+			picked := make([]yt.TrackInfo, len(idxs))
+			for i, idx := range idxs {
+				picked[i] = tracks[idx]
+			}
+			playSearchResults(picked)
+			return
+		}
+		playSearchResult(tracks[idxs[0]])
+	},
+}
+
+// playSearchResult plays a chosen search result, from the stock if it's there,
+// otherwise after downloading it, or streamed with --stream.
+func playSearchResult(selectedTrack yt.TrackInfo) {
+	// Check if track already exists locally
+	localTrackInfo, err := yt.GetLocalTrackInfo(cfg, selectedTrack.ID)
+	localPath, stocked := localTrackPath(selectedTrack.ID)
+	var downloadedFilePath string
+	var finalTrackInfo *yt.TrackInfo
 
-		// Check if track already exists locally
-		localTrackInfo, err := yt.GetLocalTrackInfo(cfg, selectedTrack.ID)
-		var downloadedFilePath string
-		var finalTrackInfo *yt.TrackInfo
+	if err == nil && stocked {
+		// Use existing local file
+		downloadedFilePath = localPath
+		// Use the local track info but preserve the title from search results
+		// as it might be more up-to-date
+		finalTrackInfo = localTrackInfo
+		finalTrackInfo.Title = selectedTrack.Title
+		finalTrackInfo.FilePath = localPath
 
+		// Add the track to the library unless it's already there with a possibly edited title
+		trackManager, err := trackpkg.NewManager("", cfg.DownloadDir)
 		if err == nil {
-			// Use existing local file
-			downloadedFilePath = filepath.Join(cfg.DownloadDir, selectedTrack.ID+".mp3")
-			// Use the local track info but preserve the title from search results
-			// as it might be more up-to-date
-			finalTrackInfo = localTrackInfo
-			finalTrackInfo.Title = selectedTrack.Title
-		} else {
-			// Download the track if not found locally
-			sanitizedTitle := strings.ReplaceAll(selectedTrack.Title, "\n", " ")
-			// Use line style for download
-			downloadSpinner := util.NewSpinnerWithStyle(
-				fmt.Sprintf("downloading '%s'...", sanitizedTitle),
-				util.StyleLine,
-			)
-			downloadedFilePath, finalTrackInfo, err = yt.DownloadTrack(cfg, selectedTrack.ID)
-			if err != nil {
-				downloadSpinner.StopWithError(fmt.Sprintf("failed to download '%s'", sanitizedTitle))
-			} else {
-				downloadSpinner.Stop("")
+			if _, exists := trackManager.GetTrack(selectedTrack.ID); !exists {
+				_ = trackManager.AddTrack(*finalTrackInfo)
 			}
+		}
+	} else if searchStream {
+		// Preview the track without adding it to the stock
+		streamTrack(&selectedTrack)
+		return
+	} else {
+		// Download the track through the download queue if not found locally
+		item, err := downloadNow(selectedTrack.ID, selectedTrack.Title)
+		if err != nil {
+			exitWithYtError("downloading track", err)
+		}
+		downloadedFilePath = item.FilePath
 
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error downloading track: %v\n", err)
-				os.Exit(1)
-			}
+		finalTrackInfo, err = yt.GetLocalTrackInfo(cfg, selectedTrack.ID)
+		if err != nil {
+			finalTrackInfo = &selectedTrack
 		}
+	}
 
-		// Initialize track manager
-		tracksDir := filepath.Dir(cfg.DownloadDir)
-		trackManager, err := trackpkg.NewManager("", tracksDir)
-		if err == nil {
-			// Add downloaded track to the library
-			_ = trackManager.AddTrack(*finalTrackInfo)
+	playTrackFile(finalTrackInfo, downloadedFilePath)
+}
+
+// playTrackURL plays the track at a URL like a chosen search result.
+func playTrackURL(rawURL string) {
+	trackID, ok := yt.ParseTrackRef(rawURL)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "\n- '%s' is not a supported track URL.\n\n", rawURL)
+		os.Exit(1)
+	}
+
+	track, err := yt.GetLocalTrackInfo(cfg, trackID)
+	if _, stocked := localTrackPath(trackID); !stocked || err != nil {
+		infoSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("fetching '%s' from %s...", rawURL, yt.SourceName(trackID)),
+			util.StyleLine,
+		)
+		track, err = yt.FetchTrackInfo(cfg, trackID)
+		infoSpinner.Stop("")
+		if err != nil {
+			exitWithYtError("fetching track info", err)
 		}
+	}
+	playSearchResult(*track)
+}
 
-		if err := player.StartPlayer(cfg, appState, downloadedFilePath); err != nil {
-			fmt.Fprintf(os.Stderr, "Error starting player: %v\n", err)
-			os.Exit(1)
+// searchResultsPlaylist is the name shown for several search results played together.
+const searchResultsPlaylist = "search results"
+
+// playSearchResults plays several chosen search results in order. Stocked tracks start playing
+// right away and the others are downloaded through the queue and added as they finish,
+// or streamed with --stream.
+func playSearchResults(picked []yt.TrackInfo) {
+	if !searchStream {
+		tracksToPlay := make([]playlist.TrackInfo, len(picked))
+		for i, track := range picked {
+			tracksToPlay[i] = playlist.TrackInfo{ID: track.ID}
 		}
+		playPlaylistTracks(searchResultsPlaylist, tracksToPlay)
+		return
+	}
 
-		appState.CurrentTrackID = finalTrackInfo.ID
-		appState.CurrentTrackTitle = finalTrackInfo.Title
-		appState.DownloadedFilePath = downloadedFilePath
-		appState.IsPlaying = true
-		appState.CurrentPlaylist = ""
+	paths := make([]string, len(picked))
+	for i, track := range picked {
+		path, stocked := localTrackPath(track.ID)
+		if !stocked {
+			path = yt.TrackURL(track.ID)
+		}
+		paths[i] = path
+	}
+	if err := player.LoadPlaylistIntoPlayer(cfg, appState, paths, 0); err != nil {
+		log.Fatalf("error loading search results into player: %v", err)
+	}
 
-		// Ignore error when saving state
-		_ = state.SaveState()
+	appState.CurrentTrackID = picked[0].ID
+	appState.CurrentTrackTitle = picked[0].Title
+	appState.CurrentTrackDuration = picked[0].Duration
+	appState.DownloadedFilePath = paths[0]
+	appState.IsPlaying = true
+	appState.CurrentPlaylist = searchResultsPlaylist
+	appState.LastPlayedTrackIndex = 0
 
-		// Show status after starting player
-		ShowStatus()
-	},
+	// Ignore error when saving state
+	_ = state.SaveState()
+
+	ShowStatus()
+}
+
+// searchOptionsFromFlags returns the search filters and sort order given on the command line.
+func searchOptionsFromFlags() yt.SearchOptions {
+	opts := yt.SearchOptions{Channel: searchChannel, Sort: searchSort}
+	if searchMinDuration != "" {
+		seconds, err := parseDurationFlag(searchMinDuration)
+		if err != nil {
+			log.Fatalf("invalid --min-duration: %v", err)
+		}
+		opts.MinDuration = seconds
+	}
+	if searchMaxDuration != "" {
+		seconds, err := parseDurationFlag(searchMaxDuration)
+		if err != nil {
+			log.Fatalf("invalid --max-duration: %v", err)
+		}
+		opts.MaxDuration = seconds
+	}
+	if searchExclude != "" {
+		re, err := regexp.Compile(searchExclude)
+		if err != nil {
+			log.Fatalf("invalid --exclude: %v", err)
+		}
+		opts.Exclude = re
+	}
+	if searchAfter != "" {
+		date, err := parseDateFlag(searchAfter)
+		if err != nil {
+			log.Fatalf("invalid --after: %v", err)
+		}
+		opts.After = date
+	}
+	if !yt.IsSearchSort(searchSort) {
+		log.Fatalf("invalid --sort '%s': use one of %s", searchSort, strings.Join(yt.SearchSorts, ", "))
+	}
+	return opts
+}
+
+// fetchSearchPage searches YouTube for a page of results, showing a spinner.
+// Results are served from the search cache unless they expired or --refresh is given.
+func fetchSearchPage(query string, opts yt.SearchOptions) []yt.TrackInfo {
+	// Show searching spinner with query
+	sanitizedQuery := strings.ReplaceAll(query, "\n", " ")
+	message := fmt.Sprintf("searching '%s'...", sanitizedQuery)
+	if opts.Page > 0 {
+		message = fmt.Sprintf("loading more results for '%s'...", sanitizedQuery)
+	}
+	// Use line style for search
+	searchSpinner := util.NewSpinnerWithStyle(message, util.StyleLine)
+	tracks, err := cachedSearch(query, opts, searchRefresh)
+	searchSpinner.Stop("")
+	if err != nil {
+		exitWithYtError("searching YouTube", err)
+	}
+	return tracks
+}
+
+// cachedSearch searches YouTube, serving the results from the search cache if refresh is false
+// and caching new results.
+func cachedSearch(query string, opts yt.SearchOptions, refresh bool) ([]yt.TrackInfo, error) {
+	cache := searchCache()
+	key := searchcache.Key(query, cfg.MaxSearchResults, opts)
+	if !refresh {
+		if tracks, ok := cache.Get(key); ok {
+			return tracks, nil
+		}
+	}
+
+	tracks, err := yt.SearchYouTubeWithOptions(cfg, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(key, query, tracks); err != nil {
+		log.Printf("warning: failed to cache search results: %v", err)
+	}
+	return tracks, nil
+}
+
+// searchCache returns the search result cache.
+func searchCache() *searchcache.Cache {
+	return searchcache.New(config.GetSearchCacheDir(), cfg.SearchCacheMaxAge())
+}
+
+// recordSearchQuery adds a query to the search history.
+func recordSearchQuery(query string) {
+	path, err := config.GetSearchHistoryPath()
+	if err == nil {
+		err = searchcache.AddToHistory(path, query)
+	}
+	if err != nil {
+		log.Printf("warning: failed to update search history: %v", err)
+	}
+}
+
+// queryFromHistory lets the user pick a recent query, returning "" if there is none or
+// the user cancelled.
+func queryFromHistory() string {
+	path, err := config.GetSearchHistoryPath()
+	if err != nil {
+		log.Fatalf("error getting search history path: %v", err)
+	}
+	history, err := searchcache.LoadHistory(path)
+	if err != nil {
+		log.Fatalf("error loading search history: %v", err)
+	}
+	if len(history) == 0 {
+		fmt.Print("\n- no recent searches. use 'ytpl search <query>'.\n\n")
+		return ""
+	}
+
+	idx, err := pickSearchHistory(history)
+	if err != nil {
+		if err == fuzzyfinder.ErrAbort {
+			fmt.Print("\n- search cancelled.\n\n")
+			return ""
+		}
+		fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
+		os.Exit(1)
+	}
+	return history[idx].Query
+}
+
+// newSearchResults returns the results of more that aren't in tracks already.
+// Pages can overlap when YouTube reorders results between requests.
+func newSearchResults(tracks, more []yt.TrackInfo) []yt.TrackInfo {
+	seen := make(map[string]bool)
+	for _, track := range tracks {
+		seen[track.ID] = true
+	}
+	var fresh []yt.TrackInfo
+	for _, track := range more {
+		if !seen[track.ID] {
+			seen[track.ID] = true
+			fresh = append(fresh, track)
+		}
+	}
+	return fresh
+}
+
+// parseDateFlag parses a date such as "2024-01-31" or "20240131" into yt-dlp's YYYYMMDD format.
+func parseDateFlag(s string) (string, error) {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date.Format("20060102"), nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is not a date like \"2024-01-31\"", s)
+}
+
+func init() {
+	searchCmd.Flags().BoolVar(&searchRefresh, "refresh", false, "search YouTube again instead of using cached results")
+	searchCmd.Flags().BoolVar(&searchStream, "stream", false, "stream the chosen result instead of downloading it")
+	searchCmd.Flags().StringVar(&searchMinDuration, "min-duration", "", "skip results shorter than this (e.g. 2m)")
+	searchCmd.Flags().StringVar(&searchMaxDuration, "max-duration", "", "skip results longer than this (e.g. 10m)")
+	searchCmd.Flags().StringVar(&searchChannel, "channel", "", "only show results from channels whose name contains this")
+	searchCmd.Flags().StringVar(&searchExclude, "exclude", "", "skip results whose title matches this regular expression")
+	searchCmd.Flags().StringVar(&searchAfter, "after", "", "only show results uploaded on or after this date (e.g. 2024-01-31)")
+	searchCmd.Flags().StringVar(&searchSort, "sort", yt.SortRelevance, "sort results by relevance, date or views")
+	searchCmd.Flags().BoolVar(&searchBest, "best", false, "play the result matching the query best without showing the list")
+	searchCmd.Flags().StringVar(&searchDuration, "duration", "", "expected duration of the track, to score results by (e.g. 3m30s)")
+	searchCmd.Flags().BoolVar(&searchMusicMode, "music", false, "search YouTube Music for songs, albums and artists")
+	searchCmd.Flags().BoolVar(&searchAlbumPlaylist, "album-playlist", false, "with --music, save a chosen album as a playlist named after it")
 }
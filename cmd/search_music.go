@@ -0,0 +1,203 @@
+// cmd/search_music.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	fuzzyfinder "github.com/koki-develop/go-fzf"
+
+	"ytpl/internal/album"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// musicSectionLabels are the labels of YouTube Music results in the picker.
+var musicSectionLabels = map[string]string{
+	yt.MusicSongs:   "song  ",
+	yt.MusicAlbums:  "album ",
+	yt.MusicArtists: "artist",
+}
+
+// pickMusicResult shows YouTube Music results grouped by section in fzf and returns the index
+// of the chosen one.
+// It is a variable so that tests can pick results without a terminal.
+var pickMusicResult = func(results []yt.MusicResult) (int, error) {
+	f, err := fuzzyfinder.New(fuzzyfinder.WithPrompt("[ music ] > "))
+	if err != nil {
+		return -1, fmt.Errorf("failed to initialize fzf: %w", err)
+	}
+
+	idxs, err := f.Find(results, func(i int) string { return formatMusicResult(results[i]) })
+	if err != nil {
+		return -1, err
+	}
+	if len(idxs) == 0 {
+		return -1, fuzzyfinder.ErrAbort
+	}
+	return idxs[0], nil
+}
+
+// formatMusicResult formats a YouTube Music result as a picker line.
+func formatMusicResult(result yt.MusicResult) string {
+	if result.Section != yt.MusicSongs {
+		return fmt.Sprintf("%s         %s", musicSectionLabels[result.Section], result.Title)
+	}
+	durationStr := strings.Trim(util.FormatDuration(result.Duration), "[]")
+	return fmt.Sprintf("%s [%5s]  %s  %s", musicSectionLabels[result.Section], durationStr,
+		util.FitWidth(result.Title, 50), result.Artist)
+}
+
+// searchMusic searches YouTube Music and plays the chosen song or album. Choosing an artist
+// lists the artist's songs and albums to choose from.
+func searchMusic(query string, sections ...string) {
+	sanitizedQuery := strings.ReplaceAll(query, "\n", " ")
+	searchSpinner := util.NewSpinnerWithStyle(fmt.Sprintf("searching YouTube Music for '%s'...", sanitizedQuery), util.StyleLine)
+	results, err := yt.SearchMusic(cfg, query, sections...)
+	searchSpinner.Stop("")
+	if err != nil {
+		exitWithYtError("searching YouTube Music", err)
+	}
+	if len(results) == 0 {
+		fmt.Print("\n- no results found.\n\n")
+		return
+	}
+
+	idx, err := pickMusicResult(results)
+	if err != nil {
+		if err == fuzzyfinder.ErrAbort {
+			fmt.Print("\n- search cancelled.\n\n")
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error running fzf: %v\n", err)
+		os.Exit(1)
+	}
+
+	result := results[idx]
+	switch result.Section {
+	case yt.MusicAlbums:
+		playAlbum(result)
+	case yt.MusicArtists:
+		searchMusic(result.Title, yt.MusicSongs, yt.MusicAlbums)
+	default:
+		playSearchResult(result.Track())
+	}
+}
+
+// playAlbum downloads the tracklist of an album, records the album and track numbers in the
+// library and plays it in album order, or streams it with --stream.
+// With --album-playlist, the album is also saved as a playlist named after it.
+func playAlbum(result yt.MusicResult) {
+	albumSpinner := util.NewSpinnerWithStyle(fmt.Sprintf("fetching the tracklist of '%s'...", result.Title), util.StyleLine)
+	albumTracks, err := yt.FetchAlbum(cfg, result)
+	albumSpinner.Stop("")
+	if err != nil {
+		exitWithYtError("fetching the album", err)
+	}
+	if len(albumTracks) == 0 {
+		fmt.Printf("\n- '%s' has no playable tracks.\n\n", result.Title)
+		return
+	}
+	fmt.Printf("\n- %s: %d tracks\n", result.Title, len(albumTracks))
+
+	if searchStream {
+		playSearchResults(albumTracks)
+		return
+	}
+
+	trackIDs := make([]string, len(albumTracks))
+	titles := make(map[string]string)
+	usedIn := make(map[string][]string)
+	for i, track := range albumTracks {
+		trackIDs[i] = track.ID
+		titles[track.ID] = track.Title
+		if searchAlbumPlaylist {
+			usedIn[track.ID] = []string{result.Title}
+		}
+	}
+	downloadMissingTracks(trackIDs, titles, usedIn)
+
+	// Record the album with the tracks that were downloaded, in album order
+	stocked, err := recordAlbumTracks(albumTracks)
+	if err != nil {
+		log.Printf("warning: failed to record the album in the library: %v", err)
+	}
+	if len(stocked) == 0 {
+		fmt.Print("- none of the album's tracks could be downloaded.\n\n")
+		return
+	}
+	a := saveAlbum(result.Title, stocked)
+
+	if searchAlbumPlaylist {
+		added, err := appendToPlaylist(result.Title, a.Tracks)
+		if err != nil {
+			log.Fatalf("error saving playlist '%s': %v", result.Title, err)
+		}
+		fmt.Printf("- added %d tracks to '%s'.\n", added, result.Title)
+	}
+
+	playAlbumTracks(a)
+}
+
+// recordAlbumTracks records the album, artist and track number of the stocked tracks of an album
+// in the library, and returns the library entries of the stocked tracks.
+func recordAlbumTracks(albumTracks []yt.TrackInfo) ([]yt.TrackInfo, error) {
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		return nil, err
+	}
+	trackManager.BatchMode(true)
+	var stocked []yt.TrackInfo
+	for _, albumTrack := range albumTracks {
+		path, ok := stockedTrackPath(trackManager, albumTrack.ID)
+		if !ok {
+			continue
+		}
+		track, exists := trackManager.GetTrack(albumTrack.ID)
+		if !exists {
+			track, err = yt.GetLocalTrackInfo(cfg, albumTrack.ID)
+			if err != nil {
+				track = &albumTrack
+			}
+			track.FilePath = path
+		}
+		track.Album = albumTrack.Album
+		track.TrackNumber = albumTrack.TrackNumber
+		if track.Artist == "" {
+			track.Artist = albumTrack.Artist
+		}
+		if err := trackManager.AddTrack(*track); err != nil {
+			return stocked, err
+		}
+		stocked = append(stocked, *track)
+	}
+	return stocked, trackManager.SaveAll()
+}
+
+// saveAlbum adds tracks to the album with the given title in the library, creating it if needed.
+// The tracks must be recorded in the library with their track number, as the album is kept in
+// track number order: tracks that failed to download before take their place once downloaded.
+func saveAlbum(title string, albumTracks []yt.TrackInfo) *album.Album {
+	path, albums := loadAlbums()
+	index := album.Find(albums, title)
+	if index < 0 {
+		albums = append(albums, &album.Album{Title: title})
+		index = len(albums) - 1
+	}
+	a := albums[index]
+	fillAlbumDetails(a, albumTracks)
+	added := 0
+	for _, track := range albumTracks {
+		added += a.Add(track.ID)
+	}
+	if added > 0 {
+		sortAlbumTracks(a, albumOrderNumber)
+	}
+	if err := album.Save(path, albums); err != nil {
+		log.Printf("warning: failed to save album '%s': %v", title, err)
+	}
+	return a
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/album"
+	"ytpl/internal/state"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func TestSearchMusic(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { searchMusicMode, searchAlbumPlaylist = false, false })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	fake.AddSearchLines(yt.MusicSearchURL("artist", yt.MusicSongs),
+		`{"id": "sssssssssss", "title": "Single", "duration": 200, "channel": "Artist - Topic", "url": "https://music.youtube.com/watch?v=sssssssssss"}`,
+		`{"id": "MPREb_podcast", "title": "Not a song", "url": "https://music.youtube.com/browse/MPREb_podcast"}`)
+	fake.AddSearchLines(yt.MusicSearchURL("artist", yt.MusicAlbums),
+		`{"id": "MPREb_album", "title": "The Album", "url": "https://music.youtube.com/browse/MPREb_album"}`)
+	fake.AddSearchLines(yt.MusicSearchURL("artist", yt.MusicArtists),
+		`{"id": "UCartist", "title": "Artist", "url": "https://music.youtube.com/channel/UCartist"}`)
+
+	albumTracks := []yt.TrackInfo{
+		{ID: "ttttttttttt", Title: "Opener", Uploader: "Artist - Topic", Duration: 1},
+		{ID: "uuuuuuuuuuu", Title: "Closer", Uploader: "Artist - Topic", Duration: 1},
+	}
+	fake.SetPlaylist("https://music.youtube.com/browse/MPREb_album", albumTracks...)
+	for _, track := range append(albumTracks, yt.TrackInfo{ID: "sssssssssss", Title: "Single", Uploader: "Artist - Topic", Duration: 1}) {
+		fake.AddVideo(ytfake.Video{Info: track})
+	}
+
+	var shown []yt.MusicResult
+	pick := func(t *testing.T, section string) {
+		previous := pickMusicResult
+		pickMusicResult = func(results []yt.MusicResult) (int, error) {
+			shown = results
+			for i, result := range results {
+				if result.Section == section {
+					return i, nil
+				}
+			}
+			return -1, nil
+		}
+		t.Cleanup(func() { pickMusicResult = previous })
+	}
+
+	t.Run("groups results and downloads a chosen album", func(t *testing.T) {
+		pick(t, yt.MusicAlbums)
+		runCommand(t, "search", "--music", "--album-playlist", "artist")
+
+		require.Len(t, shown, 3)
+		assert.Equal(t, yt.MusicResult{Section: yt.MusicSongs, ID: "sssssssssss", Title: "Single", Artist: "Artist", Duration: 200,
+			URL: "https://music.youtube.com/watch?v=sssssssssss"}, shown[0])
+		assert.Equal(t, yt.MusicAlbums, shown[1].Section)
+		assert.Equal(t, yt.MusicArtists, shown[2].Section)
+		assert.Equal(t, "song   [ 3:20]  Single", formatMusicResult(shown[0])[:22])
+
+		library := tracks.New(filepath.Join(downloadDir, ".tracks"))
+		require.NoError(t, library.Load())
+		for i, id := range []string{"ttttttttttt", "uuuuuuuuuuu"} {
+			assert.FileExists(t, filepath.Join(downloadDir, id+".mp3"))
+			track, ok := library.Get(id)
+			require.True(t, ok)
+			assert.Equal(t, "The Album", track.Album)
+			assert.Equal(t, "Artist", track.Artist)
+			assert.Equal(t, i+1, track.TrackNumber)
+		}
+		assert.Equal(t, []string{"ttttttttttt", "uuuuuuuuuuu"}, playlistIDs(t, "The Album"))
+		assert.Equal(t, albumPlaylistPrefix+"The Album", state.GetState().CurrentPlaylist)
+
+		albums, err := album.Load(albumsPath())
+		require.NoError(t, err)
+		require.Len(t, albums, 1)
+		assert.Equal(t, &album.Album{Title: "The Album", Artist: "Artist", Tracks: []string{"ttttttttttt", "uuuuuuuuuuu"}}, albums[0])
+	})
+
+	t.Run("plays a chosen song", func(t *testing.T) {
+		pick(t, yt.MusicSongs)
+		runCommand(t, "search", "--music", "artist")
+
+		assert.FileExists(t, filepath.Join(downloadDir, "sssssssssss.mp3"))
+		assert.Equal(t, "sssssssssss", state.GetState().CurrentTrackID)
+	})
+}
+
+func TestSearchMusicAlbumOrder(t *testing.T) {
+	setupTestEnv(t)
+	t.Cleanup(func() { searchMusicMode = false })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	fake.AddSearchLines(yt.MusicSearchURL("album", yt.MusicAlbums),
+		`{"id": "MPREb_album", "title": "The Album", "url": "https://music.youtube.com/browse/MPREb_album"}`)
+	albumTracks := []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "Opener", Uploader: "Artist - Topic", Duration: 1},
+		{ID: "bbbbbbbbbbb", Title: "Middle", Uploader: "Artist - Topic", Duration: 1},
+		{ID: "ccccccccccc", Title: "Closer", Uploader: "Artist - Topic", Duration: 1},
+	}
+	fake.SetPlaylist("https://music.youtube.com/browse/MPREb_album", albumTracks...)
+	// The middle track can't be downloaded the first time
+	fake.AddVideo(ytfake.Video{Info: albumTracks[0]})
+	fake.AddVideo(ytfake.Video{Info: albumTracks[2]})
+
+	previous := pickMusicResult
+	pickMusicResult = func(results []yt.MusicResult) (int, error) { return 0, nil }
+	t.Cleanup(func() { pickMusicResult = previous })
+
+	albumTrackIDs := func() []string {
+		albums, err := album.Load(albumsPath())
+		require.NoError(t, err)
+		require.Len(t, albums, 1)
+		return albums[0].Tracks
+	}
+	runCommand(t, "search", "--music", "album")
+	assert.Equal(t, []string{"aaaaaaaaaaa", "ccccccccccc"}, albumTrackIDs())
+
+	fake.AddVideo(ytfake.Video{Info: albumTracks[1]})
+	runCommand(t, "search", "--music", "album")
+	assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}, albumTrackIDs(),
+		"a track downloaded later takes its place on the album")
+}
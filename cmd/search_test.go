@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/searchcache"
+	"ytpl/internal/state"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+// setupTestEnv points ytpl's config, data and state directories to a temporary directory
+// and returns the download directory. The player is replaced by a command that exits immediately.
+func setupTestEnv(t *testing.T) string {
+	home := t.TempDir()
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "XDG_RUNTIME_DIR"} {
+		t.Setenv(env, filepath.Join(home, env))
+	}
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	downloadDir := filepath.Join(home, "stock")
+	configPath := filepath.Join(home, "XDG_CONFIG_HOME", "ytpl", "config.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0755))
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+download_dir = "`+downloadDir+`"
+player_path = "true"
+`), 0644))
+	return downloadDir
+}
+
+// runCommand runs ytpl with the given arguments.
+func runCommand(t *testing.T, args ...string) {
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	require.NoError(t, rootCmd.Execute())
+}
+
+// pickFirstResult makes the search command choose the first result instead of showing fzf.
+func pickFirstResult(t *testing.T) {
+	previous := pickSearchResult
+	pickSearchResult = func(tracks []yt.TrackInfo, match yt.MatchQuery, loadMore bool) ([]int, error) {
+		return []int{0}, nil
+	}
+	t.Cleanup(func() { pickSearchResult = previous })
+}
+
+func TestSearchCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	pickFirstResult(t)
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	require.NoError(t, fake.AddSearchFixture("never gonna", filepath.Join("..", "internal", "yt", "testdata", "search.jsonl")))
+	fake.AddVideo(ytfake.Video{
+		Info: yt.TrackInfo{
+			ID:         "dQw4w9WgXcQ",
+			Title:      "Rick Astley - Never Gonna Give You Up (Official Music Video)",
+			Uploader:   "Rick Astley",
+			Duration:   3,
+			WebpageURL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+		Extra: map[string]interface{}{"formats": []interface{}{}},
+	})
+
+	t.Run("downloads and plays the chosen result", func(t *testing.T) {
+		runCommand(t, "search", "never", "gonna")
+
+		assert.Equal(t, []string{"search:never gonna", "download:dQw4w9WgXcQ"}, fake.Calls())
+		assert.FileExists(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"))
+
+		library := tracks.New(filepath.Join(downloadDir, ".tracks"))
+		require.NoError(t, library.Load())
+		track, found := library.Get("dQw4w9WgXcQ")
+		require.True(t, found, "downloaded track should be added to the library")
+		assert.Equal(t, "Rick Astley", track.Uploader)
+		assert.Equal(t, "Never Gonna Give You Up", track.Title, "the title should be cleaned up")
+		assert.Equal(t, "Rick Astley", track.Artist)
+		assert.Equal(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"), track.FilePath, "the audio file path should be recorded")
+
+		current := state.GetState()
+		require.NotNil(t, current)
+		assert.Equal(t, "dQw4w9WgXcQ", current.CurrentTrackID)
+		assert.Equal(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"), current.DownloadedFilePath)
+	})
+
+	t.Run("plays a stocked result without downloading it again", func(t *testing.T) {
+		runCommand(t, "search", "never", "gonna")
+
+		assert.Equal(t, []string{"search:never gonna", "download:dQw4w9WgXcQ"}, fake.Calls(), "results should come from the cache")
+		assert.Equal(t, "dQw4w9WgXcQ", state.GetState().CurrentTrackID)
+	})
+}
+
+func TestSearchLoadMore(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { searchExclude = "" })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	var results []yt.TrackInfo
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "ddddddddddd", "eeeeeeeeeee", "fffffffffff",
+		"ggggggggggg", "hhhhhhhhhhh", "iiiiiiiiiii", "jjjjjjjjjjj", "kkkkkkkkkkk", "lllllllllll"} {
+		results = append(results, yt.TrackInfo{ID: id, Title: "Song " + id, Duration: 1})
+	}
+	results[11].Title = "Song (Live)"
+	fake.AddSearchResults("song", results...)
+	fake.AddVideo(ytfake.Video{Info: results[10]})
+
+	// Load the next page once, then pick its last result
+	var shown [][]string
+	previous := pickSearchResult
+	pickSearchResult = func(tracks []yt.TrackInfo, match yt.MatchQuery, loadMore bool) ([]int, error) {
+		var ids []string
+		for _, track := range tracks {
+			ids = append(ids, track.ID)
+		}
+		shown = append(shown, ids)
+		if len(shown) == 1 {
+			require.True(t, loadMore)
+			return []int{len(tracks)}, nil
+		}
+		return []int{len(tracks) - 1}, nil
+	}
+	t.Cleanup(func() { pickSearchResult = previous })
+
+	runCommand(t, "search", "--exclude", "(?i)live", "song")
+
+	assert.Equal(t, []string{"search:song", "search:song (page 2)", "download:kkkkkkkkkkk"}, fake.Calls())
+	require.Len(t, shown, 2)
+	assert.Len(t, shown[0], 10, "the first page should have max_search_results results")
+	assert.Len(t, shown[1], 11, "the second page should be appended, without the excluded title")
+	assert.FileExists(t, filepath.Join(downloadDir, "kkkkkkkkkkk.mp3"))
+}
+
+func TestSearchMultiSelect(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	results := []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "First", Uploader: "Artist", Duration: 1, ViewCount: 1200},
+		{ID: "bbbbbbbbbbb", Title: "Second", Uploader: "Artist", Duration: 1},
+		{ID: "ccccccccccc", Title: "Third", Uploader: "Artist", Duration: 1},
+	}
+	fake.AddSearchResults("artist", results...)
+	for _, result := range results {
+		fake.AddVideo(ytfake.Video{Info: result})
+	}
+
+	previous := pickSearchResult
+	pickSearchResult = func(tracks []yt.TrackInfo, match yt.MatchQuery, loadMore bool) ([]int, error) {
+		return []int{2, 0}, nil
+	}
+	t.Cleanup(func() { pickSearchResult = previous })
+
+	runCommand(t, "search", "artist")
+
+	assert.FileExists(t, filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"))
+	assert.FileExists(t, filepath.Join(downloadDir, "ccccccccccc.mp3"))
+	assert.NoFileExists(t, filepath.Join(downloadDir, "bbbbbbbbbbb.mp3"))
+	assert.Equal(t, searchResultsPlaylist, state.GetState().CurrentPlaylist)
+
+	t.Run("marks stocked results", func(t *testing.T) {
+		match := yt.MatchQuery{Query: "artist"}
+		marks := markSearchResults(results, match)
+		score := yt.ScoreMatch(results[0], match)
+		assert.True(t, strings.HasPrefix(formatSearchResult(0, results[0], marks), fmt.Sprintf("✓  01:[0:01] %3d  First", score)))
+		assert.Contains(t, formatSearchResult(0, results[0], marks), "1.2K")
+		assert.True(t, strings.HasPrefix(formatSearchResult(1, results[1], marks), "   02:[0:01]"))
+		assert.Contains(t, searchResultPreview(results[0], marks, 80), filepath.Join(downloadDir, "aaaaaaaaaaa.mp3"))
+		assert.Contains(t, searchResultPreview(results[0], marks, 80), fmt.Sprintf("match: %d/100", score))
+	})
+}
+
+func TestSearchCache(t *testing.T) {
+	setupTestEnv(t)
+	pickFirstResult(t)
+	t.Cleanup(func() { searchRefresh, searchStream, searchSort = false, false, yt.SortRelevance })
+	searchStream = true // Don't download, only the searches matter here
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	fake.AddSearchResults("first query", yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "First", Duration: 1})
+	fake.AddSearchResults("second query", yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Second", Duration: 1})
+
+	t.Run("serves repeated searches from the cache", func(t *testing.T) {
+		runCommand(t, "search", "first", "query")
+		runCommand(t, "search", "first", "query")
+		assert.Equal(t, []string{"search:first query"}, fake.Calls())
+	})
+
+	t.Run("filters are part of the key", func(t *testing.T) {
+		runCommand(t, "search", "--sort", "views", "first", "query")
+		assert.Equal(t, []string{"search:first query", "search:first query"}, fake.Calls())
+	})
+
+	t.Run("--refresh bypasses the cache", func(t *testing.T) {
+		runCommand(t, "search", "--refresh", "first", "query")
+		assert.Len(t, fake.Calls(), 3)
+	})
+
+	t.Run("search without a query picks from the history", func(t *testing.T) {
+		searchRefresh = false
+		runCommand(t, "search", "second", "query")
+
+		var offered []string
+		previous := pickSearchHistory
+		pickSearchHistory = func(history []searchcache.HistoryEntry) (int, error) {
+			for _, e := range history {
+				offered = append(offered, e.Query)
+			}
+			return 1, nil
+		}
+		t.Cleanup(func() { pickSearchHistory = previous })
+
+		runCommand(t, "search")
+		assert.Equal(t, []string{"second query", "first query"}, offered, "the most recent query should come first")
+		assert.Equal(t, "aaaaaaaaaaa", state.GetState().CurrentTrackID)
+	})
+
+	t.Run("cache clear removes cached searches", func(t *testing.T) {
+		stats, err := searchCache().Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Entries)
+
+		runCommand(t, "cache", "clear")
+		stats, err = searchCache().Stats()
+		require.NoError(t, err)
+		assert.Zero(t, stats.Entries)
+	})
+}
+
+func TestSearchBest(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { searchBest, searchDuration = false, "" })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	results := []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "Artist - Song (Live at the Arena)", Uploader: "Artist", Duration: 300},
+		{ID: "bbbbbbbbbbb", Title: "Song (Cover)", Uploader: "Someone", Duration: 200},
+		{ID: "ccccccccccc", Title: "Song", Uploader: "Artist - Topic", Duration: 201},
+	}
+	fake.AddSearchResults("artist - song", results...)
+	fake.AddVideo(ytfake.Video{Info: results[2]})
+
+	previous := pickSearchResult
+	pickSearchResult = func(tracks []yt.TrackInfo, match yt.MatchQuery, loadMore bool) ([]int, error) {
+		t.Fatal("--best should not show the result list")
+		return nil, nil
+	}
+	t.Cleanup(func() { pickSearchResult = previous })
+
+	runCommand(t, "search", "--best", "--duration", "3m20s", "artist - song")
+
+	assert.Equal(t, []string{"search:artist - song", "download:ccccccccccc"}, fake.Calls())
+	assert.FileExists(t, filepath.Join(downloadDir, "ccccccccccc.mp3"))
+}
+
+func TestSearchURL(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	fake.AddVideo(ytfake.Video{Info: yt.TrackInfo{ID: "sc:some-artist/a-track", Title: "A Track", Uploader: "Some Artist", Duration: 2}})
+
+	runCommand(t, "search", "https://soundcloud.com/some-artist/a-track")
+
+	assert.Equal(t, []string{"metadata:sc:some-artist/a-track", "download:sc:some-artist/a-track"}, fake.Calls())
+	assert.FileExists(t, filepath.Join(downloadDir, "sc+some-artist+a-track.mp3"))
+
+	library := tracks.New(filepath.Join(downloadDir, ".tracks"))
+	require.NoError(t, library.Load())
+	track, ok := library.Get("sc:some-artist/a-track")
+	require.True(t, ok)
+	assert.Equal(t, "A Track", track.Title)
+	assert.Equal(t, "soundcloud", track.Extractor)
+	assert.Equal(t, "https://soundcloud.com/some-artist/a-track", track.OriginalURL)
+	assert.Equal(t, filepath.Join(downloadDir, "sc+some-artist+a-track.mp3"), track.FilePath)
+
+	current := state.GetState()
+	assert.Equal(t, "sc:some-artist/a-track", current.CurrentTrackID)
+	assert.Equal(t, "sc:some-artist/a-track", yt.TrackIDFromFileName(current.DownloadedFilePath))
+}
@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"time"
 
 	"ytpl/internal/player"
 	"ytpl/internal/state"
 	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
 
 	"github.com/spf13/cobra"
 )
@@ -22,9 +22,14 @@ type trackInfo struct {
 	Path  string
 }
 
+// shuffleLabelFilter picks the tracks to shuffle by their tags.
+var shuffleLabelFilter labelFilter
+
 var shuffleCmd = &cobra.Command{
 	Use:   "shuffle",
 	Short: "Shuffle and play all local stocked songs",
+	Long: `Shuffle and play all local stocked songs.
+With --tag and --not-tag, only the songs with or without the given tags are played.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		rand.Seed(time.Now().UnixNano()) // Initialize random seed for different results each run
 
@@ -38,19 +43,31 @@ var shuffleCmd = &cobra.Command{
 		// Get all tracks from the manager
 		allTracks := trackManager.ListTracks()
 		if len(allTracks) == 0 {
-			fmt.Println("\n- no local songs to shuffle. use 'ytpl search' to download some.\n")
+			fmt.Print("\n- no local songs to shuffle. use 'ytpl search' to download some.\n\n")
 			return
 		}
 
 		// Convert to our minimal trackInfo format
 		tracksToShuffle := make([]trackInfo, 0, len(allTracks))
 		for _, track := range allTracks {
+			path, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
+			if !stocked || !shuffleLabelFilter.matches(&track) {
+				continue
+			}
 			tracksToShuffle = append(tracksToShuffle, trackInfo{
 				ID:    track.ID,
 				Title: track.Title,
-				Path:  filepath.Join(cfg.DownloadDir, track.ID+".mp3"),
+				Path:  path,
 			})
 		}
+		if len(tracksToShuffle) == 0 {
+			if shuffleLabelFilter.active() {
+				fmt.Print("\n- no local songs found with the given tags.\n\n")
+				return
+			}
+			fmt.Print("\n- no local songs to shuffle. use 'ytpl search' to download some.\n\n")
+			return
+		}
 
 		// Shuffle the tracks
 		rand.Shuffle(len(tracksToShuffle), func(i, j int) {
@@ -86,3 +103,7 @@ var shuffleCmd = &cobra.Command{
 		statusCmd.Run(statusCmd, []string{})
 	},
 }
+
+func init() {
+	shuffleLabelFilter.addFlags(shuffleCmd)
+}
@@ -94,9 +94,17 @@ func getBestAvailableTitle() string {
 		return "Unknown Track"
 	}
 
+	// Streams have no file name to fall back to
+	if player.IsStream(appState.DownloadedFilePath) {
+		if appState.CurrentTrackTitle != "" {
+			return appState.CurrentTrackTitle
+		}
+		return appState.DownloadedFilePath
+	}
+
 	// Get track ID from file path
 	base := filepath.Base(appState.DownloadedFilePath)
-	trackID := strings.TrimSuffix(base, filepath.Ext(base))
+	trackID := yt.TrackIDFromFileName(base)
 
 	// Initialize track manager
 	trackManager, err := tracks.NewManager(filepath.Dir(cfg.DownloadDir), cfg.DownloadDir)
@@ -125,9 +133,14 @@ func updateAppStateFromMpvStatus() {
         return
     }
 
+    if player.IsStream(currentFilePath) {
+        updateAppStateFromStream(currentFilePath, currentPlaylistPos)
+        return
+    }
+
     if currentFilePath != "" {
         _, fileName := filepath.Split(currentFilePath)
-        currentTrackID := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+        currentTrackID := yt.TrackIDFromFileName(fileName)
 
         // Get ytTrackInfo for its title as a primary fallback
         ytTrackInfo, err := yt.GetLocalTrackInfo(cfg, currentTrackID)
@@ -191,3 +204,32 @@ func updateAppStateFromMpvStatus() {
         state.SaveState()
     }
 }
+
+// updateAppStateFromStream updates appState for a track streamed from a URL.
+// The title comes from the library if the track is stocked, otherwise from the one recorded
+// when the stream started, or from mpv once its ytdl hook has resolved the URL.
+func updateAppStateFromStream(streamURL string, playlistPos int) {
+	trackID, _ := yt.ParseTrackRef(streamURL)
+	if trackID != appState.CurrentTrackID {
+		appState.CurrentTrackTitle = ""
+	}
+
+	if trackManager, err := tracks.NewManager("", cfg.DownloadDir); err == nil {
+		if track, exists := trackManager.GetTrack(trackID); exists && track != nil {
+			appState.CurrentTrackTitle = track.Title
+		}
+	}
+	if appState.CurrentTrackTitle == "" {
+		// mpv reports the URL itself as the title until the stream is resolved
+		if mediaTitle, err := player.GetProperty(appState, "media-title"); err == nil {
+			if title, ok := mediaTitle.(string); ok && !strings.Contains(streamURL, title) {
+				appState.CurrentTrackTitle = title
+			}
+		}
+	}
+
+	appState.CurrentTrackID = trackID
+	appState.DownloadedFilePath = streamURL
+	appState.LastPlayedTrackIndex = playlistPos
+	state.SaveState()
+}
@@ -0,0 +1,97 @@
+// cmd/stream.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/player"
+	"ytpl/internal/state"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+// streamCmd plays a video without downloading it.
+var streamCmd = &cobra.Command{
+	Use:   "stream <url|track_id>",
+	Short: "Play a YouTube video or a track from another site without downloading it",
+	Long: `Play a YouTube video, or a track from another site yt-dlp supports such as SoundCloud,
+Bandcamp or Vimeo, by streaming its audio with mpv, without adding it to the stock.
+Use 'ytpl list add <playlist>' while it plays to keep it; you'll be offered to download it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		trackID, ok := yt.ParseTrackRef(args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "\n- '%s' is not a supported track URL or ID.\n\n", args[0])
+			os.Exit(1)
+		}
+
+		// Play the local file if the track is already stocked
+		if localPath, stocked := localTrackPath(trackID); stocked {
+			track, err := yt.GetLocalTrackInfo(cfg, trackID)
+			if err != nil {
+				track = &yt.TrackInfo{ID: trackID, Title: trackID}
+			}
+			playTrackFile(track, localPath)
+			return
+		}
+
+		infoSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("fetching '%s' from %s...", args[0], yt.SourceName(trackID)),
+			util.StyleLine,
+		)
+		track, err := yt.FetchTrackInfo(cfg, trackID)
+		infoSpinner.Stop("")
+		if err != nil {
+			exitWithYtError("fetching video info", err)
+		}
+		streamTrack(track)
+	},
+}
+
+// streamTrack starts streaming a track and shows the playback status.
+func streamTrack(track *yt.TrackInfo) {
+	playTrackFile(track, yt.TrackURL(track.ID))
+}
+
+// playTrackFile starts playing a single track from a local file or a stream URL
+// and shows the playback status.
+func playTrackFile(track *yt.TrackInfo, path string) {
+	if err := player.StartPlayer(cfg, appState, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting player: %v\n", err)
+		os.Exit(1)
+	}
+
+	appState.CurrentTrackID = track.ID
+	appState.CurrentTrackTitle = track.Title
+	appState.CurrentTrackDuration = track.Duration
+	appState.DownloadedFilePath = path
+	appState.IsPlaying = true
+	appState.CurrentPlaylist = ""
+
+	// Ignore error when saving state
+	_ = state.SaveState()
+
+	// Show status after starting player
+	ShowStatus()
+}
+
+// offerStreamDownload asks whether to download a streamed track to the stock and downloads it.
+// Declining is fine: playlists download their missing tracks when played or synced.
+func offerStreamDownload(trackID, title string) {
+	if _, stocked := localTrackPath(trackID); stocked {
+		return
+	}
+
+	keep, err := util.Confirm(fmt.Sprintf("- '%s' is streamed. download it to the stock?", title))
+	if err != nil || !keep {
+		fmt.Print("\n- not downloaded. it will be downloaded when the playlist is played or synced.\n\n")
+		return
+	}
+	if _, err := downloadNow(trackID, title); err != nil {
+		exitWithYtError("downloading track", err)
+	}
+	fmt.Printf("\n- downloaded '%s'.\n\n", title)
+}
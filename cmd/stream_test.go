@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/state"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func TestStreamCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	pickFirstResult(t)
+	t.Cleanup(func() { searchStream = false })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	require.NoError(t, fake.AddSearchFixture("never gonna", filepath.Join("..", "internal", "yt", "testdata", "search.jsonl")))
+	fake.AddVideo(ytfake.Video{Info: yt.TrackInfo{ID: "yPYZpwSpKmA", Title: "Together Forever", Duration: 205}})
+
+	t.Run("search --stream plays the result without downloading it", func(t *testing.T) {
+		runCommand(t, "search", "--stream", "never", "gonna")
+
+		assert.Equal(t, []string{"search:never gonna"}, fake.Calls())
+		assert.NoFileExists(t, filepath.Join(downloadDir, "dQw4w9WgXcQ.mp3"))
+
+		current := state.GetState()
+		assert.Equal(t, "dQw4w9WgXcQ", current.CurrentTrackID)
+		assert.Equal(t, "Rick Astley - Never Gonna Give You Up (Official Music Video)", current.CurrentTrackTitle)
+		assert.Equal(t, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", current.DownloadedFilePath)
+	})
+
+	t.Run("stream fetches the title and streams the video", func(t *testing.T) {
+		runCommand(t, "stream", "https://youtu.be/yPYZpwSpKmA")
+
+		assert.Equal(t, "metadata:yPYZpwSpKmA", fake.Calls()[len(fake.Calls())-1])
+		assert.NoFileExists(t, filepath.Join(downloadDir, "yPYZpwSpKmA.mp3"))
+
+		current := state.GetState()
+		assert.Equal(t, "Together Forever", current.CurrentTrackTitle)
+		assert.Equal(t, 205.0, current.CurrentTrackDuration)
+		assert.Equal(t, "https://www.youtube.com/watch?v=yPYZpwSpKmA", current.DownloadedFilePath)
+	})
+}
@@ -0,0 +1,322 @@
+// cmd/sub.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/config"
+	"ytpl/internal/playlist"
+	"ytpl/internal/subscription"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var (
+	subAddInto        string
+	subAddFilter      string
+	subAddMaxDuration string
+)
+
+// subCmd is the parent command for managing subscriptions to channels and playlists.
+var subCmd = &cobra.Command{
+	Use:   "sub",
+	Short: "Manage subscriptions to YouTube channels and playlists",
+	Long: `Subscribe to YouTube channels and playlists.
+'ytpl sync' downloads their new uploads and adds them to a playlist.`,
+}
+
+// subAddCmd subscribes to a channel or playlist.
+var subAddCmd = &cobra.Command{
+	Use:   "add <channel_or_playlist_url>",
+	Short: "Subscribe to a YouTube channel or playlist",
+	Long: `Subscribe to a YouTube channel or playlist.
+The videos it has now are skipped; 'ytpl sync' downloads only the ones uploaded later.
+--filter keeps only the videos whose title matches a regular expression,
+and --max-duration skips longer videos (e.g. "10m", or seconds).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		url := args[0]
+		sub := &subscription.Subscription{URL: url, Playlist: subAddInto, Filter: subAddFilter}
+		if subAddFilter != "" {
+			if _, err := regexp.Compile(subAddFilter); err != nil {
+				log.Fatalf("invalid --filter: %v", err)
+			}
+		}
+		if subAddMaxDuration != "" {
+			seconds, err := parseDurationFlag(subAddMaxDuration)
+			if err != nil {
+				log.Fatalf("invalid --max-duration: %v", err)
+			}
+			sub.MaxDuration = seconds
+		}
+
+		path, subs := loadSubscriptions()
+		if subscription.Find(subs, url) >= 0 {
+			fmt.Printf("\n- already subscribed to '%s'.\n\n", url)
+			return
+		}
+
+		// Remember the current videos so that only later uploads are synced
+		listSpinner := util.NewSpinnerWithStyle(
+			fmt.Sprintf("listing '%s'...", url),
+			util.StyleLine,
+		)
+		entries, err := yt.FetchPlaylist(cfg, url)
+		listSpinner.Stop("")
+		if err != nil {
+			exitWithYtError("listing YouTube channel or playlist", err)
+		}
+		for _, entry := range entries {
+			sub.MarkSeen(entry.ID)
+			if sub.Name == "" && entry.Uploader != "" {
+				sub.Name = entry.Uploader
+			}
+		}
+		sub.LastSync = time.Now()
+
+		subs = append(subs, sub)
+		if err := subscription.Save(path, subs); err != nil {
+			log.Fatalf("error saving subscriptions: %v", err)
+		}
+		fmt.Printf("\n- subscribed to %s. %d existing videos will be skipped.\n", subscriptionName(sub), len(entries))
+		if sub.Playlist != "" {
+			fmt.Printf("- new uploads will be added to '%s'.\n", sub.Playlist)
+		}
+		fmt.Print("- run 'ytpl sync' to download new uploads.\n\n")
+	},
+}
+
+// subLsCmd lists the subscriptions.
+var subLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List subscriptions",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, subs := loadSubscriptions()
+		if len(subs) == 0 {
+			fmt.Print("\n- no subscriptions. add one with 'ytpl sub add <url>'.\n\n")
+			return
+		}
+
+		fmt.Println()
+		for i, sub := range subs {
+			fmt.Printf("%d. %s\n", i+1, subscriptionName(sub))
+			if sub.Name != "" {
+				fmt.Printf("   url: %s\n", sub.URL)
+			}
+			if sub.Playlist != "" {
+				fmt.Printf("   into: %s\n", sub.Playlist)
+			}
+			if sub.Filter != "" {
+				fmt.Printf("   filter: %s\n", sub.Filter)
+			}
+			if sub.MaxDuration > 0 {
+				fmt.Printf("   max duration: %s\n", util.FormatDuration(sub.MaxDuration))
+			}
+			fmt.Printf("   last sync: %s\n", sub.LastSync.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Println()
+	},
+}
+
+// subRmCmd removes a subscription by URL or by its number in 'sub ls'.
+var subRmCmd = &cobra.Command{
+	Use:   "rm <url|number>",
+	Short: "Remove a subscription",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, subs := loadSubscriptions()
+		index := subscription.Find(subs, args[0])
+		if n, err := strconv.Atoi(args[0]); err == nil && index < 0 {
+			index = n - 1
+		}
+		if index < 0 || index >= len(subs) {
+			fmt.Fprintf(os.Stderr, "\n- no subscription '%s'. see 'ytpl sub ls'.\n\n", args[0])
+			os.Exit(1)
+		}
+
+		removed := subs[index]
+		subs = append(subs[:index], subs[index+1:]...)
+		if err := subscription.Save(path, subs); err != nil {
+			log.Fatalf("error saving subscriptions: %v", err)
+		}
+		fmt.Printf("\n- unsubscribed from %s.\n\n", subscriptionName(removed))
+	},
+}
+
+// syncCmd downloads the new uploads of every subscription.
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Download new uploads of subscribed channels and playlists",
+	Long: `Check every subscription for videos uploaded since the last sync, download them,
+and add them to the subscription's playlist. Videos that fail to download for a
+reason other than being unavailable are retried on the next sync.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, subs := loadSubscriptions()
+		if len(subs) == 0 {
+			fmt.Print("\n- no subscriptions. add one with 'ytpl sub add <url>'.\n\n")
+			return
+		}
+
+		for _, sub := range subs {
+			syncSubscription(sub)
+			if err := subscription.Save(path, subs); err != nil {
+				log.Fatalf("error saving subscriptions: %v", err)
+			}
+		}
+	},
+}
+
+// syncSubscription downloads the new uploads of sub and adds them to its playlist.
+// A subscription that can't be checked is reported and skipped, so the others still sync.
+func syncSubscription(sub *subscription.Subscription) {
+	name := subscriptionName(sub)
+	listSpinner := util.NewSpinnerWithStyle(
+		fmt.Sprintf("checking %s...", name),
+		util.StyleLine,
+	)
+	entries, err := yt.FetchPlaylist(cfg, sub.URL)
+	listSpinner.Stop("")
+	if err != nil {
+		fmt.Println(util.Red(fmt.Sprintf("\n- failed to check %s: %s", name, describeYtError(err))))
+		return
+	}
+
+	var newIDs []string
+	titles := make(map[string]string)
+	usedIn := make(map[string][]string)
+	filtered := 0
+	for _, entry := range entries {
+		if sub.HasSeen(entry.ID) {
+			continue
+		}
+		if !sub.Matches(entry.Title, entry.Duration) {
+			sub.MarkSeen(entry.ID)
+			filtered++
+			continue
+		}
+		newIDs = append(newIDs, entry.ID)
+		titles[entry.ID] = entry.Title
+		if sub.Playlist != "" {
+			usedIn[entry.ID] = []string{sub.Playlist}
+		}
+	}
+
+	fmt.Printf("\n- %s: %d new uploads", name, len(newIDs))
+	if filtered > 0 {
+		fmt.Printf(", %d skipped by filters", filtered)
+	}
+	fmt.Print(".\n")
+	if len(newIDs) == 0 {
+		sub.LastSync = time.Now()
+		return
+	}
+
+	_, failedIDs := downloadMissingTracks(newIDs, titles, usedIn)
+	retry := make(map[string]bool)
+	for _, id := range failedIDs {
+		retry[id] = true
+	}
+
+	// Channels list the newest uploads first; add them oldest first
+	trackManager := loadLibrary()
+	var stocked []string
+	for i := len(newIDs) - 1; i >= 0; i-- {
+		id := newIDs[i]
+		if retry[id] {
+			continue
+		}
+		sub.MarkSeen(id)
+		if _, ok := stockedTrackPath(trackManager, id); ok {
+			stocked = append(stocked, id)
+		}
+	}
+	sub.LastSync = time.Now()
+
+	if sub.Playlist != "" && len(stocked) > 0 {
+		added, err := appendToPlaylist(sub.Playlist, stocked)
+		if err != nil {
+			log.Fatalf("error updating playlist '%s': %v", sub.Playlist, err)
+		}
+		fmt.Printf("- added %d tracks to '%s'.\n\n", added, sub.Playlist)
+	}
+}
+
+// appendToPlaylist adds the tracks that aren't in the playlist yet to its end,
+// creating the playlist if needed, and returns how many were added.
+func appendToPlaylist(name string, trackIDs []string) (int, error) {
+	p, err := playlist.LoadPlaylist(name)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+		p = &playlist.Playlist{Name: name}
+	}
+
+	existing := make(map[string]bool)
+	for _, track := range p.Tracks {
+		existing[track.ID] = true
+	}
+	added := 0
+	for _, id := range trackIDs {
+		if existing[id] {
+			continue
+		}
+		p.Tracks = append(p.Tracks, playlist.TrackInfo{ID: id})
+		existing[id] = true
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, playlist.SavePlaylist(p)
+}
+
+// loadSubscriptions returns the path of the subscriptions file and the subscriptions in it.
+func loadSubscriptions() (string, []*subscription.Subscription) {
+	path, err := config.GetSubscriptionsPath()
+	if err != nil {
+		log.Fatalf("error getting subscriptions path: %v", err)
+	}
+	subs, err := subscription.Load(path)
+	if err != nil {
+		log.Fatalf("error loading subscriptions: %v", err)
+	}
+	return path, subs
+}
+
+// subscriptionName returns a name to show for sub.
+func subscriptionName(sub *subscription.Subscription) string {
+	if sub.Name != "" {
+		return fmt.Sprintf("'%s'", sub.Name)
+	}
+	return fmt.Sprintf("'%s'", sub.URL)
+}
+
+// parseDurationFlag parses a duration such as "10m" or "1h30m", or a number of seconds.
+func parseDurationFlag(s string) (float64, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil && seconds > 0 {
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("'%s' is not a positive duration like \"10m\" or \"600\"", s)
+	}
+	return d.Seconds(), nil
+}
+
+func init() {
+	subAddCmd.Flags().StringVar(&subAddInto, "into", "", "playlist to add new uploads to")
+	subAddCmd.Flags().StringVar(&subAddFilter, "filter", "", "only sync videos whose title matches this regular expression")
+	subAddCmd.Flags().StringVar(&subAddMaxDuration, "max-duration", "", "skip videos longer than this (e.g. 10m)")
+}
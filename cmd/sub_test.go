@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/config"
+	"ytpl/internal/subscription"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func TestSubscriptionSync(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { subAddInto, subAddFilter, subAddMaxDuration = "", "", "" })
+
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	const url = "https://www.youtube.com/playlist?list=PLuploads"
+	old := yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "Old Song", Uploader: "Artist", Duration: 180}
+	fake.AddVideo(ytfake.Video{Info: old})
+	fake.SetPlaylist(url, old)
+
+	runCommand(t, "sub", "add", url, "--into", "new-music", "--filter", "(?i)song", "--max-duration", "10m")
+
+	path, err := config.GetSubscriptionsPath()
+	require.NoError(t, err)
+	subs, err := subscription.Load(path)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "Artist", subs[0].Name)
+	assert.Equal(t, 600.0, subs[0].MaxDuration)
+	assert.Equal(t, []string{old.ID}, subs[0].Seen, "existing videos should be skipped")
+
+	newSong := yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "New Song", Uploader: "Artist", Duration: 200}
+	newer := yt.TrackInfo{ID: "ccccccccccc", Title: "Newer Song", Uploader: "Artist", Duration: 190}
+	vlog := yt.TrackInfo{ID: "ddddddddddd", Title: "Tour Vlog", Uploader: "Artist", Duration: 300}
+	long := yt.TrackInfo{ID: "eeeeeeeeeee", Title: "Song Live Concert", Uploader: "Artist", Duration: 3600}
+	for _, video := range []yt.TrackInfo{newSong, newer, vlog, long} {
+		fake.AddVideo(ytfake.Video{Info: video})
+	}
+	fake.SetPlaylist(url, newer, vlog, long, newSong, old)
+
+	t.Run("downloads new matching uploads into the playlist", func(t *testing.T) {
+		runCommand(t, "sync")
+
+		assert.Equal(t, []string{newSong.ID, newer.ID}, playlistIDs(t, "new-music"), "uploads should be added oldest first")
+		assert.FileExists(t, filepath.Join(downloadDir, newSong.ID+".mp3"))
+		assert.NoFileExists(t, filepath.Join(downloadDir, old.ID+".mp3"))
+		assert.NoFileExists(t, filepath.Join(downloadDir, vlog.ID+".mp3"), "titles not matching the filter should be skipped")
+		assert.NoFileExists(t, filepath.Join(downloadDir, long.ID+".mp3"), "videos over the max duration should be skipped")
+	})
+
+	t.Run("does nothing without new uploads", func(t *testing.T) {
+		before := len(fake.Calls())
+		runCommand(t, "sync")
+		assert.Equal(t, []string{"playlist:" + url}, fake.Calls()[before:])
+	})
+
+	t.Run("rm removes by number", func(t *testing.T) {
+		runCommand(t, "sub", "rm", "1")
+		subs, err := subscription.Load(path)
+		require.NoError(t, err)
+		assert.Empty(t, subs)
+	})
+}
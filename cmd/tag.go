@@ -0,0 +1,208 @@
+// cmd/tag.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+// labelFilter is the --tag and --not-tag filter of a command picking tracks.
+type labelFilter struct {
+	tags    []string // Tags a track must all have
+	notTags []string // Tags a track must have none of
+}
+
+// addFlags adds the --tag and --not-tag flags to a command.
+func (f *labelFilter) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "only tracks with this tag (repeat for several)")
+	cmd.Flags().StringSliceVar(&f.notTags, "not-tag", nil, "leave out tracks with this tag (repeat for several)")
+}
+
+// active reports whether any tag is filtered on.
+func (f *labelFilter) active() bool {
+	return len(f.tags) > 0 || len(f.notTags) > 0
+}
+
+// matches reports whether a track has all the wanted tags and none of the excluded ones.
+func (f *labelFilter) matches(track *yt.TrackInfo) bool {
+	for _, tag := range f.tags {
+		if !hasLabel(track, tag) {
+			return false
+		}
+	}
+	for _, tag := range f.notTags {
+		if hasLabel(track, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLabel reports whether a track has the user tag, ignoring case.
+func hasLabel(track *yt.TrackInfo, label string) bool {
+	for _, l := range track.Labels {
+		if strings.EqualFold(l, strings.TrimSpace(label)) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelCount is a user tag and the number of tracks having it.
+type labelCount struct {
+	label string
+	count int
+}
+
+// countLabels returns the user tags of the tracks with their number of tracks, most used first.
+// Tags differing only in case are counted together under their first spelling.
+func countLabels(trackList []yt.TrackInfo) []labelCount {
+	var counts []labelCount
+	index := make(map[string]int)
+	for _, track := range trackList {
+		for _, label := range track.Labels {
+			key := strings.ToLower(label)
+			i, ok := index[key]
+			if !ok {
+				i = len(counts)
+				index[key] = i
+				counts = append(counts, labelCount{label: label})
+			}
+			counts[i].count++
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return strings.ToLower(counts[i].label) < strings.ToLower(counts[j].label)
+	})
+	return counts
+}
+
+// tagCmd groups the commands on the user tags of tracks.
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag tracks with moods and contexts",
+	Long: `Tag tracks with free-form tags such as moods and contexts, e.g. "chill" or "work".
+play, edit, del, list show and shuffle take --tag to only pick tracks with a tag and --not-tag
+to leave out tracks with a tag; both can be repeated. Tags are matched ignoring case.
+
+These tags are your own: the tags set by the uploader on YouTube are kept apart.
+The tags of many tracks can also be edited with 'ytpl edit --bulk'.`,
+}
+
+// tagAddCmd adds user tags to the tracks matching a query.
+var tagAddCmd = &cobra.Command{
+	Use:   "add <query> <tag>...",
+	Short: "Add tags to the tracks matching the query",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeLabels(args[0], parseLabels(strings.Join(args[1:], ",")), true)
+	},
+}
+
+// tagRmCmd removes user tags from the tracks matching a query.
+var tagRmCmd = &cobra.Command{
+	Use:   "rm <query> <tag>...",
+	Short: "Remove tags from the tracks matching the query",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeLabels(args[0], parseLabels(strings.Join(args[1:], ",")), false)
+	},
+}
+
+// tagLsCmd lists the user tags with the number of tracks having them.
+var tagLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List tags with their number of tracks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+		if err != nil {
+			log.Fatalf("failed to initialize track manager: %v", err)
+		}
+		counts := countLabels(trackManager.ListTracks())
+		if len(counts) == 0 {
+			fmt.Print("\n- no tags yet. add some with 'ytpl tag add <query> <tag>'.\n\n")
+			return
+		}
+
+		width := 0
+		for _, c := range counts {
+			width = max(width, len(c.label))
+		}
+		fmt.Println()
+		for _, c := range counts {
+			fmt.Printf("  %-*s  %d\n", width, c.label, c.count)
+		}
+		fmt.Println()
+	},
+}
+
+// changeLabels adds the tags to, or removes them from, the stocked tracks matching the query.
+func changeLabels(query string, labels []string, add bool) {
+	if len(labels) == 0 {
+		log.Fatal("no tags given")
+	}
+	trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+	if err != nil {
+		log.Fatalf("failed to initialize track manager: %v", err)
+	}
+	matching := editableTracks(trackManager, query, labelFilter{})
+	if len(matching) == 0 {
+		return
+	}
+
+	trackManager.BatchMode(true)
+	changed := 0
+	for _, track := range matching {
+		updated := *track
+		updated.Labels = nil
+		for _, label := range track.Labels {
+			if add || !containsFold(labels, label) {
+				updated.Labels = append(updated.Labels, label)
+			}
+		}
+		if add {
+			for _, label := range labels {
+				if !hasLabel(&updated, label) {
+					updated.Labels = append(updated.Labels, label)
+				}
+			}
+		}
+		if len(updated.Labels) == len(track.Labels) {
+			continue
+		}
+		if err := trackManager.UpdateTrack(&updated); err != nil {
+			log.Fatalf("error updating track %s: %v", track.ID, err)
+		}
+		changed++
+	}
+	if err := trackManager.SaveAll(); err != nil {
+		log.Fatalf("error saving tracks: %v", err)
+	}
+
+	if add {
+		fmt.Printf("\n- tagged %d of %d matching tracks with %s.\n\n", changed, len(matching), strings.Join(labels, ", "))
+	} else {
+		fmt.Printf("\n- untagged %d of %d matching tracks from %s.\n\n", changed, len(matching), strings.Join(labels, ", "))
+	}
+}
+
+// containsFold reports whether values has the value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
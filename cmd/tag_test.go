@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+func TestLabelFilter(t *testing.T) {
+	track := &yt.TrackInfo{ID: "aaaaaaaaaaa", Labels: []string{"Chill", "work"}}
+	for _, tc := range []struct {
+		name   string
+		filter labelFilter
+		want   bool
+	}{
+		{"no filter", labelFilter{}, true},
+		{"has the tag, ignoring case", labelFilter{tags: []string{"chill"}}, true},
+		{"has all the tags", labelFilter{tags: []string{"chill", "work"}}, true},
+		{"misses a tag", labelFilter{tags: []string{"chill", "party"}}, false},
+		{"has none of the excluded tags", labelFilter{notTags: []string{"xmas"}}, true},
+		{"has an excluded tag", labelFilter{tags: []string{"chill"}, notTags: []string{"Work"}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.filter.matches(track))
+		})
+	}
+}
+
+func TestCountLabels(t *testing.T) {
+	counts := countLabels([]yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Labels: []string{"work", "Chill"}},
+		{ID: "bbbbbbbbbbb", Labels: []string{"chill", "xmas"}},
+		{ID: "ccccccccccc", Labels: []string{"Work"}},
+		{ID: "ddddddddddd"},
+		{ID: "eeeeeeeeeee", Labels: []string{"chill"}},
+	})
+	assert.Equal(t, []labelCount{{"Chill", 3}, {"work", 2}, {"xmas", 1}}, counts)
+}
+
+func TestTagCommands(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "First", Artist: "Band", Labels: []string{"Chill"}},
+		{ID: "bbbbbbbbbbb", Title: "Second", Artist: "Band"},
+		{ID: "ccccccccccc", Title: "Jingle", Artist: "Someone", Labels: []string{"xmas"}},
+		{ID: "ddddddddddd", Title: "Missing", Artist: "Band"}, // Not stocked
+	} {
+		if track.ID != "ddddddddddd" {
+			track.FilePath = filepath.Join(downloadDir, track.ID+".flac")
+			writeTestFLAC(t, track.FilePath)
+		}
+		require.NoError(t, library.AddTrack(track))
+	}
+	labels := func() map[string][]string {
+		library, err := tracks.NewManager("", downloadDir)
+		require.NoError(t, err)
+		got := make(map[string][]string)
+		for _, track := range library.ListTracks() {
+			got[track.ID] = track.Labels
+		}
+		return got
+	}
+
+	runCommand(t, "tag", "add", "band", "chill", "work,focus")
+	assert.Equal(t, map[string][]string{
+		"aaaaaaaaaaa": {"Chill", "work", "focus"},
+		"bbbbbbbbbbb": {"chill", "work", "focus"},
+		"ccccccccccc": {"xmas"},
+		"ddddddddddd": nil,
+	}, labels())
+
+	runCommand(t, "tag", "rm", "second", "WORK", "party")
+	assert.Equal(t, []string{"chill", "focus"}, labels()["bbbbbbbbbbb"])
+
+	t.Run("pickers filter by tag", func(t *testing.T) {
+		library, err := tracks.NewManager("", downloadDir)
+		require.NoError(t, err)
+		ids := func(filter labelFilter) []string {
+			var ids []string
+			for _, track := range editableTracks(library, "", filter) {
+				ids = append(ids, track.ID)
+			}
+			return ids
+		}
+		assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb"}, ids(labelFilter{tags: []string{"chill"}}))
+		assert.Equal(t, []string{"aaaaaaaaaaa"}, ids(labelFilter{tags: []string{"work"}, notTags: []string{"xmas"}}))
+		assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb"}, ids(labelFilter{notTags: []string{"xmas"}}))
+		assert.Empty(t, ids(labelFilter{tags: []string{"party"}}))
+	})
+}
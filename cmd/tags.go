@@ -0,0 +1,294 @@
+// cmd/tags.go
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/album"
+	"ytpl/internal/audiotag"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var tagsDryRun bool
+
+// fetchCover returns the image data of a cover, given as a URL or a file path.
+// It is replaced in tests.
+var fetchCover = func(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+}
+
+// tagChange is a field of the tags embedded in an audio file that differs from the library.
+type tagChange struct {
+	field, before, after string
+}
+
+// tagsUpdate is the update of the tags of the audio file of a track.
+type tagsUpdate struct {
+	track   yt.TrackInfo
+	path    string
+	tags    audiotag.Tags
+	cover   string // Source of the cover to embed, if the file has no picture
+	changes []tagChange
+}
+
+// tagsCmd groups the commands on the tags embedded in the audio files.
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Manage the tags embedded in the audio files",
+	Long: `Manage the tags embedded in the audio files of the stocked tracks.
+'ytpl edit' writes the changed metadata into the files; 'ytpl tags sync' makes the tags of all
+files match the library.`,
+}
+
+// tagsSyncCmd writes the library metadata into the tags of the audio files that differ from it.
+var tagsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Make the tags of the audio files match the library",
+	Long: `Make the title, artist, album, year, genre, track number and comment embedded in the
+audio files match the library, so that other players show the same metadata.
+Files without an embedded picture get the album cover, or the video thumbnail, as cover.
+Fields without a value in the library, such as a comment embedded on download, are left as
+they are, and embedded pictures, such as the thumbnails embedded on download, are never replaced.
+
+Tags can be written into mp3, flac, opus, ogg and m4a files; other files are skipped.
+The changes are shown as a diff; --dry-run only shows them.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+		if err != nil {
+			log.Fatalf("failed to initialize track manager: %v", err)
+		}
+		_, albums := loadAlbums()
+
+		trackList := trackManager.ListTracks()
+		sort.Slice(trackList, func(i, j int) bool {
+			return strings.ToLower(trackList[i].Title) < strings.ToLower(trackList[j].Title)
+		})
+		var updates []tagsUpdate
+		skipped := 0
+		for _, track := range trackList {
+			path, stocked := yt.ResolveTrackPath(cfg, track.ID, track.FilePath)
+			if !stocked {
+				continue
+			}
+			if !audiotag.Supported(path) {
+				skipped++
+				continue
+			}
+			current, err := audiotag.Read(path)
+			if err != nil {
+				log.Printf("warning: %v", err)
+				continue
+			}
+
+			update := tagsUpdate{track: track, path: path, tags: libraryTags(track)}
+			update.changes = diffTags(current, update.tags)
+			if current.Cover == nil {
+				if update.cover = coverSource(track, albums); update.cover != "" {
+					update.changes = append(update.changes, tagChange{field: "cover", after: update.cover})
+				}
+			}
+			if len(update.changes) > 0 {
+				updates = append(updates, update)
+			}
+		}
+		if len(updates) == 0 {
+			fmt.Print("\n- all file tags match the library.\n\n")
+			printSkippedTagFiles(skipped)
+			return
+		}
+
+		fmt.Println()
+		for _, update := range updates {
+			printTagsDiff(update)
+		}
+		if tagsDryRun {
+			fmt.Printf("- %d files would change. run without --dry-run to apply.\n\n", len(updates))
+			printSkippedTagFiles(skipped)
+			return
+		}
+
+		written := 0
+		for _, update := range updates {
+			if update.cover != "" {
+				cover, err := coverPicture(update.cover)
+				if err != nil {
+					log.Printf("warning: failed to get the cover of %s: %v", update.track.ID, err)
+				}
+				update.tags.Cover = cover
+			}
+			if err := audiotag.Write(update.path, update.tags); err != nil {
+				log.Printf("warning: %v", err)
+				continue
+			}
+			written++
+		}
+		fmt.Printf("- updated the tags of %d files.\n\n", written)
+		printSkippedTagFiles(skipped)
+	},
+}
+
+// libraryTags returns the library metadata of a track as the tags of its audio file.
+// Like the tags yt-dlp embeds, the artist falls back to the uploader and the year to the upload date.
+func libraryTags(track yt.TrackInfo) audiotag.Tags {
+	tags := audiotag.Tags{
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       track.Album,
+		Year:        track.ReleaseYear,
+		Genre:       track.Genre,
+		TrackNumber: track.TrackNumber,
+		Comment:     track.Comment,
+	}
+	if tags.Artist == "" {
+		tags.Artist = track.Creator
+	}
+	if tags.Artist == "" {
+		tags.Artist = strings.TrimSuffix(track.Uploader, " - Topic")
+	}
+	if tags.Year == 0 && len(track.UploadDate) >= 4 {
+		tags.Year, _ = strconv.Atoi(track.UploadDate[:4])
+	}
+	return tags
+}
+
+// tagValue is a text field of tags with its value as shown in diffs.
+type tagValue struct {
+	field audiotag.Field
+	value string
+}
+
+// tagValues returns the text fields of tags.
+func tagValues(tags audiotag.Tags) []tagValue {
+	return []tagValue{
+		{audiotag.FieldTitle, tags.Title},
+		{audiotag.FieldArtist, tags.Artist},
+		{audiotag.FieldAlbum, tags.Album},
+		{audiotag.FieldYear, formatEditNumber(tags.Year)},
+		{audiotag.FieldGenre, tags.Genre},
+		{audiotag.FieldTrackNumber, formatEditNumber(tags.TrackNumber)},
+		{audiotag.FieldComment, tags.Comment},
+	}
+}
+
+// diffTags returns the text fields of the current tags that differ from the wanted ones.
+// Fields the wanted tags leave empty are not written, so they only differ if they are cleared.
+func diffTags(current, want audiotag.Tags) []tagChange {
+	var changes []tagChange
+	before := tagValues(current)
+	for i, after := range tagValues(want) {
+		if after.value == "" && !slices.Contains(want.Clear, after.field) {
+			continue
+		}
+		if before[i].value != after.value {
+			changes = append(changes, tagChange{field: string(after.field), before: before[i].value, after: after.value})
+		}
+	}
+	return changes
+}
+
+// clearedTags returns the text fields of the previous tags that were cleared in the updated ones.
+func clearedTags(previous, updated audiotag.Tags) []audiotag.Field {
+	var cleared []audiotag.Field
+	before := tagValues(previous)
+	for i, after := range tagValues(updated) {
+		if before[i].value != "" && after.value == "" {
+			cleared = append(cleared, after.field)
+		}
+	}
+	return cleared
+}
+
+// printTagsDiff prints the changes to the tags of a file.
+func printTagsDiff(update tagsUpdate) {
+	fmt.Println(util.Bold(update.track.ID) + " " + update.path)
+	for _, change := range update.changes {
+		label := fmt.Sprintf("%-8s", change.field+":")
+		if change.before != "" {
+			fmt.Println(util.Red("- " + label + change.before))
+		}
+		if change.after != "" {
+			fmt.Println(util.Green("+ " + label + change.after))
+		}
+	}
+	fmt.Println()
+}
+
+// printSkippedTagFiles notes the files skipped because their tags can't be written.
+func printSkippedTagFiles(skipped int) {
+	if skipped > 0 {
+		fmt.Printf("- skipped %d files in formats whose tags can't be written.\n\n", skipped)
+	}
+}
+
+// coverSource returns the cover of the album of a track, or else its thumbnail.
+func coverSource(track yt.TrackInfo, albums []*album.Album) string {
+	if track.Album != "" {
+		if index := album.Find(albums, track.Album); index >= 0 && albums[index].Cover != "" {
+			return albums[index].Cover
+		}
+	}
+	return track.Thumbnail
+}
+
+// coverPicture fetches a cover to embed. Only JPEG and PNG images can be embedded in all formats,
+// so the JPEG variant of YouTube's WebP thumbnails is fetched.
+func coverPicture(source string) (*audiotag.Picture, error) {
+	if strings.Contains(source, "i.ytimg.com/vi_webp/") {
+		source = strings.Replace(source, "/vi_webp/", "/vi/", 1)
+		source = strings.TrimSuffix(source, ".webp") + ".jpg"
+	}
+	data, err := fetchCover(source)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := http.DetectContentType(data)
+	if mimeType != "image/jpeg" && mimeType != "image/png" {
+		return nil, fmt.Errorf("unsupported cover image type %s", mimeType)
+	}
+	return &audiotag.Picture{MIMEType: mimeType, Data: data}, nil
+}
+
+// writeTrackTags writes the library metadata of an edited track into its audio file, if it is
+// stocked in a format whose tags can be written. Fields cleared by the edit are removed from the
+// file. Failures are reported as warnings.
+func writeTrackTags(before, after yt.TrackInfo) {
+	path, stocked := yt.ResolveTrackPath(cfg, after.ID, after.FilePath)
+	if !stocked || !audiotag.Supported(path) {
+		return
+	}
+	tags := libraryTags(after)
+	tags.Clear = clearedTags(libraryTags(before), tags)
+	if err := audiotag.Write(path, tags); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+func init() {
+	tagsSyncCmd.Flags().BoolVar(&tagsDryRun, "dry-run", false, "show the changes without applying them")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/audiotag"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+// writeTestFLAC writes a FLAC file with only a stream info block and no tags.
+func writeTestFLAC(t *testing.T, path string) {
+	t.Helper()
+	data := append([]byte("fLaC\x80\x00\x00\x22"), make([]byte, 34)...)
+	require.NoError(t, os.WriteFile(path, append(data, "audio frames"...), 0644))
+}
+
+func TestDiffTags(t *testing.T) {
+	current := audiotag.Tags{Title: "Old", Artist: "Band", Genre: "Rock", Comment: "https://www.youtube.com/watch?v=aaaaaaaaaaa"}
+	want := audiotag.Tags{Title: "New", Artist: "Band", Year: 2019}
+	assert.Equal(t, []tagChange{
+		{field: "title", before: "Old", after: "New"},
+		{field: "year", after: "2019"},
+	}, diffTags(current, want), "fields without a library value are left out")
+
+	want.Clear = []audiotag.Field{audiotag.FieldGenre}
+	assert.Equal(t, tagChange{field: "genre", before: "Rock"}, diffTags(current, want)[2], "cleared fields are removed")
+}
+
+func TestClearedTags(t *testing.T) {
+	previous := audiotag.Tags{Title: "Song", Album: "Debut", Year: 2019, Genre: "Rock"}
+	updated := audiotag.Tags{Title: "Song", Year: 2020}
+	assert.Equal(t, []audiotag.Field{audiotag.FieldAlbum, audiotag.FieldGenre}, clearedTags(previous, updated))
+}
+
+func TestTagsSyncCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { tagsDryRun = false })
+	cover := append([]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), make([]byte, 32)...)
+	var fetched []string
+	previous := fetchCover
+	fetchCover = func(source string) ([]byte, error) {
+		fetched = append(fetched, source)
+		return cover, nil
+	}
+	t.Cleanup(func() { fetchCover = previous })
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	path := filepath.Join(downloadDir, "aaaaaaaaaaa.flac")
+	writeTestFLAC(t, path)
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	require.NoError(t, library.AddTrack(yt.TrackInfo{
+		ID: "aaaaaaaaaaa", Title: "Song", Uploader: "The Band - Topic", Album: "Debut", UploadDate: "20190412",
+		Thumbnail: "https://i.ytimg.com/vi_webp/aaaaaaaaaaa/maxresdefault.webp", FilePath: path,
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "bbbbbbbbbbb.wav"), []byte("RIFF"), 0644))
+	require.NoError(t, library.AddTrack(yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Wave"}))
+
+	t.Run("dry run leaves the files untouched", func(t *testing.T) {
+		runCommand(t, "tags", "sync", "--dry-run")
+		tags, err := audiotag.Read(path)
+		require.NoError(t, err)
+		assert.Equal(t, audiotag.Tags{}, tags)
+		assert.Empty(t, fetched)
+	})
+
+	t.Run("writes the library metadata and the cover", func(t *testing.T) {
+		tagsDryRun = false
+		runCommand(t, "tags", "sync")
+		tags, err := audiotag.Read(path)
+		require.NoError(t, err)
+		assert.Equal(t, audiotag.Tags{
+			Title: "Song", Artist: "The Band", Album: "Debut", Year: 2019,
+			Cover: &audiotag.Picture{MIMEType: "image/jpeg", Data: cover},
+		}, tags)
+		assert.Equal(t, []string{"https://i.ytimg.com/vi/aaaaaaaaaaa/maxresdefault.jpg"}, fetched)
+	})
+
+	t.Run("edit writes the changes into the file", func(t *testing.T) {
+		resetFlags(t, editCmd)
+		runCommand(t, "edit", "--title", "Renamed", "--genre", "Rock", "aaaaaaaaaaa")
+		tags, err := audiotag.Read(path)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", tags.Title)
+		assert.Equal(t, "Rock", tags.Genre)
+		require.NotNil(t, tags.Cover)
+		assert.Equal(t, cover, tags.Cover.Data, "the embedded cover must be kept")
+	})
+
+	t.Run("edit removes cleared fields from the file", func(t *testing.T) {
+		resetFlags(t, editCmd)
+		runCommand(t, "edit", "--genre", "", "--album", "", "aaaaaaaaaaa")
+		tags, err := audiotag.Read(path)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", tags.Title)
+		assert.Empty(t, tags.Genre)
+		assert.Empty(t, tags.Album)
+
+		tagsDryRun = true
+		runCommand(t, "tags", "sync", "--dry-run")
+		tags, err = audiotag.Read(path)
+		require.NoError(t, err)
+		assert.Empty(t, tags.Album, "the file matches the library")
+	})
+}
@@ -0,0 +1,135 @@
+// cmd/tidy.go
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ytpl/internal/tidy"
+	"ytpl/internal/tracks"
+	"ytpl/internal/util"
+	"ytpl/internal/yt"
+)
+
+var (
+	tidyDryRun bool
+	tidyYes    bool
+)
+
+// tidyCmd cleans up the titles and artists of the tracks in the library.
+var tidyCmd = &cobra.Command{
+	Use:   "tidy [query]",
+	Short: "Clean up track titles and artists in the library",
+	Long: `Clean up the titles of the tracks in the library, or of the tracks matching the query.
+Noise such as "(Official Music Video)", "[MV]" or "【公式】" is removed, "Artist - Title" and
+"Title / Artist" are split into the artist and the title when one part matches the known artist
+or the uploader, and "feat." credits are moved to the artist. The title_rules of config.toml
+are applied after the built-in rules.
+
+The changes are shown as a diff and applied after confirmation; --dry-run only shows them.
+New downloads are cleaned up the same way.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := ""
+		if len(args) > 0 {
+			query = strings.ToLower(args[0])
+		}
+
+		trackManager, err := tracks.NewManager("", cfg.DownloadDir)
+		if err != nil {
+			log.Fatalf("failed to initialize track manager: %v", err)
+		}
+		cleaner, err := tidy.New(cfg.TitleRules)
+		if err != nil {
+			log.Fatalf("error in title_rules: %v", err)
+		}
+
+		trackList := trackManager.ListTracks()
+		sort.Slice(trackList, func(i, j int) bool {
+			return strings.ToLower(trackList[i].Title) < strings.ToLower(trackList[j].Title)
+		})
+		var before, after []yt.TrackInfo
+		for _, track := range trackList {
+			if query != "" && !strings.Contains(strings.ToLower(track.Title+" "+track.Artist+" "+track.ID), query) {
+				continue
+			}
+			tidied := track
+			if cleaner.Apply(&tidied) {
+				before = append(before, track)
+				after = append(after, tidied)
+			}
+		}
+		if len(after) == 0 {
+			fmt.Print("\n- all titles are tidy.\n\n")
+			return
+		}
+
+		fmt.Println()
+		for i := range after {
+			printTidyDiff(before[i], after[i])
+		}
+		if tidyDryRun {
+			fmt.Printf("- %d tracks would change. run without --dry-run to apply.\n\n", len(after))
+			return
+		}
+
+		if !tidyYes {
+			confirmed, err := util.Confirm(fmt.Sprintf("- apply the changes to %d tracks?", len(after)))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting confirmation: %v\n", err)
+				os.Exit(1)
+			}
+			if !confirmed {
+				fmt.Print("\n- tidy cancelled.\n\n")
+				return
+			}
+		}
+
+		trackManager.BatchMode(true)
+		for i := range after {
+			if err := trackManager.UpdateTrack(&after[i]); err != nil {
+				log.Fatalf("error updating track %s: %v", after[i].ID, err)
+			}
+		}
+		if err := trackManager.SaveAll(); err != nil {
+			log.Fatalf("error saving tracks: %v", err)
+		}
+		fmt.Printf("\n- tidied %d tracks.\n\n", len(after))
+	},
+}
+
+// printTidyDiff prints the title and artist of a track before and after cleaning it up.
+func printTidyDiff(before, after yt.TrackInfo) {
+	fmt.Println(util.Bold(before.ID))
+	if before.Title != after.Title {
+		fmt.Println(util.Red("- title:  " + before.Title))
+		fmt.Println(util.Green("+ title:  " + after.Title))
+	}
+	if before.Artist != after.Artist {
+		if before.Artist != "" {
+			fmt.Println(util.Red("- artist: " + before.Artist))
+		}
+		fmt.Println(util.Green("+ artist: " + after.Artist))
+	}
+	fmt.Println()
+}
+
+// tidyNewTrack cleans up the title and artist of a newly downloaded track.
+// Invalid title_rules are reported and left out.
+func tidyNewTrack(track *yt.TrackInfo) {
+	cleaner, err := tidy.New(cfg.TitleRules)
+	if err != nil {
+		log.Printf("warning: invalid title_rules: %v", err)
+	}
+	cleaner.Apply(track)
+}
+
+func init() {
+	tidyCmd.Flags().BoolVar(&tidyDryRun, "dry-run", false, "show the changes without applying them")
+	tidyCmd.Flags().BoolVarP(&tidyYes, "yes", "y", false, "apply the changes without asking")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/tracks"
+	"ytpl/internal/yt"
+)
+
+func TestTidyCommand(t *testing.T) {
+	downloadDir := setupTestEnv(t)
+	t.Cleanup(func() { tidyDryRun, tidyYes = false, false })
+
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	library, err := tracks.NewManager("", downloadDir)
+	require.NoError(t, err)
+	for _, track := range []yt.TrackInfo{
+		{ID: "aaaaaaaaaaa", Title: "Rick Astley - Never Gonna Give You Up (Official Music Video)", Uploader: "Rick Astley"},
+		{ID: "bbbbbbbbbbb", Title: "Already Tidy", Artist: "Band"},
+	} {
+		require.NoError(t, library.AddTrack(track))
+	}
+
+	titles := func(t *testing.T) map[string][2]string {
+		library := tracks.New(filepath.Join(downloadDir, ".tracks"))
+		require.NoError(t, library.Load())
+		result := make(map[string][2]string)
+		for _, track := range library.List() {
+			result[track.ID] = [2]string{track.Title, track.Artist}
+		}
+		return result
+	}
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		t.Cleanup(func() { tidyDryRun = false })
+		runCommand(t, "tidy", "--dry-run")
+
+		assert.Equal(t, [2]string{"Rick Astley - Never Gonna Give You Up (Official Music Video)", ""}, titles(t)["aaaaaaaaaaa"])
+	})
+
+	t.Run("applies the changes", func(t *testing.T) {
+		runCommand(t, "tidy", "--yes")
+
+		assert.Equal(t, map[string][2]string{
+			"aaaaaaaaaaa": {"Never Gonna Give You Up", "Rick Astley"},
+			"bbbbbbbbbbb": {"Already Tidy", "Band"},
+		}, titles(t))
+	})
+}
@@ -0,0 +1,94 @@
+// internal/album/album.go
+package album
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Album is an album in the library: stocked tracks kept in track order.
+type Album struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+	Year   int    `json:"year,omitempty"`
+	// Tracks holds the track IDs in track order; the track number of a track is its position plus one.
+	Tracks []string `json:"tracks"`
+	Cover  string   `json:"cover,omitempty"` // URL or path of the cover image
+}
+
+// Add appends the tracks that aren't on the album yet and returns how many were added.
+func (a *Album) Add(trackIDs ...string) int {
+	added := 0
+	for _, id := range trackIDs {
+		if a.TrackNumber(id) == 0 {
+			a.Tracks = append(a.Tracks, id)
+			added++
+		}
+	}
+	return added
+}
+
+// TrackNumber returns the track number of a track on the album, or 0 if it's not on it.
+func (a *Album) TrackNumber(trackID string) int {
+	for i, id := range a.Tracks {
+		if id == trackID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Load reads the albums stored at path.
+// A missing file means there are no albums.
+func Load(path string) ([]*Album, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read albums file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var albums []*Album
+	if err := json.Unmarshal(data, &albums); err != nil {
+		return nil, fmt.Errorf("failed to parse albums file %s: %w", path, err)
+	}
+	return albums, nil
+}
+
+// Save writes the albums to path atomically.
+func Save(path string, albums []*Album) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create albums directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(albums, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal albums: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write albums file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace albums file: %w", err)
+	}
+	return nil
+}
+
+// Find returns the index of the album with the given title, ignoring case, or -1.
+func Find(albums []*Album, title string) int {
+	for i, a := range albums {
+		if strings.EqualFold(a.Title, strings.TrimSpace(title)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,166 @@
+// internal/audiotag/audiotag.go
+
+// Package audiotag writes metadata into the tags embedded in audio files: ID3v2 for mp3,
+// Vorbis comments for flac, opus and ogg, and iTunes metadata atoms for m4a.
+// Embedded pictures such as the thumbnail yt-dlp embeds are always kept.
+package audiotag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// ErrUnsupported is returned for audio files whose tags can't be written, e.g. wav.
+var ErrUnsupported = errors.New("writing tags is not supported for this audio format")
+
+// Field is a text field of the tags.
+type Field string
+
+const (
+	FieldTitle       Field = "title"
+	FieldArtist      Field = "artist"
+	FieldAlbum       Field = "album"
+	FieldYear        Field = "year"
+	FieldGenre       Field = "genre"
+	FieldTrackNumber Field = "track"
+	FieldComment     Field = "comment"
+)
+
+// Tags are the tags written into an audio file. Empty fields are left as they are in the file,
+// so that tags such as the comment yt-dlp embeds are kept when the library has no value for them.
+type Tags struct {
+	Title       string
+	Artist      string
+	Album       string
+	Year        int
+	Genre       string
+	TrackNumber int
+	Comment     string
+	// Clear lists the fields removed from the file, e.g. those cleared in the library.
+	Clear []Field
+	// Cover is embedded as the front cover if the file has no picture yet.
+	// Pictures already in the file are never replaced or removed.
+	Cover *Picture
+}
+
+// Picture is an image embedded in an audio file.
+type Picture struct {
+	MIMEType string // "image/jpeg" or "image/png"
+	Data     []byte
+}
+
+// Write sets the non-empty fields of tags in the audio file at path and removes the fields of
+// tags.Clear, keeping embedded pictures and the other tags. The file is replaced atomically.
+func Write(path string, tags Tags) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return writeID3(path, tags)
+	case ".flac":
+		return rewrite(path, func(data []byte) ([]byte, error) { return writeFLAC(data, tags) })
+	case ".opus", ".ogg", ".oga":
+		return rewrite(path, func(data []byte) ([]byte, error) { return writeOgg(data, tags) })
+	case ".m4a", ".mp4", ".m4b":
+		return rewrite(path, func(data []byte) ([]byte, error) { return writeMP4(data, tags) })
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, filepath.Ext(path))
+	}
+}
+
+// Supported reports whether the tags of the audio file at path can be written, by its extension.
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".flac", ".opus", ".ogg", ".oga", ".m4a", ".mp4", ".m4b":
+		return true
+	}
+	return false
+}
+
+// Read returns the tags embedded in the audio file at path. The cover is the embedded picture,
+// if any.
+func Read(path string) (Tags, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer file.Close()
+
+	m, err := tag.ReadFrom(file)
+	if err != nil {
+		if errors.Is(err, tag.ErrNoTagsFound) {
+			return Tags{}, nil
+		}
+		return Tags{}, fmt.Errorf("failed to read tags of %s: %w", path, err)
+	}
+	trackNumber, _ := m.Track()
+	tags := Tags{
+		Title:       m.Title(),
+		Artist:      m.Artist(),
+		Album:       m.Album(),
+		Year:        m.Year(),
+		Genre:       m.Genre(),
+		TrackNumber: trackNumber,
+		Comment:     m.Comment(),
+	}
+	if picture := m.Picture(); picture != nil {
+		tags.Cover = &Picture{MIMEType: picture.MIMEType, Data: picture.Data}
+	}
+	return tags, nil
+}
+
+// clears reports whether the field is removed from the file.
+func (t Tags) clears(field Field) bool {
+	for _, f := range t.Clear {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite replaces the audio file at path with the result of update applied to its contents,
+// through a temporary file so that a failure leaves the file untouched.
+func rewrite(path string, update func(data []byte) ([]byte, error)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, err := update(data)
+	if err != nil {
+		return fmt.Errorf("failed to write tags of %s: %w", path, err)
+	}
+
+	tempPath := path + ".tags.tmp"
+	if err := os.WriteFile(tempPath, updated, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// yearString formats a year tag, leaving 0 empty.
+func yearString(year int) string {
+	if year <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", year)
+}
+
+// trackNumberString formats a track number tag, leaving 0 empty.
+func trackNumberString(number int) string {
+	if number <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", number)
+}
@@ -0,0 +1,328 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// Image data recognized by their signatures
+	embeddedJPEG = append([]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), bytes.Repeat([]byte{0x42}, 64)...)
+	newPNG       = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0x24}, 64)...)
+
+	// oldComment is a comment already in the file, kept as newTags has none
+	oldComment = "https://www.youtube.com/watch?v=aaaaaaaaaaa"
+
+	newTags = Tags{
+		Title:       "New Title",
+		Artist:      "New Artist",
+		Album:       "New Album",
+		Year:        2021,
+		Genre:       "Pop",
+		TrackNumber: 3,
+		Cover:       &Picture{MIMEType: "image/png", Data: newPNG},
+	}
+)
+
+// assertTags checks the tags read back from path against newTags, with the given cover.
+func assertTags(t *testing.T, path string, cover []byte) {
+	t.Helper()
+	tags, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, newTags.Title, tags.Title)
+	assert.Equal(t, newTags.Artist, tags.Artist)
+	assert.Equal(t, newTags.Album, tags.Album)
+	assert.Equal(t, newTags.Year, tags.Year)
+	assert.Equal(t, newTags.Genre, tags.Genre)
+	assert.Equal(t, newTags.TrackNumber, tags.TrackNumber)
+	require.NotNil(t, tags.Cover)
+	assert.Equal(t, cover, tags.Cover.Data)
+}
+
+func TestWriteID3(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 32)
+	for _, tc := range []struct {
+		name      string
+		embedded  bool
+		wantCover []byte
+	}{
+		{"keeps the embedded thumbnail", true, embeddedJPEG},
+		{"embeds the cover", false, newPNG},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			require.NoError(t, os.WriteFile(path, audio, 0644))
+			id3, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			require.NoError(t, err)
+			id3.SetVersion(3)
+			id3.SetTitle("Old Title")
+			id3.SetYear("1999")
+			id3.AddCommentFrame(id3v2.CommentFrame{Encoding: id3v2.EncodingISO, Language: "eng", Text: oldComment})
+			if tc.embedded {
+				id3.AddAttachedPicture(id3v2.PictureFrame{
+					Encoding: id3v2.EncodingISO, MimeType: "image/jpeg", PictureType: id3v2.PTFrontCover, Picture: embeddedJPEG,
+				})
+			}
+			require.NoError(t, id3.Save())
+			require.NoError(t, id3.Close())
+
+			require.NoError(t, Write(path, newTags))
+			assertTags(t, path, tc.wantCover)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(data, audio), "audio data must be kept")
+			assert.NotContains(t, string(data), "TYER")
+			tags, err := Read(path)
+			require.NoError(t, err)
+			assert.Equal(t, oldComment, tags.Comment, "fields left empty must be kept")
+		})
+	}
+}
+
+// flacFile builds a FLAC file with the given metadata blocks after the stream info.
+func flacFile(blocks ...flacBlock) []byte {
+	blocks = append([]flacBlock{{kind: flacStreamInfo, data: make([]byte, 34)}}, blocks...)
+	data := []byte("fLaC")
+	for i, block := range blocks {
+		header := block.kind
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		size := len(block.data)
+		data = append(data, header, byte(size>>16), byte(size>>8), byte(size))
+		data = append(data, block.data...)
+	}
+	return append(data, "audio frames"...)
+}
+
+func TestWriteFLAC(t *testing.T) {
+	comment := &vorbisComment{vendor: "reference libFLAC", fields: []string{"TITLE=Old Title", "ENCODER=Lavf", "DATE=1999", "COMMENT=" + oldComment}}
+	path := filepath.Join(t.TempDir(), "track.flac")
+	require.NoError(t, os.WriteFile(path, flacFile(
+		flacBlock{kind: flacVorbisComment, data: comment.bytes()},
+		flacBlock{kind: flacPicture, data: pictureBlock(&Picture{MIMEType: "image/jpeg", Data: embeddedJPEG})},
+		flacBlock{kind: flacPadding, data: make([]byte, 1024)},
+	), 0644))
+
+	require.NoError(t, Write(path, newTags))
+	assertTags(t, path, embeddedJPEG)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasSuffix(data, []byte("audio frames")))
+	assert.Contains(t, string(data), "ENCODER=Lavf", "unmanaged comments must be kept")
+	assert.Equal(t, 1, bytes.Count(data, []byte("\x89PNG"))+bytes.Count(data, embeddedJPEG), "only one picture")
+	assert.NotContains(t, string(data), "1999")
+	assert.Contains(t, string(data), "COMMENT="+oldComment, "fields left empty must be kept")
+}
+
+func TestWriteFLACEmbedsCover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.flac")
+	require.NoError(t, os.WriteFile(path, flacFile(), 0644))
+
+	require.NoError(t, Write(path, newTags))
+	assertTags(t, path, newPNG)
+}
+
+// opusFile builds an Opus Ogg file whose comment header has the given fields, followed by two audio pages.
+func opusFile(fields ...string) []byte {
+	const serial = 0x1234
+	head := append([]byte("OpusHead"), 1, 2, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	comment := &vorbisComment{vendor: "Lavf", fields: fields}
+	pages := paginate([][]byte{head}, serial, 0)
+	pages[0].flags |= oggFirst
+	pages = append(pages, paginate([][]byte{append([]byte("OpusTags"), comment.bytes()...)}, serial, uint32(len(pages)))...)
+	for i := 0; i < 2; i++ {
+		pages = append(pages, &oggPage{
+			granule: uint64(960 * (i + 1)), serial: serial, sequence: uint32(len(pages)),
+			segments: []byte{3}, body: []byte{0xfc, 0xff, 0xfe},
+		})
+	}
+	var data []byte
+	for _, page := range pages {
+		data = append(data, page.bytes()...)
+	}
+	return data
+}
+
+// assertOggPages checks the CRCs and sequence numbers of the pages of an Ogg file.
+func assertOggPages(t *testing.T, data []byte) {
+	t.Helper()
+	for sequence := uint32(0); len(data) > 0; sequence++ {
+		page, size, err := readOggPage(data)
+		require.NoError(t, err)
+		assert.Equal(t, sequence, page.sequence)
+		assert.Equal(t, data[:size], page.bytes(), "CRC of page %d", sequence)
+		data = data[size:]
+	}
+}
+
+func TestWriteOgg(t *testing.T) {
+	embedded := "METADATA_BLOCK_PICTURE=" + base64.StdEncoding.EncodeToString(pictureBlock(&Picture{MIMEType: "image/jpeg", Data: embeddedJPEG}))
+	// A large picture, as embedded by yt-dlp, spans several header pages
+	large := append(append([]byte(nil), embeddedJPEG...), bytes.Repeat([]byte{0x42}, 200_000)...)
+	largeEmbedded := "METADATA_BLOCK_PICTURE=" + base64.StdEncoding.EncodeToString(pictureBlock(&Picture{MIMEType: "image/jpeg", Data: large}))
+
+	for _, tc := range []struct {
+		name      string
+		fields    []string
+		wantCover []byte
+	}{
+		{"keeps the embedded thumbnail", []string{"title=Old Title", embedded}, embeddedJPEG},
+		{"keeps a large thumbnail", []string{"title=Old Title", largeEmbedded}, large},
+		{"embeds the cover", []string{"title=Old Title"}, newPNG},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.opus")
+			original := opusFile(tc.fields...)
+			require.NoError(t, os.WriteFile(path, original, 0644))
+
+			require.NoError(t, Write(path, newTags))
+			assertTags(t, path, tc.wantCover)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assertOggPages(t, data)
+			assert.NotContains(t, string(data), "Old Title")
+		})
+	}
+}
+
+func TestWriteOggRejectsOtherCodecs(t *testing.T) {
+	page := &oggPage{flags: oggFirst, segments: []byte{8}, body: []byte("\x80theora\x00")}
+	path := filepath.Join(t.TempDir(), "video.ogg")
+	require.NoError(t, os.WriteFile(path, page.bytes(), 0644))
+
+	assert.Error(t, Write(path, newTags))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, page.bytes(), data, "the file must be left untouched")
+}
+
+// mp4File builds an m4a file with an optional cover item and a chunk offset pointing to the media data.
+func mp4File(cover []byte) []byte {
+	var ilst []byte
+	ilst = append(ilst, mp4Item(mp4Title, mp4UTF8, []byte("Old Title"))...)
+	ilst = append(ilst, mp4Item("\xa9too", mp4UTF8, []byte("Lavf"))...)
+	ilst = append(ilst, mp4Item(mp4Comment, mp4UTF8, []byte(oldComment))...)
+	if cover != nil {
+		ilst = append(ilst, mp4Item(mp4Cover, mp4JPEG, cover)...)
+	}
+	meta := newMP4Box("meta", make([]byte, 4),
+		newMP4Box("hdlr", make([]byte, 8), []byte("mdirappl"), make([]byte, 9)),
+		newMP4Box("ilst", ilst))
+
+	ftyp := newMP4Box("ftyp", []byte("M4A \x00\x00\x02\x00isomiso2"))
+	stco := func(offset uint32) []byte {
+		return newMP4Box("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, binary.BigEndian.AppendUint32(nil, offset))
+	}
+	trak := func(offset uint32) []byte {
+		return newMP4Box("trak", newMP4Box("mdia", newMP4Box("minf", newMP4Box("stbl", stco(offset)))))
+	}
+	moovSize := len(newMP4Box("moov", trak(0), newMP4Box("udta", meta)))
+	offset := uint32(len(ftyp) + moovSize + 8)
+
+	data := append(ftyp, newMP4Box("moov", trak(offset), newMP4Box("udta", meta))...)
+	return append(data, newMP4Box("mdat", []byte("audio samples"))...)
+}
+
+// mp4ChunkOffset returns the chunk offset of the first track of an m4a file.
+func mp4ChunkOffset(t *testing.T, data []byte) uint32 {
+	t.Helper()
+	box := mp4Box{end: len(data)}
+	for _, kind := range []string{"moov", "trak", "mdia", "minf", "stbl", "stco"} {
+		boxes, err := mp4Boxes(data, box.payload, box.end)
+		require.NoError(t, err)
+		var ok bool
+		box, ok = findMP4Box(boxes, kind)
+		require.True(t, ok, "missing %s box", kind)
+	}
+	return binary.BigEndian.Uint32(data[box.payload+8:])
+}
+
+func TestWriteMP4(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		embedded  []byte
+		wantCover []byte
+	}{
+		{"keeps the embedded thumbnail", embeddedJPEG, embeddedJPEG},
+		{"embeds the cover", nil, newPNG},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.m4a")
+			require.NoError(t, os.WriteFile(path, mp4File(tc.embedded), 0644))
+
+			require.NoError(t, Write(path, newTags))
+			assertTags(t, path, tc.wantCover)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			offset := mp4ChunkOffset(t, data)
+			assert.Equal(t, "audio samples", string(data[offset:offset+13]), "chunk offsets must follow the media data")
+			assert.Contains(t, string(data), "\xa9too", "unmanaged items must be kept")
+			assert.NotContains(t, string(data), "Old Title")
+			assert.Contains(t, string(data), oldComment, "fields left empty must be kept")
+		})
+	}
+}
+
+func TestWriteClearsFields(t *testing.T) {
+	id3File := func(t *testing.T, path string) {
+		require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 32), 0644))
+		id3, err := id3v2.Open(path, id3v2.Options{Parse: true})
+		require.NoError(t, err)
+		id3.SetVersion(3)
+		id3.SetTitle("Old Title")
+		id3.SetYear("1999")
+		id3.AddCommentFrame(id3v2.CommentFrame{Encoding: id3v2.EncodingISO, Language: "eng", Text: oldComment})
+		require.NoError(t, id3.Save())
+		require.NoError(t, id3.Close())
+	}
+	comment := &vorbisComment{vendor: "Lavf", fields: []string{"TITLE=Old Title", "DATE=1999", "COMMENT=" + oldComment}}
+
+	for _, tc := range []struct {
+		name  string
+		write func(t *testing.T, path string)
+	}{
+		{"track.mp3", id3File},
+		{"track.flac", func(t *testing.T, path string) {
+			require.NoError(t, os.WriteFile(path, flacFile(flacBlock{kind: flacVorbisComment, data: comment.bytes()}), 0644))
+		}},
+		{"track.opus", func(t *testing.T, path string) {
+			require.NoError(t, os.WriteFile(path, opusFile(comment.fields...), 0644))
+		}},
+		{"track.m4a", func(t *testing.T, path string) {
+			require.NoError(t, os.WriteFile(path, mp4File(nil), 0644))
+			require.NoError(t, Write(path, Tags{Year: 1999, Comment: oldComment}))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.name)
+			tc.write(t, path)
+
+			require.NoError(t, Write(path, Tags{Title: "New Title", Clear: []Field{FieldYear, FieldComment}}))
+			tags, err := Read(path)
+			require.NoError(t, err)
+			assert.Equal(t, "New Title", tags.Title)
+			assert.Zero(t, tags.Year, "cleared fields must be removed")
+			assert.Empty(t, tags.Comment, "cleared fields must be removed")
+		})
+	}
+}
+
+func TestWriteUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.wav")
+	require.NoError(t, os.WriteFile(path, []byte("RIFF"), 0644))
+
+	assert.ErrorIs(t, Write(path, newTags), ErrUnsupported)
+}
@@ -0,0 +1,93 @@
+// internal/audiotag/flac.go
+package audiotag
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FLAC metadata block types. See https://xiph.org/flac/format.html#metadata_block.
+const (
+	flacStreamInfo    = 0
+	flacPadding       = 1
+	flacVorbisComment = 4
+	flacPicture       = 6
+)
+
+// flacBlock is a metadata block of a FLAC file.
+type flacBlock struct {
+	kind byte
+	data []byte
+}
+
+// writeFLAC returns the FLAC file data with the tags written into its Vorbis comment block.
+// Picture blocks are kept; padding is dropped, as the whole file is rewritten anyway.
+func writeFLAC(data []byte, tags Tags) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return nil, fmt.Errorf("not a FLAC file")
+	}
+
+	// Read the metadata blocks up to the one flagged as the last
+	var blocks []flacBlock
+	pos := 4
+	for last := false; !last; {
+		if len(data) < pos+4 {
+			return nil, fmt.Errorf("truncated FLAC metadata")
+		}
+		last = data[pos]&0x80 != 0
+		kind := data[pos] & 0x7f
+		size := int(data[pos+1])<<16 | int(data[pos+2])<<8 | int(data[pos+3])
+		pos += 4
+		if len(data) < pos+size {
+			return nil, fmt.Errorf("truncated FLAC metadata")
+		}
+		blocks = append(blocks, flacBlock{kind: kind, data: data[pos : pos+size]})
+		pos += size
+	}
+	if len(blocks) == 0 || blocks[0].kind != flacStreamInfo {
+		return nil, fmt.Errorf("FLAC file without stream info")
+	}
+
+	comment := &vorbisComment{vendor: "ytpl"}
+	hasPicture := false
+	var kept []flacBlock
+	for _, block := range blocks {
+		switch block.kind {
+		case flacVorbisComment:
+			parsed, _, err := parseVorbisComment(block.data)
+			if err != nil {
+				return nil, err
+			}
+			comment = parsed
+		case flacPadding:
+		default:
+			hasPicture = hasPicture || block.kind == flacPicture
+			kept = append(kept, block)
+		}
+	}
+	comment.apply(tags)
+
+	// The comment goes right after the stream info, which must come first
+	blocks = append([]flacBlock{kept[0], {kind: flacVorbisComment, data: comment.bytes()}}, kept[1:]...)
+	if tags.Cover != nil && !hasPicture {
+		blocks = append(blocks, flacBlock{kind: flacPicture, data: pictureBlock(tags.Cover)})
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.WriteString("fLaC")
+	for i, block := range blocks {
+		if len(block.data) >= 1<<24 {
+			return nil, fmt.Errorf("FLAC metadata block too large")
+		}
+		header := block.kind
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		size := len(block.data)
+		out.Write([]byte{header, byte(size >> 16), byte(size >> 8), byte(size)})
+		out.Write(block.data)
+	}
+	out.Write(data[pos:])
+	return out.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+// internal/audiotag/id3.go
+package audiotag
+
+import (
+	"fmt"
+
+	"github.com/bogem/id3v2/v2"
+)
+
+// writeID3 writes the tags into the ID3v2 tag of an mp3 file, upgrading it to ID3v2.4.
+// Frames other than the written ones, such as attached pictures, are kept.
+func writeID3(path string, tags Tags) error {
+	id3, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to read ID3 tag of %s: %w", path, err)
+	}
+	defer id3.Close()
+
+	id3.SetVersion(4)
+	id3.SetDefaultEncoding(id3v2.EncodingUTF8)
+	setText := func(id string, field Field, value string) {
+		if value != "" || tags.clears(field) {
+			id3.DeleteFrames(id)
+		}
+		if value != "" {
+			id3.AddTextFrame(id, id3.DefaultEncoding(), value)
+		}
+	}
+	setText("TIT2", FieldTitle, tags.Title)
+	setText("TPE1", FieldArtist, tags.Artist)
+	setText("TALB", FieldAlbum, tags.Album)
+	if tags.Year > 0 || tags.clears(FieldYear) {
+		id3.DeleteFrames("TYER") // The ID3v2.3 year frame, replaced by TDRC in ID3v2.4
+	}
+	setText("TDRC", FieldYear, yearString(tags.Year))
+	setText("TCON", FieldGenre, tags.Genre)
+	setText("TRCK", FieldTrackNumber, trackNumberString(tags.TrackNumber))
+
+	if tags.Comment != "" || tags.clears(FieldComment) {
+		id3.DeleteFrames("COMM")
+	}
+	if tags.Comment != "" {
+		id3.AddCommentFrame(id3v2.CommentFrame{
+			Encoding: id3.DefaultEncoding(),
+			Language: "eng",
+			Text:     tags.Comment,
+		})
+	}
+
+	if tags.Cover != nil && len(id3.GetFrames("APIC")) == 0 {
+		id3.AddAttachedPicture(id3v2.PictureFrame{
+			Encoding:    id3.DefaultEncoding(),
+			MimeType:    tags.Cover.MIMEType,
+			PictureType: id3v2.PTFrontCover,
+			Description: "Cover",
+			Picture:     tags.Cover.Data,
+		})
+	}
+
+	if err := id3.Save(); err != nil {
+		return fmt.Errorf("failed to save ID3 tag of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// internal/audiotag/mp4.go
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// mp4Box is a box (atom) of an MP4 file, located by offsets into the file data.
+type mp4Box struct {
+	kind    string
+	start   int // Offset of the box header
+	payload int // Offset of the box contents
+	end     int
+}
+
+// iTunes metadata items written from the tags. "gnre" is the numeric genre, replaced by "©gen".
+const (
+	mp4Title       = "\xa9nam"
+	mp4Artist      = "\xa9ART"
+	mp4Album       = "\xa9alb"
+	mp4Year        = "\xa9day"
+	mp4Genre       = "\xa9gen"
+	mp4GenreID     = "gnre"
+	mp4TrackNumber = "trkn"
+	mp4Comment     = "\xa9cmt"
+	mp4Cover       = "covr"
+)
+
+// Type indicators of iTunes metadata values.
+const (
+	mp4Implicit = 0
+	mp4UTF8     = 1
+	mp4JPEG     = 13
+	mp4PNG      = 14
+)
+
+// mp4Boxes parses the boxes between the offsets start and end of data.
+func mp4Boxes(data []byte, start, end int) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for pos := start; pos < end; {
+		if end-pos < 8 {
+			return nil, fmt.Errorf("truncated MP4 box")
+		}
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		box := mp4Box{kind: string(data[pos+4 : pos+8]), start: pos, payload: pos + 8}
+		switch size {
+		case 0: // The box extends to the end
+			size = end - pos
+		case 1: // 64-bit size
+			if end-pos < 16 {
+				return nil, fmt.Errorf("truncated MP4 box")
+			}
+			large := binary.BigEndian.Uint64(data[pos+8:])
+			if large > uint64(end-pos) {
+				return nil, fmt.Errorf("truncated MP4 box %q", box.kind)
+			}
+			size = int(large)
+			box.payload = pos + 16
+		}
+		if size < box.payload-pos || size > end-pos {
+			return nil, fmt.Errorf("invalid size of MP4 box %q", box.kind)
+		}
+		box.end = pos + size
+		boxes = append(boxes, box)
+		pos = box.end
+	}
+	return boxes, nil
+}
+
+// findMP4Box returns the first box of the given kind.
+func findMP4Box(boxes []mp4Box, kind string) (mp4Box, bool) {
+	for _, box := range boxes {
+		if box.kind == kind {
+			return box, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// newMP4Box serializes a box with the given contents.
+func newMP4Box(kind string, contents ...[]byte) []byte {
+	size := 8
+	for _, c := range contents {
+		size += len(c)
+	}
+	box := binary.BigEndian.AppendUint32(make([]byte, 0, size), uint32(size))
+	box = append(box, kind...)
+	for _, c := range contents {
+		box = append(box, c...)
+	}
+	return box
+}
+
+// withMP4Child returns the contents of the children with the first box of the given kind
+// replaced by child, or child appended if there is none.
+func withMP4Child(data []byte, children []mp4Box, kind string, child []byte) []byte {
+	var contents []byte
+	replaced := false
+	for _, box := range children {
+		if box.kind == kind && !replaced {
+			contents = append(contents, child...)
+			replaced = true
+			continue
+		}
+		contents = append(contents, data[box.start:box.end]...)
+	}
+	if !replaced {
+		contents = append(contents, child...)
+	}
+	return contents
+}
+
+// writeMP4 returns the MP4 file data with the tags written into the iTunes metadata list
+// moov/udta/meta/ilst. The chunk offsets are updated if the media data follows the moov box.
+func writeMP4(data []byte, tags Tags) ([]byte, error) {
+	top, err := mp4Boxes(data, 0, len(data))
+	if err != nil {
+		return nil, err
+	}
+	moov, ok := findMP4Box(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("MP4 file without moov box")
+	}
+
+	moovChildren, err := mp4Boxes(data, moov.payload, moov.end)
+	if err != nil {
+		return nil, err
+	}
+	var udtaChildren, metaChildren, ilstChildren []mp4Box
+	metaHead := make([]byte, 4) // Version and flags
+	if udta, ok := findMP4Box(moovChildren, "udta"); ok {
+		if udtaChildren, err = mp4Boxes(data, udta.payload, udta.end); err != nil {
+			return nil, err
+		}
+	}
+	if meta, ok := findMP4Box(udtaChildren, "meta"); ok {
+		if meta.end-meta.payload < 4 {
+			return nil, fmt.Errorf("invalid MP4 meta box")
+		}
+		metaHead = data[meta.payload : meta.payload+4]
+		if metaChildren, err = mp4Boxes(data, meta.payload+4, meta.end); err != nil {
+			return nil, err
+		}
+	}
+	if ilst, ok := findMP4Box(metaChildren, "ilst"); ok {
+		if ilstChildren, err = mp4Boxes(data, ilst.payload, ilst.end); err != nil {
+			return nil, err
+		}
+	}
+
+	ilst, err := mp4ItemList(data, ilstChildren, tags)
+	if err != nil {
+		return nil, err
+	}
+	metaContents := withMP4Child(data, metaChildren, "ilst", ilst)
+	if _, ok := findMP4Box(metaChildren, "hdlr"); !ok {
+		// The metadata handler must come first
+		hdlr := newMP4Box("hdlr", make([]byte, 8), []byte("mdirappl"), make([]byte, 9))
+		metaContents = append(hdlr, metaContents...)
+	}
+	meta := newMP4Box("meta", metaHead, metaContents)
+	udta := newMP4Box("udta", withMP4Child(data, udtaChildren, "meta", meta))
+	newMoov := newMP4Box("moov", withMP4Child(data, moovChildren, "udta", udta))
+
+	// Media data after the moov box moves by the change of its size
+	delta := len(newMoov) - (moov.end - moov.start)
+	if delta != 0 {
+		for _, box := range top {
+			if box.start < moov.start {
+				continue
+			}
+			if box.kind == "moof" {
+				return nil, fmt.Errorf("fragmented MP4 files are not supported")
+			}
+			if box.kind == "mdat" {
+				if err := shiftChunkOffsets(newMoov, delta); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	out := make([]byte, 0, len(data)+delta)
+	out = append(out, data[:moov.start]...)
+	out = append(out, newMoov...)
+	return append(out, data[moov.end:]...), nil
+}
+
+// mp4ItemList returns the ilst box with the non-empty items of the tags replacing those in items,
+// and without the cleared ones. Other items, such as the cover, are kept.
+func mp4ItemList(data []byte, items []mp4Box, tags Tags) ([]byte, error) {
+	replaced := map[string]bool{
+		mp4Title:       tags.Title != "" || tags.clears(FieldTitle),
+		mp4Artist:      tags.Artist != "" || tags.clears(FieldArtist),
+		mp4Album:       tags.Album != "" || tags.clears(FieldAlbum),
+		mp4Year:        tags.Year > 0 || tags.clears(FieldYear),
+		mp4Genre:       tags.Genre != "" || tags.clears(FieldGenre),
+		mp4GenreID:     tags.Genre != "" || tags.clears(FieldGenre),
+		mp4TrackNumber: tags.TrackNumber > 0 || tags.clears(FieldTrackNumber),
+		mp4Comment:     tags.Comment != "" || tags.clears(FieldComment),
+	}
+	var contents []byte
+	hasCover := false
+	for _, item := range items {
+		if replaced[item.kind] {
+			continue
+		}
+		hasCover = hasCover || item.kind == mp4Cover
+		contents = append(contents, data[item.start:item.end]...)
+	}
+
+	text := func(kind, value string) {
+		if value != "" {
+			contents = append(contents, mp4Item(kind, mp4UTF8, []byte(value))...)
+		}
+	}
+	text(mp4Title, tags.Title)
+	text(mp4Artist, tags.Artist)
+	text(mp4Album, tags.Album)
+	text(mp4Year, yearString(tags.Year))
+	text(mp4Genre, tags.Genre)
+	text(mp4Comment, tags.Comment)
+	if tags.TrackNumber > 0 {
+		if tags.TrackNumber > math.MaxUint16 {
+			return nil, fmt.Errorf("track number %d too large", tags.TrackNumber)
+		}
+		number := make([]byte, 8) // Reserved, number, total, reserved
+		binary.BigEndian.PutUint16(number[2:], uint16(tags.TrackNumber))
+		contents = append(contents, mp4Item(mp4TrackNumber, mp4Implicit, number)...)
+	}
+
+	if tags.Cover != nil && !hasCover {
+		// MP4 covers can only be JPEG or PNG images
+		switch http.DetectContentType(tags.Cover.Data) {
+		case "image/jpeg":
+			contents = append(contents, mp4Item(mp4Cover, mp4JPEG, tags.Cover.Data)...)
+		case "image/png":
+			contents = append(contents, mp4Item(mp4Cover, mp4PNG, tags.Cover.Data)...)
+		}
+	}
+	return newMP4Box("ilst", contents), nil
+}
+
+// mp4Item serializes an iTunes metadata item with a single data box.
+func mp4Item(kind string, valueType uint32, value []byte) []byte {
+	head := make([]byte, 8) // Type indicator and locale
+	binary.BigEndian.PutUint32(head, valueType)
+	return newMP4Box(kind, newMP4Box("data", head, value))
+}
+
+// shiftChunkOffsets adds delta to the chunk offsets in the stco and co64 boxes of the tracks
+// of a serialized moov box.
+func shiftChunkOffsets(moov []byte, delta int) error {
+	var walk func(start, end int) error
+	walk = func(start, end int) error {
+		boxes, err := mp4Boxes(moov, start, end)
+		if err != nil {
+			return err
+		}
+		for _, box := range boxes {
+			switch box.kind {
+			case "trak", "mdia", "minf", "stbl":
+				if err := walk(box.payload, box.end); err != nil {
+					return err
+				}
+			case "stco", "co64":
+				if box.end-box.payload < 8 {
+					return fmt.Errorf("invalid MP4 %s box", box.kind)
+				}
+				count := int(binary.BigEndian.Uint32(moov[box.payload+4:]))
+				entrySize := 4
+				if box.kind == "co64" {
+					entrySize = 8
+				}
+				entries := moov[box.payload+8 : box.end]
+				if len(entries) < count*entrySize {
+					return fmt.Errorf("truncated MP4 %s box", box.kind)
+				}
+				for i := 0; i < count; i++ {
+					entry := entries[i*entrySize:]
+					if entrySize == 4 {
+						offset := int64(binary.BigEndian.Uint32(entry)) + int64(delta)
+						if offset < 0 || offset > math.MaxUint32 {
+							return fmt.Errorf("chunk offset out of range")
+						}
+						binary.BigEndian.PutUint32(entry, uint32(offset))
+					} else {
+						binary.BigEndian.PutUint64(entry, uint64(int64(binary.BigEndian.Uint64(entry))+int64(delta)))
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if !bytes.Equal(moov[4:8], []byte("moov")) {
+		return fmt.Errorf("not a moov box")
+	}
+	return walk(8, len(moov))
+}
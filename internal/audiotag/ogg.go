@@ -0,0 +1,213 @@
+// internal/audiotag/ogg.go
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Ogg page header flags. See https://xiph.org/ogg/doc/framing.html.
+const (
+	oggContinued = 0x01 // The page starts with the rest of a packet
+	oggFirst     = 0x02 // First page of the stream
+)
+
+// oggPage is a page of an Ogg stream.
+type oggPage struct {
+	flags    byte
+	granule  uint64
+	serial   uint32
+	sequence uint32
+	segments []byte // Lacing values
+	body     []byte
+}
+
+// oggCRCTable is the table of the CRC of Ogg pages: polynomial 0x04c11db7, not reflected.
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// readOggPage reads the page at the start of data and returns it with its size.
+func readOggPage(data []byte) (*oggPage, int, error) {
+	if len(data) < 27 || !bytes.HasPrefix(data, []byte("OggS")) {
+		return nil, 0, fmt.Errorf("invalid Ogg page")
+	}
+	count := int(data[26])
+	if len(data) < 27+count {
+		return nil, 0, fmt.Errorf("truncated Ogg page")
+	}
+	segments := data[27 : 27+count]
+	size := 27 + count
+	for _, lacing := range segments {
+		size += int(lacing)
+	}
+	if len(data) < size {
+		return nil, 0, fmt.Errorf("truncated Ogg page")
+	}
+	return &oggPage{
+		flags:    data[5],
+		granule:  binary.LittleEndian.Uint64(data[6:]),
+		serial:   binary.LittleEndian.Uint32(data[14:]),
+		sequence: binary.LittleEndian.Uint32(data[18:]),
+		segments: segments,
+		body:     data[27+count : size],
+	}, size, nil
+}
+
+// bytes serializes the page with its CRC.
+func (p *oggPage) bytes() []byte {
+	data := make([]byte, 27, 27+len(p.segments)+len(p.body))
+	copy(data, "OggS")
+	data[5] = p.flags
+	binary.LittleEndian.PutUint64(data[6:], p.granule)
+	binary.LittleEndian.PutUint32(data[14:], p.serial)
+	binary.LittleEndian.PutUint32(data[18:], p.sequence)
+	data[26] = byte(len(p.segments))
+	data = append(data, p.segments...)
+	data = append(data, p.body...)
+
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(data[22:], crc)
+	return data
+}
+
+// writeOgg returns the Opus or Vorbis Ogg file data with the tags written into its comment header.
+// The header pages are laid out again, so the following pages are renumbered.
+func writeOgg(data []byte, tags Tags) ([]byte, error) {
+	// Collect the header packets: identification and comment for Opus, plus setup for Vorbis
+	var packets [][]byte
+	var packet []byte
+	headerCount := 0
+	pos := 0
+	headerPages := 0
+	var serial uint32
+	for headerCount == 0 || len(packets) < headerCount {
+		page, size, err := readOggPage(data[pos:])
+		if err != nil {
+			return nil, err
+		}
+		if headerPages == 0 {
+			serial = page.serial
+		} else if page.serial != serial {
+			return nil, fmt.Errorf("multiplexed Ogg streams are not supported")
+		}
+		offset := 0
+		for i, lacing := range page.segments {
+			packet = append(packet, page.body[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing == 255 {
+				continue
+			}
+			packets = append(packets, packet)
+			packet = nil
+			if len(packets) == 1 {
+				switch {
+				case bytes.HasPrefix(packets[0], []byte("OpusHead")):
+					headerCount = 2
+				case bytes.HasPrefix(packets[0], []byte("\x01vorbis")):
+					headerCount = 3
+				default:
+					return nil, fmt.Errorf("unsupported Ogg codec")
+				}
+			}
+			if len(packets) == headerCount && i != len(page.segments)-1 {
+				return nil, fmt.Errorf("audio data shares a page with the headers")
+			}
+		}
+		pos += size
+		headerPages++
+	}
+
+	// Rewrite the comment header, keeping what follows the comments
+	prefix := []byte("OpusTags")
+	if headerCount == 3 {
+		prefix = []byte("\x03vorbis")
+	}
+	if !bytes.HasPrefix(packets[1], prefix) {
+		return nil, fmt.Errorf("missing Ogg comment header")
+	}
+	comment, rest, err := parseVorbisComment(packets[1][len(prefix):])
+	if err != nil {
+		return nil, err
+	}
+	comment.applyWithCover(tags)
+	packets[1] = append(append(append([]byte(nil), prefix...), comment.bytes()...), rest...)
+
+	// The identification header has a page of its own, the other headers share the next pages
+	var out bytes.Buffer
+	out.Grow(len(data))
+	pages := paginate([][]byte{packets[0]}, serial, 0)
+	pages[0].flags |= oggFirst
+	pages = append(pages, paginate(packets[1:], serial, uint32(len(pages)))...)
+	for _, page := range pages {
+		out.Write(page.bytes())
+	}
+
+	// Renumber the audio pages after the new header pages
+	for pos < len(data) {
+		page, size, err := readOggPage(data[pos:])
+		if err != nil {
+			return nil, err
+		}
+		if page.serial == serial {
+			page.sequence = page.sequence - uint32(headerPages) + uint32(len(pages))
+		}
+		out.Write(page.bytes())
+		pos += size
+	}
+	return out.Bytes(), nil
+}
+
+// paginate lays out header packets in pages of up to 255 segments, numbered from sequence.
+// Pages where a packet ends have granule position 0; the others have none (-1).
+func paginate(packets [][]byte, serial, sequence uint32) []*oggPage {
+	var pages []*oggPage
+	page := &oggPage{serial: serial, sequence: sequence}
+	ended, midPacket := false, false
+	flush := func() {
+		if !ended {
+			page.granule = ^uint64(0)
+		}
+		pages = append(pages, page)
+		page = &oggPage{serial: serial, sequence: sequence + uint32(len(pages))}
+		if midPacket {
+			page.flags = oggContinued
+		}
+		ended = false
+	}
+
+	for _, packet := range packets {
+		for remaining := packet; ; {
+			if len(page.segments) == 255 {
+				flush()
+			}
+			n := min(len(remaining), 255)
+			page.segments = append(page.segments, byte(n))
+			page.body = append(page.body, remaining[:n]...)
+			remaining = remaining[n:]
+			midPacket = n == 255
+			if !midPacket {
+				ended = true
+				break
+			}
+		}
+	}
+	flush()
+	return pages
+}
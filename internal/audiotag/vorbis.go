@@ -0,0 +1,146 @@
+// internal/audiotag/vorbis.go
+package audiotag
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// vorbisComment is a Vorbis comment block, the tags of flac, opus and ogg files.
+// See https://xiph.org/vorbis/doc/v-comment.html.
+type vorbisComment struct {
+	vendor string
+	fields []string // "NAME=value"
+}
+
+// parseVorbisComment parses a Vorbis comment block and returns it with the bytes following it,
+// such as the framing bit of Vorbis streams or the padding of Opus streams.
+func parseVorbisComment(data []byte) (*vorbisComment, []byte, error) {
+	next := func() (string, error) {
+		if len(data) < 4 {
+			return "", fmt.Errorf("truncated vorbis comment")
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(len(data)-4) < uint64(n) {
+			return "", fmt.Errorf("truncated vorbis comment")
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, nil
+	}
+
+	vendor, err := next()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("truncated vorbis comment")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	c := &vorbisComment{vendor: vendor}
+	for i := uint32(0); i < count; i++ {
+		field, err := next()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.fields = append(c.fields, field)
+	}
+	return c, data, nil
+}
+
+// bytes serializes the comment block.
+func (c *vorbisComment) bytes() []byte {
+	size := 8 + len(c.vendor)
+	for _, field := range c.fields {
+		size += 4 + len(field)
+	}
+	data := make([]byte, 0, size)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.vendor)))
+	data = append(data, c.vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.fields)))
+	for _, field := range c.fields {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
+
+// has reports whether the comment has a field with the given name.
+func (c *vorbisComment) has(name string) bool {
+	for _, field := range c.fields {
+		if key, _, _ := strings.Cut(field, "="); strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove removes the fields with the given name.
+func (c *vorbisComment) remove(name string) {
+	kept := c.fields[:0]
+	for _, field := range c.fields {
+		if key, _, _ := strings.Cut(field, "="); !strings.EqualFold(key, name) {
+			kept = append(kept, field)
+		}
+	}
+	c.fields = kept
+}
+
+// set replaces the fields with the given name with value. An empty value leaves them as they are.
+func (c *vorbisComment) set(name, value string) {
+	if value == "" {
+		return
+	}
+	c.remove(name)
+	c.fields = append(c.fields, name+"="+value)
+}
+
+// apply sets the non-empty text fields of the tags and removes the cleared ones.
+func (c *vorbisComment) apply(tags Tags) {
+	for _, f := range []struct {
+		name  string
+		field Field
+		value string
+	}{
+		{"TITLE", FieldTitle, tags.Title},
+		{"ARTIST", FieldArtist, tags.Artist},
+		{"ALBUM", FieldAlbum, tags.Album},
+		{"DATE", FieldYear, yearString(tags.Year)},
+		{"GENRE", FieldGenre, tags.Genre},
+		{"TRACKNUMBER", FieldTrackNumber, trackNumberString(tags.TrackNumber)},
+		{"COMMENT", FieldComment, tags.Comment},
+	} {
+		if f.value == "" && tags.clears(f.field) {
+			c.remove(f.name)
+		}
+		c.set(f.name, f.value)
+	}
+}
+
+// applyWithCover sets the text fields of the tags and embeds the cover as a
+// METADATA_BLOCK_PICTURE field, as done for opus and ogg files, if there is no picture yet.
+func (c *vorbisComment) applyWithCover(tags Tags) {
+	c.apply(tags)
+	if tags.Cover != nil && !c.has("METADATA_BLOCK_PICTURE") && !c.has("COVERART") {
+		c.fields = append(c.fields, "METADATA_BLOCK_PICTURE="+base64.StdEncoding.EncodeToString(pictureBlock(tags.Cover)))
+	}
+}
+
+// pictureBlock serializes a picture as a FLAC picture block, also used in Vorbis comments.
+// See https://xiph.org/flac/format.html#metadata_block_picture.
+func pictureBlock(picture *Picture) []byte {
+	const frontCover = 3
+	description := "Cover"
+	data := make([]byte, 0, 32+len(picture.MIMEType)+len(description)+len(picture.Data))
+	data = binary.BigEndian.AppendUint32(data, frontCover)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(picture.MIMEType)))
+	data = append(data, picture.MIMEType...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(description)))
+	data = append(data, description...)
+	data = append(data, make([]byte, 16)...) // Width, height, color depth and palette size: unknown
+	data = binary.BigEndian.AppendUint32(data, uint32(len(picture.Data)))
+	return append(data, picture.Data...)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/adrg/xdg"
@@ -14,21 +15,60 @@ import (
 
 // Config holds the application configuration.
 type Config struct {
-	DownloadDir         string `toml:"download_dir"`
-	PlayerPath          string `toml:"player_path"`
-	PlayerIPCSocketPath string `toml:"player_ipc_socket_path"`
-	DefaultVolume       int    `toml:"default_volume"`
-	YtDlpPath           string `toml:"yt_dlp_path"`
-	PlaylistDir         string `toml:"playlist_dir"`
-	CookieBrowser       string `toml:"cookie_browser"`
-	CookieProfile       string `toml:"cookie_profile"`
-	MaxSearchResults    int    `toml:"max_search_results"`
+	DownloadDir         string      `toml:"download_dir"`
+	PlayerPath          string      `toml:"player_path"`
+	PlayerIPCSocketPath string      `toml:"player_ipc_socket_path"`
+	DefaultVolume       int         `toml:"default_volume"`
+	YtDlpPath           string      `toml:"yt_dlp_path"`
+	PlaylistDir         string      `toml:"playlist_dir"`
+	CookieBrowser       string      `toml:"cookie_browser"`
+	CookieProfile       string      `toml:"cookie_profile"`
+	MaxSearchResults    int         `toml:"max_search_results"`
+	DownloadConcurrency int         `toml:"download_concurrency"`
+	AudioFormat         string      `toml:"audio_format"`
+	AudioQuality        string      `toml:"audio_quality"`
+	SearchCacheTTL      string      `toml:"search_cache_ttl"`
+	RadioMode           string      `toml:"radio_mode"`
+	RadioQueueSize      int         `toml:"radio_queue_size"`
+	MetadataFields      []string    `toml:"metadata_fields"`
+	TitleRules          []TitleRule `toml:"title_rules"`
 }
 
+// TitleRule is a user rule cleaning up track titles, applied after the built-in rules.
+// Matches of Pattern, a Go regular expression, are replaced with Replace, which may refer to
+// groups as $1. A pattern with groups named "artist" or "title" splits the title instead.
+type TitleRule struct {
+	Pattern string `toml:"pattern"`
+	Replace string `toml:"replace"`
+}
+
+// AudioFormats are the accepted values of audio_format.
+// "best" keeps the best audio stream without re-encoding it.
+var AudioFormats = []string{"best", "mp3", "opus", "m4a", "aac", "flac", "vorbis", "wav", "alac"}
+
+// DefaultMetadataFields are the yt-dlp metadata fields kept in .info.json files unless
+// metadata_fields is set.
+var DefaultMetadataFields = []string{
+	"id", "title", "uploader", "creator", "duration", "release_year", "upload_date",
+	"webpage_url", "extractor", "original_url", "thumbnail", "description",
+	"album", "artist", "track", "track_number", "genre",
+	"channel", "channel_id", "channel_url",
+	"chapters", "tags", "categories",
+}
+
+// Accepted values of radio_mode.
+const (
+	RadioStream   = "stream"   // Radio tracks are streamed without being stocked
+	RadioDownload = "download" // Radio tracks are downloaded to the stock before they play
+)
+
 const (
-	configFileName = "config.toml"
-	stateFileName  = "state.json"
-	appName        = "ytpl"
+	configFileName  = "config.toml"
+	stateFileName   = "state.json"
+	queueFileName   = "queue.json"
+	subsFileName    = "subscriptions.json"
+	historyFileName = "search_history.json"
+	appName         = "ytpl"
 )
 
 // LoadConfig loads the application configuration from config.toml.
@@ -99,6 +139,50 @@ func LoadConfig() (*Config, error) {
 	} else if cfg.MaxSearchResults < 1 { // Ensure it's at least 1
 		cfg.MaxSearchResults = 1
 	}
+	// Set default for DownloadConcurrency
+	if cfg.DownloadConcurrency < 1 { // If 0 or not set, default to 3
+		cfg.DownloadConcurrency = 3
+	}
+	// Set defaults for the audio format and quality
+	cfg.AudioFormat = strings.ToLower(strings.TrimSpace(cfg.AudioFormat))
+	if cfg.AudioFormat == "" {
+		cfg.AudioFormat = "mp3"
+	} else if !isAudioFormat(cfg.AudioFormat) {
+		log.Printf("warning: unsupported audio_format %q (supported: %s). using mp3.", cfg.AudioFormat, strings.Join(AudioFormats, ", "))
+		cfg.AudioFormat = "mp3"
+	}
+	if cfg.AudioQuality == "" {
+		cfg.AudioQuality = "0" // Best quality for VBR formats
+	}
+	// Set default for the search cache TTL
+	if cfg.SearchCacheTTL == "" {
+		cfg.SearchCacheTTL = defaultSearchCacheTTL
+	} else if _, err := parseTTL(cfg.SearchCacheTTL); err != nil {
+		log.Printf("warning: invalid search_cache_ttl %q: %v. using %s.", cfg.SearchCacheTTL, err, defaultSearchCacheTTL)
+		cfg.SearchCacheTTL = defaultSearchCacheTTL
+	}
+	// Set defaults for the radio
+	cfg.RadioMode = strings.ToLower(strings.TrimSpace(cfg.RadioMode))
+	if cfg.RadioMode == "" {
+		cfg.RadioMode = RadioStream
+	} else if cfg.RadioMode != RadioStream && cfg.RadioMode != RadioDownload {
+		log.Printf("warning: unsupported radio_mode %q (supported: %s, %s). using %s.", cfg.RadioMode, RadioStream, RadioDownload, RadioStream)
+		cfg.RadioMode = RadioStream
+	}
+	if cfg.RadioQueueSize < 1 { // If 0 or not set, default to 10
+		cfg.RadioQueueSize = 10
+	}
+	// Set default for the kept metadata fields
+	var metadataFields []string
+	for _, field := range cfg.MetadataFields {
+		if field = strings.TrimSpace(field); field != "" {
+			metadataFields = append(metadataFields, field)
+		}
+	}
+	cfg.MetadataFields = metadataFields
+	if len(cfg.MetadataFields) == 0 {
+		cfg.MetadataFields = append([]string(nil), DefaultMetadataFields...)
+	}
 
 	// Ensure all necessary directories exist
 	if err := os.MkdirAll(cfg.DownloadDir, 0755); err != nil {
@@ -125,6 +209,43 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// isAudioFormat reports whether format is one of AudioFormats.
+func isAudioFormat(format string) bool {
+	for _, f := range AudioFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultSearchCacheTTL is how long search results are cached unless search_cache_ttl is set.
+const defaultSearchCacheTTL = "1h"
+
+// SearchCacheMaxAge returns how long search results are cached, or 0 if caching is disabled.
+func (c *Config) SearchCacheMaxAge() time.Duration {
+	ttl, err := parseTTL(c.SearchCacheTTL)
+	if err != nil {
+		ttl, _ = parseTTL(defaultSearchCacheTTL)
+	}
+	return ttl
+}
+
+// parseTTL parses a duration such as "1h" or "30m". "0" disables caching.
+func parseTTL(s string) (time.Duration, error) {
+	if strings.TrimSpace(s) == "0" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return ttl, nil
+}
+
 // GetConfigPath returns the expected path for the config file.
 func GetConfigPath() (string, error) {
 	return xdg.ConfigFile(filepath.Join(appName, configFileName))
@@ -135,6 +256,26 @@ func GetStatePath() (string, error) {
 	return xdg.StateFile(filepath.Join(appName, stateFileName))
 }
 
+// GetQueuePath returns the expected path for the download queue file.
+func GetQueuePath() (string, error) {
+	return xdg.StateFile(filepath.Join(appName, queueFileName))
+}
+
+// GetSubscriptionsPath returns the expected path for the subscriptions file.
+func GetSubscriptionsPath() (string, error) {
+	return xdg.DataFile(filepath.Join(appName, subsFileName))
+}
+
+// GetSearchCacheDir returns the directory of the search result cache.
+func GetSearchCacheDir() string {
+	return filepath.Join(xdg.CacheHome, appName, "search")
+}
+
+// GetSearchHistoryPath returns the expected path for the search history file.
+func GetSearchHistoryPath() (string, error) {
+	return xdg.StateFile(filepath.Join(appName, historyFileName))
+}
+
 // GetDefaultConfigContent returns a string with default config.toml content.
 func GetDefaultConfigContent() string {
 	return `
@@ -172,5 +313,48 @@ cookie_browser = "chrome"
 
 # Maximum number of search results to retrieve from YouTube.
 max_search_results = 30
+
+# Number of tracks downloaded in parallel by the download queue.
+download_concurrency = 3
+
+# Audio format of downloaded tracks: "best", "mp3", "opus", "m4a", "aac", "flac", "vorbis", "wav" or "alac".
+# "best" keeps the original audio stream (usually opus or m4a) without re-encoding.
+# wav and aac files can't hold the embedded thumbnail.
+audio_format = "mp3"
+
+# Audio quality: 0 (best) to 10 (worst) for VBR, or a bitrate such as "192K".
+# Ignored when the original audio stream is kept.
+audio_quality = "0"
+
+# How long search results are cached, e.g. "30m" or "24h". "0" disables the cache.
+search_cache_ttl = "1h"
+
+# Whether 'ytpl radio' streams its tracks ("stream") or downloads them to the stock first ("download").
+radio_mode = "stream"
+
+# Number of upcoming tracks 'ytpl radio' keeps queued.
+radio_queue_size = 10
+
+# yt-dlp metadata fields kept in the .info.json file of each track; other fields are removed to save space.
+# "*" keeps every field. id, title, duration, webpage_url, extractor and original_url are always kept.
+# Run 'ytpl rebuild --refetch-metadata' to restore fields of tracks downloaded before adding them.
+metadata_fields = [
+  "id", "title", "uploader", "creator", "duration", "release_year", "upload_date",
+  "webpage_url", "extractor", "original_url", "thumbnail", "description",
+  "album", "artist", "track", "track_number", "genre",
+  "channel", "channel_id", "channel_url",
+  "chapters", "tags", "categories",
+]
+
+# Rules cleaning up the titles of new downloads and of 'ytpl tidy', applied after the built-in rules
+# that strip noise such as "(Official Music Video)", split "Artist - Title" and move "feat." credits.
+# Matches of pattern, a Go regular expression, are replaced with replace, which may refer to groups as $1.
+# A pattern with groups named artist or title splits the title into the artist and the title instead.
+# [[title_rules]]
+# pattern = '(?i)\s*\(remaster(ed)?( \d{4})?\)'
+# replace = ""
+#
+# [[title_rules]]
+# pattern = '^(?P<title>.+?) by (?P<artist>.+)$'
 `
 }
@@ -61,6 +61,7 @@ func StartPlayer(cfg *config.Config, s *state.PlayerState, filePath string) erro
 		"--force-window=no", // Do not force window display (for audio-only)
 		"--no-video",        // Explicitly disable video display
 	}
+	args = append(args, ytdlArgs(cfg)...)
 
 	cmd := exec.Command(cfg.PlayerPath, args...)
 
@@ -89,6 +90,28 @@ func StartPlayer(cfg *config.Config, s *state.PlayerState, filePath string) erro
 	return nil
 }
 
+// IsStream reports whether path is a URL streamed by mpv rather than a local file.
+func IsStream(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// ytdlArgs returns the mpv options that make its ytdl hook resolve streamed URLs
+// with the configured yt-dlp, fetching audio only.
+func ytdlArgs(cfg *config.Config) []string {
+	args := []string{"--ytdl-format=bestaudio/best"}
+	if cfg.YtDlpPath != "" {
+		args = append(args, fmt.Sprintf("--script-opts-append=ytdl_hook-ytdl_path=%s", cfg.YtDlpPath))
+	}
+	if cfg.CookieBrowser != "" {
+		browser := cfg.CookieBrowser
+		if cfg.CookieProfile != "" {
+			browser += ":" + cfg.CookieProfile
+		}
+		args = append(args, fmt.Sprintf("--ytdl-raw-options-append=cookies-from-browser=%s", browser))
+	}
+	return args
+}
+
 // SendCommand sends an ipc command to mpv.
 func SendCommand(s *state.PlayerState, command []interface{}) error {
 	if s.PID == 0 || s.IPCSocketPath == "" {
@@ -232,6 +255,53 @@ func LoadFile(s *state.PlayerState, filePath string) error {
 	return SendCommand(s, []interface{}{"loadfile", filePath, "replace"})
 }
 
+// InsertFile appends a file to mpv's current playlist and moves it to the given index.
+// Both commands are sent over the same connection so that the move always sees the appended entry.
+// playlistLen is the number of entries in the playlist before the file is appended.
+func InsertFile(s *state.PlayerState, filePath string, index, playlistLen int) error {
+	if s.PID == 0 || s.IPCSocketPath == "" {
+		return fmt.Errorf("player is not running or ipc socket path is unknown")
+	}
+
+	conn, err := net.DialTimeout("unix", s.IPCSocketPath, 1*time.Second)
+	if err != nil {
+		return fmt.Errorf("player not reachable, possibly stopped")
+	}
+	defer conn.Close()
+
+	commands := [][]interface{}{
+		{"loadfile", filePath, "append"},
+	}
+	// The appended entry ends up at index playlistLen; move it only if it belongs elsewhere
+	if index < playlistLen {
+		commands = append(commands, []interface{}{"playlist-move", playlistLen, index})
+	}
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+	for _, command := range commands {
+		if err := encoder.Encode(IPCCommand{Command: command}); err != nil {
+			return fmt.Errorf("failed to send command to mpv: %w", err)
+		}
+		// Wait for the reply before sending the next command, skipping any event messages
+		for {
+			var resp IPCResponse
+			if err := decoder.Decode(&resp); err != nil {
+				return fmt.Errorf("failed to decode response from mpv: %w", err)
+			}
+			if resp.Error == "" {
+				continue // Event message, not a command reply
+			}
+			if resp.Error != "success" {
+				return fmt.Errorf("mpv returned error for '%v': %s", command[0], strings.ToLower(resp.Error))
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
 // Next sends a 'playlist-next' command to mpv.
 func Next(s *state.PlayerState) error {
 	return SendCommand(s, []interface{}{"playlist-next"})
@@ -277,6 +347,7 @@ func LoadPlaylistIntoPlayer(cfg *config.Config, s *state.PlayerState, filePaths
 		"--force-window=no", // Do not force window display for audio playback
 		"--no-video",        // Explicitly disable video display
 	}
+	baseArgs = append(baseArgs, ytdlArgs(cfg)...)
 
 	// Add each file to the arguments for mpv to treat as a playlist
 	for _, p := range filePaths {
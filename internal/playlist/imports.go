@@ -0,0 +1,105 @@
+// internal/playlist/imports.go
+package playlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Imports records the tracks added to a playlist by 'ytpl list import', by YouTube playlist URL,
+// so that pruning an import never drops the tracks added by hand.
+type Imports struct {
+	Sources map[string][]string `json:"sources"` // YouTube playlist URL -> IDs of the tracks it added
+}
+
+// Added reports whether the import of url added the track.
+func (im *Imports) Added(url, trackID string) bool {
+	for _, id := range im.Sources[url] {
+		if id == trackID {
+			return true
+		}
+	}
+	return false
+}
+
+// Record records tracks added by the import of url.
+func (im *Imports) Record(url string, trackIDs ...string) {
+	if im.Sources == nil {
+		im.Sources = make(map[string][]string)
+	}
+	for _, id := range trackIDs {
+		if !im.Added(url, id) {
+			im.Sources[url] = append(im.Sources[url], id)
+		}
+	}
+}
+
+// Keep forgets the recorded tracks that are no longer in the playlist.
+func (im *Imports) Keep(p *Playlist) {
+	inPlaylist := make(map[string]bool, len(p.Tracks))
+	for _, track := range p.Tracks {
+		inPlaylist[track.ID] = true
+	}
+	for url, ids := range im.Sources {
+		kept := ids[:0]
+		for _, id := range ids {
+			if inPlaylist[id] {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			delete(im.Sources, url)
+		} else {
+			im.Sources[url] = kept
+		}
+	}
+}
+
+// getImportsFilePath returns the path of the import record of a playlist, next to its file.
+func getImportsFilePath(name string) string {
+	return strings.TrimSuffix(getPlaylistFilePath(name), ".ytpl") + ".imports.json"
+}
+
+// LoadImports reads the import record of a playlist.
+// A missing record means no tracks were imported.
+func LoadImports(name string) (*Imports, error) {
+	path := getImportsFilePath(name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Imports{}, nil
+		}
+		return nil, fmt.Errorf("failed to read import record %s: %w", path, err)
+	}
+
+	imports := &Imports{}
+	if err := json.Unmarshal(data, imports); err != nil {
+		return nil, fmt.Errorf("failed to parse import record %s: %w", path, err)
+	}
+	return imports, nil
+}
+
+// SaveImports writes the import record of a playlist atomically.
+func SaveImports(name string, imports *Imports) error {
+	path := getImportsFilePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create playlist directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(imports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal import record: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write import record: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace import record: %w", err)
+	}
+	return nil
+}
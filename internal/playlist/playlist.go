@@ -249,6 +249,11 @@ func DeletePlaylist(name string) error {
 			}
 		}
 	}
+	if err == nil {
+		if importsErr := os.Remove(getImportsFilePath(name)); importsErr != nil && !os.IsNotExist(importsErr) {
+			log.Printf("warning: failed to remove import record of playlist %s: %v", name, importsErr)
+		}
+	}
 	return err
 }
 
@@ -0,0 +1,436 @@
+// internal/queue/queue.go
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"ytpl/internal/yt"
+)
+
+// Status is the state of a queued download.
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // Waiting for a worker
+	StatusActive    Status = "active"    // Being downloaded by a worker
+	StatusDone      Status = "done"      // Downloaded successfully
+	StatusFailed    Status = "failed"    // Download failed, can be retried
+	StatusCancelled Status = "cancelled" // Cancelled by the user, can be retried
+)
+
+// doneRetention is how long finished downloads are kept in the queue file.
+const doneRetention = 24 * time.Hour
+
+// Item is a single track in the download queue.
+type Item struct {
+	TrackID   string       `json:"track_id"`
+	Title     string       `json:"title"`
+	Status    Status       `json:"status"`
+	Percent   float64      `json:"percent"` // 0-100, or -1 if unknown
+	Speed     float64      `json:"speed"`   // Bytes per second
+	ETA       int          `json:"eta"`     // Seconds, or -1 if unknown
+	Error     string       `json:"error,omitempty"`
+	ErrorKind yt.ErrorKind `json:"error_kind,omitempty"` // Cause of the failure, if known
+	Attempts  int          `json:"attempts"`
+	FilePath  string       `json:"file_path,omitempty"`
+	WorkerPID int          `json:"worker_pid,omitempty"` // Process downloading the item while active
+	AddedAt   time.Time    `json:"added_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Err returns the error of a failed item, keeping the cause recorded by the worker.
+func (i Item) Err() error {
+	if i.Status != StatusFailed {
+		return nil
+	}
+	return &yt.Error{Kind: i.ErrorKind, Message: i.Error}
+}
+
+// Finished reports whether the item is in a final state.
+func (i Item) Finished() bool {
+	return i.Status == StatusDone || i.Status == StatusFailed || i.Status == StatusCancelled
+}
+
+// queueFile is the on-disk representation of the queue.
+type queueFile struct {
+	WorkerPID int    `json:"worker_pid,omitempty"` // Background worker process, if any
+	Items     []Item `json:"items"`
+}
+
+// Queue is a download queue persisted on disk.
+// It can be shared between processes; every access locks the queue file.
+type Queue struct {
+	path     string
+	lockPath string
+}
+
+// Open returns the queue stored at path.
+// The file is created on the first modification.
+func Open(path string) *Queue {
+	return &Queue{
+		path:     path,
+		lockPath: path + ".lock",
+	}
+}
+
+// update loads the queue under an exclusive lock, applies fn and saves the result.
+// If fn returns an error, nothing is saved.
+func (q *Queue) update(fn func(f *queueFile) error) error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	lock, err := os.OpenFile(q.lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue lock file: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock queue: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	f, err := q.load()
+	if err != nil {
+		return err
+	}
+	recoverStale(f)
+
+	if err := fn(f); err != nil {
+		return err
+	}
+	return q.save(f)
+}
+
+// load reads the queue file.
+// Note: Caller must hold the lock
+func (q *Queue) load() (*queueFile, error) {
+	f := &queueFile{}
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("failed to read queue file %s: %w", q.path, err)
+	}
+	if len(data) == 0 {
+		return f, nil
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse queue file %s: %w", q.path, err)
+	}
+	return f, nil
+}
+
+// save writes the queue file atomically, dropping old finished downloads.
+// Note: Caller must hold the lock
+func (q *Queue) save(f *queueFile) error {
+	kept := f.Items[:0]
+	for _, item := range f.Items {
+		if item.Status == StatusDone && time.Since(item.UpdatedAt) > doneRetention {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	f.Items = kept
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue: %w", err)
+	}
+
+	tempPath := q.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+	if err := os.Rename(tempPath, q.path); err != nil {
+		return fmt.Errorf("failed to replace queue file: %w", err)
+	}
+	return nil
+}
+
+// recoverStale puts downloads whose worker process died back into the pending state,
+// so that they are resumed after a crash or restart.
+func recoverStale(f *queueFile) {
+	if f.WorkerPID != 0 && !processAlive(f.WorkerPID) {
+		f.WorkerPID = 0
+	}
+	for i := range f.Items {
+		item := &f.Items[i]
+		if item.Status == StatusActive && !processAlive(item.WorkerPID) {
+			item.Status = StatusPending
+			item.WorkerPID = 0
+			item.Percent = -1
+			item.Speed = 0
+			item.ETA = -1
+		}
+	}
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Unix, signal 0 can be used to check if a process exists
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// find returns the index of the item with the given track ID, or -1.
+func (f *queueFile) find(trackID string) int {
+	for i, item := range f.Items {
+		if item.TrackID == trackID {
+			return i
+		}
+	}
+	return -1
+}
+
+// List returns all items in the queue, oldest first.
+func (q *Queue) List() ([]Item, error) {
+	var items []Item
+	err := q.update(func(f *queueFile) error {
+		items = append(items, f.Items...)
+		return nil
+	})
+	return items, err
+}
+
+// Get returns the item with the given track ID.
+func (q *Queue) Get(trackID string) (Item, bool, error) {
+	var item Item
+	found := false
+	err := q.update(func(f *queueFile) error {
+		if i := f.find(trackID); i >= 0 {
+			item, found = f.Items[i], true
+		}
+		return nil
+	})
+	return item, found, err
+}
+
+// Add queues a track for download.
+// A track that is already pending or active is left as is; a finished one is queued again.
+// It returns false if the track was already waiting in the queue.
+func (q *Queue) Add(trackID, title string) (bool, error) {
+	added := false
+	err := q.update(func(f *queueFile) error {
+		now := time.Now()
+		if i := f.find(trackID); i >= 0 {
+			item := &f.Items[i]
+			if !item.Finished() {
+				return nil
+			}
+			if title != "" {
+				item.Title = title
+			}
+			item.Status = StatusPending
+			item.Error = ""
+			item.ErrorKind = yt.KindUnknown
+			item.Attempts = 0
+			item.Percent = -1
+			item.Speed = 0
+			item.ETA = -1
+			item.AddedAt = now
+			item.UpdatedAt = now
+			added = true
+			return nil
+		}
+
+		f.Items = append(f.Items, Item{
+			TrackID:   trackID,
+			Title:     title,
+			Status:    StatusPending,
+			Percent:   -1,
+			ETA:       -1,
+			AddedAt:   now,
+			UpdatedAt: now,
+		})
+		added = true
+		return nil
+	})
+	return added, err
+}
+
+// Claim marks the oldest pending item accepted by filter as active for the current process
+// and returns it. It returns nil if there is no such item. A nil filter accepts every item.
+func (q *Queue) Claim(filter func(Item) bool) (*Item, error) {
+	var claimed *Item
+	err := q.update(func(f *queueFile) error {
+		for i := range f.Items {
+			item := &f.Items[i]
+			if item.Status != StatusPending || (filter != nil && !filter(*item)) {
+				continue
+			}
+			item.Status = StatusActive
+			item.WorkerPID = os.Getpid()
+			item.Attempts++
+			item.UpdatedAt = time.Now()
+			copied := *item
+			claimed = &copied
+			return nil
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// SetProgress records the download progress of an active item.
+func (q *Queue) SetProgress(trackID string, percent, speed float64, eta int) error {
+	return q.update(func(f *queueFile) error {
+		if i := f.find(trackID); i >= 0 && f.Items[i].Status == StatusActive {
+			f.Items[i].Percent = percent
+			f.Items[i].Speed = speed
+			f.Items[i].ETA = eta
+			f.Items[i].UpdatedAt = time.Now()
+		}
+		return nil
+	})
+}
+
+// Finish records the result of a download.
+// A download cancelled while running stays cancelled regardless of downloadErr.
+func (q *Queue) Finish(trackID, title, filePath string, downloadErr error) error {
+	return q.update(func(f *queueFile) error {
+		i := f.find(trackID)
+		if i < 0 {
+			return nil
+		}
+		item := &f.Items[i]
+		item.WorkerPID = 0
+		item.Speed = 0
+		item.ETA = -1
+		item.UpdatedAt = time.Now()
+		if item.Status == StatusCancelled {
+			return nil
+		}
+		if title != "" {
+			item.Title = title
+		}
+		if downloadErr != nil {
+			item.Status = StatusFailed
+			item.Error = downloadErr.Error()
+			item.ErrorKind = yt.ErrorKindOf(downloadErr)
+			return nil
+		}
+		item.Status = StatusDone
+		item.Error = ""
+		item.ErrorKind = yt.KindUnknown
+		item.Percent = 100
+		item.FilePath = filePath
+		return nil
+	})
+}
+
+// Retry queues failed and cancelled items again.
+// If no track IDs are given, all failed items are retried.
+// It returns the number of items queued again.
+func (q *Queue) Retry(trackIDs ...string) (int, error) {
+	count := 0
+	err := q.update(func(f *queueFile) error {
+		for i := range f.Items {
+			item := &f.Items[i]
+			if len(trackIDs) == 0 && item.Status != StatusFailed {
+				continue
+			}
+			if len(trackIDs) > 0 && (!contains(trackIDs, item.TrackID) || (item.Status != StatusFailed && item.Status != StatusCancelled)) {
+				continue
+			}
+			item.Status = StatusPending
+			item.Error = ""
+			item.ErrorKind = yt.KindUnknown
+			item.Attempts = 0
+			item.Percent = -1
+			item.Speed = 0
+			item.ETA = -1
+			item.UpdatedAt = time.Now()
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Cancel cancels pending and active items.
+// If no track IDs are given, every unfinished item is cancelled.
+// Workers notice the cancellation of an active item and stop its download.
+// It returns the number of cancelled items.
+func (q *Queue) Cancel(trackIDs ...string) (int, error) {
+	count := 0
+	err := q.update(func(f *queueFile) error {
+		for i := range f.Items {
+			item := &f.Items[i]
+			if item.Finished() || (len(trackIDs) > 0 && !contains(trackIDs, item.TrackID)) {
+				continue
+			}
+			item.Status = StatusCancelled
+			item.UpdatedAt = time.Now()
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// RegisterWorker records pid as the background worker.
+// It returns false if another live background worker is already registered.
+func (q *Queue) RegisterWorker(pid int) (bool, error) {
+	registered := false
+	err := q.update(func(f *queueFile) error {
+		if f.WorkerPID != 0 && f.WorkerPID != pid {
+			return nil
+		}
+		f.WorkerPID = pid
+		registered = true
+		return nil
+	})
+	return registered, err
+}
+
+// UnregisterWorker removes pid as the background worker, unless pending items are left.
+// It returns false if the worker should keep running because new items were queued.
+func (q *Queue) UnregisterWorker(pid int) (bool, error) {
+	unregistered := false
+	err := q.update(func(f *queueFile) error {
+		for _, item := range f.Items {
+			if item.Status == StatusPending {
+				return nil
+			}
+		}
+		if f.WorkerPID == pid {
+			f.WorkerPID = 0
+		}
+		unregistered = true
+		return nil
+	})
+	return unregistered, err
+}
+
+// WorkerRunning reports whether a live background worker is registered.
+func (q *Queue) WorkerRunning() (bool, error) {
+	running := false
+	err := q.update(func(f *queueFile) error {
+		running = f.WorkerPID != 0
+		return nil
+	})
+	return running, err
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/yt"
+)
+
+func testQueue(t *testing.T) *Queue {
+	return Open(filepath.Join(t.TempDir(), "queue.json"))
+}
+
+// deadPID returns the pid of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	return cmd.Process.Pid
+}
+
+// getItem returns the item of a track, failing the test if it isn't queued.
+func getItem(t *testing.T, q *Queue, trackID string) Item {
+	t.Helper()
+	item, found, err := q.Get(trackID)
+	require.NoError(t, err)
+	require.True(t, found, "%s must be queued", trackID)
+	return item
+}
+
+func TestQueueLifecycle(t *testing.T) {
+	q := testQueue(t)
+
+	added, err := q.Add("aaaaaaaaaaa", "First")
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = q.Add("aaaaaaaaaaa", "First again")
+	require.NoError(t, err)
+	assert.False(t, added, "a pending track is not queued twice")
+	_, err = q.Add("bbbbbbbbbbb", "Second")
+	require.NoError(t, err)
+
+	items, err := q.List()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "First", items[0].Title)
+	assert.Equal(t, StatusPending, items[0].Status)
+	assert.Equal(t, -1.0, items[0].Percent)
+	assert.Equal(t, -1, items[0].ETA)
+
+	t.Run("claims the oldest pending item accepted by the filter", func(t *testing.T) {
+		item, err := q.Claim(func(item Item) bool { return item.TrackID == "bbbbbbbbbbb" })
+		require.NoError(t, err)
+		require.NotNil(t, item)
+		assert.Equal(t, "bbbbbbbbbbb", item.TrackID)
+
+		item, err = q.Claim(nil)
+		require.NoError(t, err)
+		require.NotNil(t, item)
+		assert.Equal(t, "aaaaaaaaaaa", item.TrackID)
+		assert.Equal(t, StatusActive, item.Status)
+		assert.Equal(t, os.Getpid(), item.WorkerPID)
+		assert.Equal(t, 1, item.Attempts)
+
+		item, err = q.Claim(nil)
+		require.NoError(t, err)
+		assert.Nil(t, item, "no pending item is left")
+
+		added, err := q.Add("aaaaaaaaaaa", "")
+		require.NoError(t, err)
+		assert.False(t, added, "an active track is not queued again")
+	})
+
+	t.Run("finishes downloads", func(t *testing.T) {
+		require.NoError(t, q.SetProgress("aaaaaaaaaaa", 50, 1024, 30))
+		require.NoError(t, q.Finish("aaaaaaaaaaa", "First Song", "/music/aaaaaaaaaaa.mp3", nil))
+		item := getItem(t, q, "aaaaaaaaaaa")
+		assert.Equal(t, StatusDone, item.Status)
+		assert.Equal(t, "First Song", item.Title)
+		assert.Equal(t, "/music/aaaaaaaaaaa.mp3", item.FilePath)
+		assert.Equal(t, 100.0, item.Percent)
+		assert.Equal(t, -1, item.ETA)
+		assert.Zero(t, item.WorkerPID)
+		assert.NoError(t, item.Err())
+
+		require.NoError(t, q.SetProgress("bbbbbbbbbbb", 40, 1024, 20))
+		require.NoError(t, q.Finish("bbbbbbbbbbb", "", "", &yt.Error{Kind: yt.KindNetwork, Message: "connection reset"}))
+		item = getItem(t, q, "bbbbbbbbbbb")
+		assert.Equal(t, StatusFailed, item.Status)
+		assert.Equal(t, "connection reset", item.Error)
+		assert.Equal(t, yt.KindNetwork, yt.ErrorKindOf(item.Err()))
+	})
+
+	t.Run("retries failed items from scratch", func(t *testing.T) {
+		count, err := q.Retry()
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "only failed items are retried")
+		item := getItem(t, q, "bbbbbbbbbbb")
+		assert.Equal(t, StatusPending, item.Status)
+		assert.Empty(t, item.Error)
+		assert.Equal(t, yt.KindUnknown, item.ErrorKind)
+		assert.Zero(t, item.Attempts)
+		assert.Equal(t, -1.0, item.Percent)
+		assert.Zero(t, item.Speed)
+		assert.Equal(t, -1, item.ETA)
+	})
+
+	t.Run("cancels unfinished items and retries them by ID", func(t *testing.T) {
+		count, err := q.Cancel()
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "done items are not cancelled")
+		assert.Equal(t, StatusCancelled, getItem(t, q, "bbbbbbbbbbb").Status)
+
+		_, err = q.Claim(nil)
+		require.NoError(t, err)
+		require.NoError(t, q.Finish("bbbbbbbbbbb", "", "", nil))
+		assert.Equal(t, StatusCancelled, getItem(t, q, "bbbbbbbbbbb").Status, "a cancelled download stays cancelled")
+
+		count, err = q.Retry("bbbbbbbbbbb", "aaaaaaaaaaa")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "done items are not retried")
+		assert.Equal(t, StatusPending, getItem(t, q, "bbbbbbbbbbb").Status)
+	})
+
+	t.Run("queues finished items again", func(t *testing.T) {
+		_, err := q.Claim(nil)
+		require.NoError(t, err)
+		require.NoError(t, q.Finish("bbbbbbbbbbb", "", "", &yt.Error{Kind: yt.KindUnavailable, Message: "Video unavailable"}))
+
+		added, err := q.Add("bbbbbbbbbbb", "Second Song")
+		require.NoError(t, err)
+		assert.True(t, added)
+		item := getItem(t, q, "bbbbbbbbbbb")
+		assert.Equal(t, StatusPending, item.Status)
+		assert.Equal(t, "Second Song", item.Title)
+		assert.Empty(t, item.Error)
+		assert.Equal(t, yt.KindUnknown, item.ErrorKind)
+		assert.Zero(t, item.Attempts)
+		assert.Equal(t, -1, item.ETA)
+	})
+}
+
+func TestQueueRecovery(t *testing.T) {
+	q := testQueue(t)
+	dead := deadPID(t)
+	now := time.Now()
+	require.NoError(t, q.update(func(f *queueFile) error {
+		f.WorkerPID = dead
+		f.Items = []Item{
+			{TrackID: "aaaaaaaaaaa", Status: StatusActive, WorkerPID: dead, Percent: 40, Speed: 1024, ETA: 10, UpdatedAt: now},
+			{TrackID: "bbbbbbbbbbb", Status: StatusActive, WorkerPID: os.Getpid(), Percent: 60, ETA: 5, UpdatedAt: now},
+			{TrackID: "ccccccccccc", Status: StatusDone, UpdatedAt: now.Add(-doneRetention - time.Hour)},
+			{TrackID: "ddddddddddd", Status: StatusDone, UpdatedAt: now.Add(-time.Hour)},
+			{TrackID: "eeeeeeeeeee", Status: StatusFailed, UpdatedAt: now.Add(-doneRetention - time.Hour)},
+		}
+		return nil
+	}))
+
+	items, err := q.List()
+	require.NoError(t, err)
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.TrackID)
+	}
+	assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ddddddddddd", "eeeeeeeeeee"}, ids,
+		"only done items are dropped once expired")
+
+	stale := items[0]
+	assert.Equal(t, StatusPending, stale.Status, "items of a dead worker are pending again")
+	assert.Zero(t, stale.WorkerPID)
+	assert.Equal(t, -1.0, stale.Percent)
+	assert.Zero(t, stale.Speed)
+	assert.Equal(t, -1, stale.ETA)
+	assert.Equal(t, StatusActive, items[1].Status, "items of a live worker are left as they are")
+
+	running, err := q.WorkerRunning()
+	require.NoError(t, err)
+	assert.False(t, running, "a dead worker is unregistered")
+}
+
+func TestQueueWorkerRegistration(t *testing.T) {
+	q := testQueue(t)
+	pid := os.Getpid()
+
+	registered, err := q.RegisterWorker(pid)
+	require.NoError(t, err)
+	assert.True(t, registered)
+	registered, err = q.RegisterWorker(pid)
+	require.NoError(t, err)
+	assert.True(t, registered, "the registered worker may register again")
+	registered, err = q.RegisterWorker(os.Getppid())
+	require.NoError(t, err)
+	assert.False(t, registered, "only one live worker is registered")
+
+	_, err = q.Add("aaaaaaaaaaa", "First")
+	require.NoError(t, err)
+	done, err := q.UnregisterWorker(pid)
+	require.NoError(t, err)
+	assert.False(t, done, "the worker keeps running while items are pending")
+	running, err := q.WorkerRunning()
+	require.NoError(t, err)
+	assert.True(t, running)
+
+	_, err = q.Cancel()
+	require.NoError(t, err)
+	done, err = q.UnregisterWorker(pid)
+	require.NoError(t, err)
+	assert.True(t, done)
+	running, err = q.WorkerRunning()
+	require.NoError(t, err)
+	assert.False(t, running)
+}
+
+func TestPoolRun(t *testing.T) {
+	q := testQueue(t)
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "ddddddddddd"} {
+		_, err := q.Add(id, "")
+		require.NoError(t, err)
+	}
+
+	var updates []Item
+	pool := &Pool{
+		Queue:   q,
+		Workers: 3,
+		// Progress is reported from two goroutines, like the stdout and stderr of yt-dlp
+		Download: func(ctx context.Context, item Item, onProgress func(yt.DownloadProgress)) (string, string, error) {
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for percent := range 50 {
+						onProgress(yt.DownloadProgress{Percent: float64(percent * 2), Speed: 1024, ETA: 50 - percent})
+					}
+				}()
+			}
+			wg.Wait()
+			if item.TrackID == "ddddddddddd" {
+				return "", "", &yt.Error{Kind: yt.KindUnavailable, Message: "Video unavailable"}
+			}
+			return "/music/" + item.TrackID + ".mp3", "Title of " + item.TrackID, nil
+		},
+		OnUpdate: func(item Item) { updates = append(updates, item) },
+	}
+	require.NoError(t, pool.Run(context.Background()))
+
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"} {
+		item := getItem(t, q, id)
+		assert.Equal(t, StatusDone, item.Status)
+		assert.Equal(t, "Title of "+id, item.Title)
+		assert.Equal(t, "/music/"+id+".mp3", item.FilePath)
+	}
+	failed := getItem(t, q, "ddddddddddd")
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, yt.KindUnavailable, failed.ErrorKind)
+
+	final := make(map[string]Status)
+	for _, item := range updates {
+		final[item.TrackID] = item.Status
+	}
+	assert.Equal(t, map[string]Status{
+		"aaaaaaaaaaa": StatusDone, "bbbbbbbbbbb": StatusDone, "ccccccccccc": StatusDone, "ddddddddddd": StatusFailed,
+	}, final)
+
+	t.Run("waits for the given tracks", func(t *testing.T) {
+		_, err := q.Add("eeeeeeeeeee", "")
+		require.NoError(t, err)
+		_, err = q.Add("fffffffffff", "")
+		require.NoError(t, err)
+		require.NoError(t, pool.Run(context.Background(), "eeeeeeeeeee"))
+		assert.Equal(t, StatusDone, getItem(t, q, "eeeeeeeeeee").Status)
+		assert.Equal(t, StatusPending, getItem(t, q, "fffffffffff").Status, "other tracks are left pending")
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.True(t, errors.Is(pool.Run(ctx), context.Canceled))
+		assert.Equal(t, StatusPending, getItem(t, q, "fffffffffff").Status)
+	})
+}
@@ -0,0 +1,217 @@
+// internal/queue/worker.go
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ytpl/internal/yt"
+)
+
+const (
+	// pollInterval is how often the queue file is checked while waiting for other processes.
+	pollInterval = 500 * time.Millisecond
+	// progressSaveInterval limits how often the progress of a download is written to the queue file.
+	progressSaveInterval = time.Second
+)
+
+// DownloadFunc downloads a queued track.
+// It returns the path of the downloaded audio file and the track title.
+// onProgress may be called concurrently, but not after DownloadFunc returns.
+type DownloadFunc func(ctx context.Context, item Item, onProgress func(yt.DownloadProgress)) (string, string, error)
+
+// Pool downloads queued tracks with a fixed number of parallel workers.
+type Pool struct {
+	Queue    *Queue
+	Workers  int
+	Download DownloadFunc
+	// OnUpdate is called whenever the progress or the state of an item changes.
+	// It is called once per item in a final state, and never concurrently.
+	OnUpdate func(item Item)
+
+	mu       sync.Mutex
+	reported map[string]Item // Last state passed to OnUpdate per track
+}
+
+// Run downloads pending items.
+// If no track IDs are given, it processes every pending item and returns once none are left.
+// Otherwise it only processes the given tracks and returns once all of them are finished,
+// waiting for those that are being downloaded by another ytpl process.
+func (p *Pool) Run(ctx context.Context, trackIDs ...string) error {
+	var filter func(Item) bool
+	if len(trackIDs) > 0 {
+		filter = func(item Item) bool { return contains(trackIDs, item.TrackID) }
+	}
+
+	for {
+		if err := p.work(ctx, filter); err != nil {
+			return err
+		}
+		if len(trackIDs) == 0 {
+			return nil
+		}
+
+		finished, err := p.watch(ctx, trackIDs)
+		if err != nil || finished {
+			return err
+		}
+		// Some tracks became pending again (e.g. their worker died), so claim them
+	}
+}
+
+// work runs the workers until no pending item accepted by filter is left.
+func (p *Pool) work(ctx context.Context, filter func(Item) bool) error {
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				item, err := p.Queue.Claim(filter)
+				if err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+					return
+				}
+				if item == nil {
+					return
+				}
+				p.process(ctx, *item)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// process downloads a claimed item and records the result.
+func (p *Pool) process(ctx context.Context, item Item) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stop the download if the item is cancelled from another process
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current, found, err := p.Queue.Get(item.TrackID)
+				if err == nil && (!found || current.Status == StatusCancelled) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	p.notify(item)
+	var (
+		progressMu sync.Mutex // The download may report progress from several goroutines
+		lastSaved  time.Time
+	)
+	filePath, title, err := p.Download(ctx, item, func(progress yt.DownloadProgress) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		item.Percent = progress.Percent
+		item.Speed = progress.Speed
+		item.ETA = progress.ETA
+		p.notify(item)
+		if time.Since(lastSaved) >= progressSaveInterval {
+			lastSaved = time.Now()
+			_ = p.Queue.SetProgress(item.TrackID, item.Percent, item.Speed, item.ETA)
+		}
+	})
+
+	if finishErr := p.Queue.Finish(item.TrackID, title, filePath, err); finishErr != nil {
+		item.Status = StatusFailed
+		item.Error = finishErr.Error()
+		p.notify(item)
+		return
+	}
+	if final, found, getErr := p.Queue.Get(item.TrackID); getErr == nil && found {
+		p.notify(final)
+	}
+}
+
+// watch polls the queue until all given tracks are finished, reporting changes made by
+// other processes. It returns false if one of the tracks became pending again.
+func (p *Pool) watch(ctx context.Context, trackIDs []string) (bool, error) {
+	for {
+		items, err := p.Queue.List()
+		if err != nil {
+			return false, err
+		}
+
+		finished := true
+		for _, id := range trackIDs {
+			var item *Item
+			for i := range items {
+				if items[i].TrackID == id {
+					item = &items[i]
+					break
+				}
+			}
+			if item == nil {
+				continue // Not queued, nothing to wait for
+			}
+			p.notify(*item)
+			switch {
+			case item.Status == StatusPending:
+				return false, nil
+			case !item.Finished():
+				finished = false
+			}
+		}
+		if finished {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// notify passes an item to OnUpdate if it changed since the last call.
+func (p *Pool) notify(item Item) {
+	if p.OnUpdate == nil {
+		return
+	}
+
+	// Calls are serialized so that OnUpdate doesn't need to be safe for concurrent use
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reported == nil {
+		p.reported = make(map[string]Item)
+	}
+	last, seen := p.reported[item.TrackID]
+	if seen && last.Status == item.Status && last.Percent == item.Percent && last.Speed == item.Speed {
+		return
+	}
+	p.reported[item.TrackID] = item
+
+	p.OnUpdate(item)
+}
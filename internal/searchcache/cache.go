@@ -0,0 +1,186 @@
+// internal/searchcache/cache.go
+package searchcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ytpl/internal/yt"
+)
+
+// Cache stores parsed search results on disk, one file per search, for a limited time.
+type Cache struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// entry is the content of a cache file.
+type entry struct {
+	Query     string         `json:"query"`
+	FetchedAt time.Time      `json:"fetched_at"`
+	Tracks    []yt.TrackInfo `json:"tracks"`
+}
+
+// Stats describes the content of the cache.
+type Stats struct {
+	Entries int       // Cached searches
+	Expired int       // Cached searches older than the TTL
+	Bytes   int64     // Size of the cache files
+	Oldest  time.Time // Time the oldest search was fetched
+	Newest  time.Time // Time the newest search was fetched
+}
+
+// New returns a cache storing results in dir for maxAge. A maxAge of 0 disables the cache.
+func New(dir string, maxAge time.Duration) *Cache {
+	return &Cache{dir: dir, maxAge: maxAge}
+}
+
+// Key returns the cache key of a search. Searches with the same query, number of results,
+// filters, sort order and page share a key.
+func Key(query string, count int, opts yt.SearchOptions) string {
+	exclude := ""
+	if opts.Exclude != nil {
+		exclude = opts.Exclude.String()
+	}
+	parts := []string{
+		strings.TrimSpace(query),
+		fmt.Sprint(count),
+		fmt.Sprint(opts.MinDuration),
+		fmt.Sprint(opts.MaxDuration),
+		strings.ToLower(opts.Channel),
+		exclude,
+		opts.After,
+		opts.Sort,
+		fmt.Sprint(opts.Page),
+		opts.Music,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Get returns the cached results for key, if there are results younger than the TTL.
+func (c *Cache) Get(key string) ([]yt.TrackInfo, bool) {
+	if c.maxAge == 0 {
+		return nil, false
+	}
+	e, err := c.read(c.path(key))
+	if err != nil || time.Since(e.FetchedAt) > c.maxAge {
+		return nil, false
+	}
+	return e.Tracks, true
+}
+
+// Put stores the results of a search under key, and removes expired entries.
+func (c *Cache) Put(key, query string, tracks []yt.TrackInfo) error {
+	if c.maxAge == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create search cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry{Query: query, FetchedAt: time.Now(), Tracks: tracks})
+	if err != nil {
+		return fmt.Errorf("failed to marshal search results: %w", err)
+	}
+	path := c.path(key)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write search cache: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace search cache: %w", err)
+	}
+
+	c.removeExpired()
+	return nil
+}
+
+// Clear removes all cached searches and returns how many there were.
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+	return len(files), nil
+}
+
+// Stats returns the number, size and age of the cached searches.
+func (c *Cache) Stats() (Stats, error) {
+	var stats Stats
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		e, err := c.read(file)
+		if err != nil {
+			continue
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if c.maxAge == 0 || time.Since(e.FetchedAt) > c.maxAge {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || e.FetchedAt.Before(stats.Oldest) {
+			stats.Oldest = e.FetchedAt
+		}
+		if e.FetchedAt.After(stats.Newest) {
+			stats.Newest = e.FetchedAt
+		}
+	}
+	return stats, nil
+}
+
+// removeExpired removes the cached searches older than the TTL. Errors are ignored,
+// as leftover files are only a matter of disk space.
+func (c *Cache) removeExpired() {
+	files, err := c.files()
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if e, err := c.read(file); err != nil || time.Since(e.FetchedAt) > c.maxAge {
+			os.Remove(file)
+		}
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *Cache) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search cache: %w", err)
+	}
+	return files, nil
+}
+
+func (c *Cache) read(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,80 @@
+// internal/searchcache/history.go
+package searchcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxHistory is the number of queries kept in the search history.
+const maxHistory = 100
+
+// HistoryEntry is a query in the search history.
+type HistoryEntry struct {
+	Query    string    `json:"query"`
+	Searched time.Time `json:"searched"`
+}
+
+// LoadHistory returns the search history stored at path, most recent query first.
+// A missing file means an empty history.
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read search history %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var history []HistoryEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse search history %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// AddToHistory records a query as the most recent one in the history at path.
+// An earlier entry of the same query is moved to the top.
+func AddToHistory(path, query string) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	history, err := LoadHistory(path)
+	if err != nil {
+		return err
+	}
+
+	updated := []HistoryEntry{{Query: query, Searched: time.Now()}}
+	for _, e := range history {
+		if e.Query != query {
+			updated = append(updated, e)
+		}
+	}
+	if len(updated) > maxHistory {
+		updated = updated[:maxHistory]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create search history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal search history: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write search history: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace search history: %w", err)
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ type PlayerState struct {
 	LastPlayedTrackIndex int     `json:"last_played_track_index"` // For playlist continuation
 	PlaybackHistory      []string `json:"playback_history"`        // For 'shuffle' or 'next' tracking
 	ShuffleQueue         []string `json:"shuffle_queue"`           // For shuffle mode
+	SkippedTracks        []string `json:"skipped_tracks,omitempty"` // Tracks skipped with 'next' while the player runs, left out of the radio
 	mu                   sync.Mutex // Mutex for concurrent access
 }
 
@@ -0,0 +1,109 @@
+// internal/subscription/subscription.go
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Subscription is a YouTube channel or playlist whose new uploads are downloaded by 'ytpl sync'.
+type Subscription struct {
+	URL         string    `json:"url"`
+	Name        string    `json:"name,omitempty"`         // Channel name, for display
+	Playlist    string    `json:"playlist,omitempty"`     // Playlist new tracks are added to, if any
+	Filter      string    `json:"filter,omitempty"`       // Regular expression titles must match
+	MaxDuration float64   `json:"max_duration,omitempty"` // In seconds, 0 for no limit
+	LastSync    time.Time `json:"last_sync"`
+	// Seen holds the videos already handled, so that only new uploads are downloaded.
+	Seen []string `json:"seen"`
+}
+
+// Matches reports whether a video passes the subscription's filters.
+// Videos with an unknown duration pass the duration limit.
+func (s *Subscription) Matches(title string, duration float64) bool {
+	if s.MaxDuration > 0 && duration > s.MaxDuration {
+		return false
+	}
+	if s.Filter != "" {
+		re, err := regexp.Compile(s.Filter)
+		if err != nil || !re.MatchString(title) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasSeen reports whether the video was already handled.
+func (s *Subscription) HasSeen(trackID string) bool {
+	for _, id := range s.Seen {
+		if id == trackID {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkSeen records videos as handled.
+func (s *Subscription) MarkSeen(trackIDs ...string) {
+	for _, id := range trackIDs {
+		if !s.HasSeen(id) {
+			s.Seen = append(s.Seen, id)
+		}
+	}
+}
+
+// Load reads the subscriptions stored at path.
+// A missing file means there are no subscriptions.
+func Load(path string) ([]*Subscription, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read subscriptions file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var subs []*Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse subscriptions file %s: %w", path, err)
+	}
+	return subs, nil
+}
+
+// Save writes the subscriptions to path atomically.
+func Save(path string, subs []*Subscription) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create subscriptions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscriptions file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace subscriptions file: %w", err)
+	}
+	return nil
+}
+
+// Find returns the index of the subscription to url, or -1.
+func Find(subs []*Subscription, url string) int {
+	for i, sub := range subs {
+		if sub.URL == url {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,208 @@
+// internal/tidy/tidy.go
+
+// Package tidy cleans up track titles: it strips noise such as "(Official Music Video)",
+// splits "Artist - Title" and "Title / Artist" into an artist and a title, and moves
+// "feat." credits from the title to the artist.
+package tidy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	config "ytpl/internal/config" // Alias for internal/config
+	"ytpl/internal/yt"
+)
+
+// Rule replaces the matches of Pattern in a title with Replace, which may refer to groups as $1.
+// A pattern with a group named "artist" or "title" splits the title instead: when it matches,
+// the groups become the artist and the title of the track.
+type Rule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// splits reports whether the rule splits titles into an artist and a title.
+func (r Rule) splits() bool {
+	return r.Pattern.SubexpIndex("artist") >= 0 || r.Pattern.SubexpIndex("title") >= 0
+}
+
+// noiseWords are the words of a bracketed part of a title that says nothing about the song,
+// as in "(Official Music Video)", "[MV]" or "【公式】".
+const noiseWords = `official|公式|music|video|mv|m/v|pv|audio|lyrics?|visuali[sz]er|hd|hq|4k|full|ver\.?|version|color\s+coded|歌詞付き?|字幕付き?`
+
+// BuiltinRules are the rules applied before the title_rules of the config.
+var BuiltinRules = []Rule{
+	// Bracketed noise: "(Official Music Video)", "[MV]", "【公式】", "(Lyrics)"
+	{Pattern: regexp.MustCompile(`(?i)\s*[(\[【（]\s*(?:(?:` + noiseWords + `)[\s/&.,_-]*)+[)\]】）]`)},
+	// Noise after a bar: "Title | Official Video"
+	{Pattern: regexp.MustCompile(`(?i)\s*[|｜][^|｜]*(?:official|公式|video|mv|audio|lyrics?)[^|｜]*$`)},
+	// Trailing noise without brackets: "Title Official Music Video", "Title MV"
+	{Pattern: regexp.MustCompile(`(?i)\s+(?:official\s+)?(?:music\s+video|lyric\s+video|official\s+video|official\s+audio|mv|pv)$`)},
+	// Trailing hashtags: "Title #shorts #music", but not numbers as in "Symphony #5"
+	{Pattern: regexp.MustCompile(`(?:\s+#\p{L}[^\s#]*)+\s*$`)},
+	// Brackets left empty by other rules
+	{Pattern: regexp.MustCompile(`\s*[(\[【（]\s*[)\]】）]`)},
+}
+
+var (
+	// artistFirst matches "Artist - Title".
+	artistFirst = regexp.MustCompile(`^(.+?)\s+[-–—~]\s+(.+)$`)
+	// titleFirst matches "Title / Artist" and "Title／Artist".
+	titleFirst = regexp.MustCompile(`^(.+?)(?:\s+/\s+|\s*／\s*)(.+)$`)
+	// bracketedFeat matches a bracketed credit such as "(feat. Artist)" anywhere in a title.
+	bracketedFeat = regexp.MustCompile(`(?i)\s*[(\[（]\s*(?:feat\.?|ft\.?|featuring)\s+([^)\]）]+?)\s*[)\]）]`)
+	// trailingFeat matches a credit such as " feat. Artist" at the end of a title.
+	trailingFeat = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.|featuring)\s+(.+)$`)
+)
+
+// Cleaner cleans up titles with the built-in rules followed by user rules.
+type Cleaner struct {
+	rules []Rule
+}
+
+// New returns a Cleaner applying the built-in rules and then the given user rules.
+// Rules that fail to compile are left out and reported in the error; the Cleaner is
+// usable nonetheless.
+func New(userRules []config.TitleRule) (*Cleaner, error) {
+	c := &Cleaner{rules: append([]Rule(nil), BuiltinRules...)}
+	var errs []error
+	for i, rule := range userRules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("title rule %d: %w", i+1, err))
+			continue
+		}
+		c.rules = append(c.rules, Rule{Pattern: pattern, Replace: rule.Replace})
+	}
+	return c, errors.Join(errs...)
+}
+
+// Apply cleans up the title and artist of a track and reports whether they changed.
+func (c *Cleaner) Apply(track *yt.TrackInfo) bool {
+	title, artist := c.Clean(track.Title, track.Artist, track.Uploader)
+	changed := title != track.Title || artist != track.Artist
+	track.Title, track.Artist = title, artist
+	return changed
+}
+
+// Clean returns the cleaned-up title and artist of a track. artist is the artist already known,
+// if any, and uploader the channel of the track. A title is only split when one of its parts
+// matches the known artist, or else the artist in the channel name; titles of unknown artists
+// are only split by user rules.
+func (c *Cleaner) Clean(title, artist, uploader string) (string, string) {
+	cleaned := title
+	splitArtist := ""
+	for _, rule := range c.rules {
+		if !rule.splits() {
+			cleaned = tidySpaces(rule.Pattern.ReplaceAllString(cleaned, rule.Replace))
+			continue
+		}
+		match := rule.Pattern.FindStringSubmatch(cleaned)
+		if match == nil {
+			continue
+		}
+		if i := rule.Pattern.SubexpIndex("artist"); i >= 0 && match[i] != "" {
+			splitArtist = tidySpaces(match[i])
+		}
+		if i := rule.Pattern.SubexpIndex("title"); i >= 0 && match[i] != "" {
+			cleaned = tidySpaces(match[i])
+		}
+	}
+	if cleaned == "" {
+		return title, artist
+	}
+
+	if splitArtist == "" {
+		splitArtist, cleaned = split(cleaned, artist, uploader)
+	}
+	if artist == "" {
+		artist = splitArtist
+	}
+
+	// Move "feat." credits to the artist, as long as there is an artist to credit them to
+	withoutFeat, featured := extractFeat(cleaned)
+	if featured != "" && withoutFeat != "" {
+		main := artist
+		if main == "" {
+			main = uploaderArtist(uploader)
+		}
+		if main != "" {
+			cleaned = withoutFeat
+			artist = main
+			if !strings.Contains(normalize(main), normalize(featured)) {
+				artist = main + " feat. " + featured
+			}
+		}
+	}
+	return cleaned, artist
+}
+
+// split splits "Artist - Title" and "Title / Artist" into the artist and the title, when one of
+// the parts matches the known artist or else the artist in the channel name; the parts are
+// swapped if the other one matches. A title neither part of which matches isn't split,
+// e.g. "Yesterday - Remastered 2009" or "Title - Live at Budokan".
+func split(title, artist, uploader string) (string, string) {
+	var artistPart, titlePart string
+	if match := artistFirst.FindStringSubmatch(title); match != nil {
+		artistPart, titlePart = match[1], match[2]
+	} else if match := titleFirst.FindStringSubmatch(title); match != nil {
+		titlePart, artistPart = match[1], match[2]
+	} else {
+		return "", title
+	}
+
+	known := artist
+	if known == "" {
+		known = uploaderArtist(uploader)
+	}
+	if !sameArtist(artistPart, known) {
+		if !sameArtist(titlePart, known) {
+			return "", title
+		}
+		artistPart, titlePart = titlePart, artistPart
+	}
+	return tidySpaces(artistPart), tidySpaces(titlePart)
+}
+
+// extractFeat removes a "feat." credit from a title and returns the title and the credited artist.
+func extractFeat(title string) (string, string) {
+	for _, pattern := range []*regexp.Regexp{bracketedFeat, trailingFeat} {
+		if match := pattern.FindStringSubmatchIndex(title); match != nil {
+			featured := title[match[2]:match[3]]
+			return tidySpaces(title[:match[0]] + title[match[1]:]), tidySpaces(featured)
+		}
+	}
+	return title, ""
+}
+
+// uploaderArtist returns the artist name in a channel name such as "Artist - Topic" or "ArtistVEVO".
+func uploaderArtist(uploader string) string {
+	name := strings.TrimSuffix(uploader, " - Topic")
+	name = strings.TrimSuffix(name, "VEVO")
+	return strings.TrimSpace(name)
+}
+
+// sameArtist reports whether name is, or contains, the known artist, ignoring case, spaces and
+// punctuation.
+func sameArtist(name, known string) bool {
+	n, k := normalize(name), normalize(known)
+	return n != "" && k != "" && (n == k || strings.Contains(n, k) || strings.Contains(k, n))
+}
+
+// normalize lowercases s and drops everything but letters and digits.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// tidySpaces collapses runs of spaces and trims separators left at either end of s.
+func tidySpaces(s string) string {
+	return strings.Trim(strings.Join(strings.Fields(s), " "), " -–—~|｜/:")
+}
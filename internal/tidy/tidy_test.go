@@ -0,0 +1,88 @@
+package tidy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/config"
+	"ytpl/internal/tidy"
+	"ytpl/internal/yt"
+)
+
+func TestClean(t *testing.T) {
+	cleaner, err := tidy.New(nil)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name                    string
+		title, artist, uploader string
+		wantTitle, wantArtist   string
+	}{
+		{"strips bracketed noise", "Never Gonna Give You Up (Official Music Video)", "Rick Astley", "Rick Astley",
+			"Never Gonna Give You Up", "Rick Astley"},
+		{"strips Japanese noise", "【公式】夜に駆ける [MV]", "YOASOBI", "Ayase / YOASOBI",
+			"夜に駆ける", "YOASOBI"},
+		{"strips noise after a bar and hashtags", "Song | Official Video #shorts", "Artist", "",
+			"Song", "Artist"},
+		{"keeps numbers after a hash", "Beethoven Symphony #5", "Beethoven", "",
+			"Beethoven Symphony #5", "Beethoven"},
+		{"keeps a number before hashtags", "Song #1 #shorts", "Artist", "",
+			"Song #1", "Artist"},
+		{"splits artist and title", "Rick Astley - Never Gonna Give You Up (Official Video)", "", "RickAstleyVEVO",
+			"Never Gonna Give You Up", "Rick Astley"},
+		{"splits title and artist", "夜に駆ける / YOASOBI", "", "YOASOBI - Topic",
+			"夜に駆ける", "YOASOBI"},
+		{"swaps parts matching the uploader", "Song Title - The Band", "", "The Band - Topic",
+			"Song Title", "The Band"},
+		{"keeps a title whose parts don't match the known artist", "Song - Live at Budokan", "The Band", "",
+			"Song - Live at Budokan", "The Band"},
+		{"keeps a title whose parts don't match the uploader", "Yesterday - Remastered 2009", "", "The Beatles - Topic",
+			"Yesterday - Remastered 2009", ""},
+		{"keeps a remaster note", "Stairway to Heaven - 2012 Remaster", "", "Led Zeppelin",
+			"Stairway to Heaven - 2012 Remaster", ""},
+		{"keeps a live note", "Song Title - Live at Budokan", "", "Some Channel",
+			"Song Title - Live at Budokan", ""},
+		{"keeps titles of unknown artists", "Artist - Song", "", "",
+			"Artist - Song", ""},
+		{"moves feat. credits to the artist", "Artist - Song (feat. Guest) [Official Audio]", "", "ArtistVEVO",
+			"Song", "Artist feat. Guest"},
+		{"moves trailing ft. credits to the uploader", "Song ft. Guest", "", "Artist",
+			"Song", "Artist feat. Guest"},
+		{"keeps credits without an artist", "Song (feat. Guest)", "", "",
+			"Song (feat. Guest)", ""},
+		{"keeps clean titles", "Bohemian Rhapsody", "Queen", "Queen Official",
+			"Bohemian Rhapsody", "Queen"},
+		{"keeps titles that are only noise", "(Official Video)", "", "",
+			"(Official Video)", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			title, artist := cleaner.Clean(tc.title, tc.artist, tc.uploader)
+			assert.Equal(t, tc.wantTitle, title)
+			assert.Equal(t, tc.wantArtist, artist)
+
+			// Cleaning up twice changes nothing
+			again, againArtist := cleaner.Clean(title, artist, tc.uploader)
+			assert.Equal(t, title, again)
+			assert.Equal(t, artist, againArtist)
+		})
+	}
+}
+
+func TestUserRules(t *testing.T) {
+	_, err := tidy.New([]config.TitleRule{{Pattern: "("}})
+	assert.Error(t, err, "invalid patterns should be reported")
+
+	cleaner, err := tidy.New([]config.TitleRule{
+		{Pattern: `(?i)\s*\(remaster(ed)?( \d{4})?\)`},
+		{Pattern: `^(?P<title>.+?) by (?P<artist>.+)$`},
+		{Pattern: `(?i)\bpt\.`, Replace: "Part"},
+	})
+	require.NoError(t, err)
+
+	track := yt.TrackInfo{Title: "Suite pt. 2 (Remastered 2011) by The Band"}
+	assert.True(t, cleaner.Apply(&track))
+	assert.Equal(t, "Suite Part 2", track.Title)
+	assert.Equal(t, "The Band", track.Artist)
+	assert.False(t, cleaner.Apply(&track), "a tidy track should not change")
+}
@@ -0,0 +1,73 @@
+// internal/tracklist/report.go
+package tracklist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Statuses of the tracks in an import report.
+const (
+	StatusMatched     = "matched"     // Resolved to a video and downloaded
+	StatusUncertain   = "uncertain"   // The best search result scored too low to be used
+	StatusNotFound    = "not found"   // The search returned no results
+	StatusFailed      = "failed"      // The search or the download failed, worth retrying
+	StatusUnavailable = "unavailable" // The resolved video can't be downloaded
+)
+
+// reportHeader is the header row of import reports. Its artist, title, duration and id
+// columns make a report a valid track list.
+var reportHeader = []string{"artist", "title", "duration", "id", "status", "score", "candidate", "youtube_title", "note"}
+
+// ReportRow is the outcome of importing a track list entry.
+type ReportRow struct {
+	Entry
+	Status       string
+	Score        int    // Match score of the chosen or candidate video, -1 if not scored
+	Candidate    string // Best search result of an uncertain match
+	YouTubeTitle string // Title of the resolved or candidate video
+	Note         string // Error message of failed tracks
+}
+
+// WriteReport writes an import report as CSV to path. Resolved videos are in the id column,
+// so that importing the report again reuses them and only searches for the others;
+// accepting an uncertain match means copying its candidate to the id column.
+func WriteReport(path string, rows []ReportRow) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	tempPath := path + ".tmp"
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	w.Write(reportHeader)
+	for _, row := range rows {
+		duration, score := "", ""
+		if row.Duration > 0 {
+			duration = strconv.Itoa(int(math.Round(row.Duration)))
+		}
+		if row.Score >= 0 {
+			score = strconv.Itoa(row.Score)
+		}
+		w.Write([]string{row.Artist, row.Title, duration, row.ID, row.Status, score, row.Candidate, row.YouTubeTitle, row.Note})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace report: %w", err)
+	}
+	return nil
+}
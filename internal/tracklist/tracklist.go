@@ -0,0 +1,212 @@
+// internal/tracklist/tracklist.go
+package tracklist
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a track of a track list exported from another music service.
+type Entry struct {
+	Line     int    // Line number in the file, for messages
+	Artist   string // Empty if unknown
+	Title    string
+	Duration float64 // In seconds, 0 if unknown
+	ID       string  // YouTube video ID, if already resolved in a report
+}
+
+// Query returns the search query for the entry, "Artist - Title" or the title alone.
+func (e Entry) Query() string {
+	if e.Artist == "" {
+		return e.Title
+	}
+	return e.Artist + " - " + e.Title
+}
+
+// bom is the byte order mark some services write at the start of exported files.
+const bom = "\ufeff"
+
+// Columns of CSV track lists. Headers are matched case-insensitively against these names,
+// which cover the exports of common music services.
+var (
+	artistColumns   = []string{"artist", "artists", "artist name", "artist name(s)", "artist_name"}
+	titleColumns    = []string{"title", "name", "track", "track name", "track_name"}
+	durationColumns = []string{"duration", "length", "time", "duration_s", "duration (s)"}
+	durationMsCols  = []string{"duration (ms)", "duration_ms", "duration ms"}
+	idColumns       = []string{"id", "video id", "video_id", "youtube id"}
+)
+
+// Load reads the track list at path. Files ending in .csv are read as CSV, with the columns
+// named in a header row or, without one, in the order artist, title, duration.
+// Other files are read as one "Artist - Title" per line; blank lines and lines starting
+// with # are skipped.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open track list: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ParseCSV(f)
+	}
+	return ParseText(f)
+}
+
+// ParseText reads a track list of "Artist - Title" lines. Lines without " - " are titles alone.
+func ParseText(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), bom))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entry := Entry{Line: line, Title: text}
+		if artist, title, ok := strings.Cut(text, " - "); ok {
+			entry.Artist, entry.Title = strings.TrimSpace(artist), strings.TrimSpace(title)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read track list: %w", err)
+	}
+	return entries, nil
+}
+
+// ParseCSV reads a CSV track list, with or without a header row.
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	var (
+		records [][]string
+		lines   []int
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read track list: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// Without a header, the columns are artist, title and duration
+	artistCol, titleCol, durationCol, durationMsCol, idCol := 0, 1, 2, -1, -1
+	start := 0
+	header := make([]string, len(records[0]))
+	for i, name := range records[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, bom)))
+	}
+	if isHeader(header) {
+		artistCol = column(header, artistColumns)
+		titleCol = column(header, titleColumns)
+		durationCol = column(header, durationColumns)
+		durationMsCol = column(header, durationMsCols)
+		idCol = column(header, idColumns)
+		start = 1
+	}
+
+	var entries []Entry
+	for i, record := range records[start:] {
+		entry := Entry{Line: lines[start+i]}
+		entry.Artist = field(record, artistCol)
+		entry.Title = field(record, titleCol)
+		entry.ID = field(record, idCol)
+		if entry.Title == "" && entry.ID == "" {
+			continue
+		}
+		if value := field(record, durationCol); value != "" {
+			seconds, err := ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", entry.Line, err)
+			}
+			entry.Duration = seconds
+		}
+		if value := field(record, durationMsCol); value != "" {
+			ms, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid duration '%s'", entry.Line, value)
+			}
+			entry.Duration = ms / 1000
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ParseDuration parses a track duration given as seconds ("225"), minutes and seconds
+// ("3:45"), hours, minutes and seconds ("1:02:03"), or a Go duration ("3m45s").
+func ParseDuration(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil && seconds >= 0 {
+		return seconds, nil
+	}
+	if parts := strings.Split(s, ":"); len(parts) == 2 || len(parts) == 3 {
+		total := 0.0
+		for _, part := range parts {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration '%s'", s)
+			}
+			total = total*60 + n
+		}
+		return total, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("invalid duration '%s'", s)
+}
+
+// isHeader reports whether the first row of a CSV track list names its columns:
+// it names a title column and has no durations in it.
+func isHeader(row []string) bool {
+	if column(row, titleColumns) < 0 {
+		return false
+	}
+	for _, cell := range row {
+		if _, err := ParseDuration(cell); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// column returns the index of the first header matching one of names, or -1.
+func column(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if h == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// field returns the trimmed value of column i of record, or "" if it has no such column.
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
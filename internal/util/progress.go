@@ -0,0 +1,92 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ProgressBoard renders one status line per slot at the bottom of the terminal
+// and redraws them in place, e.g. one line per parallel download.
+type ProgressBoard struct {
+	mu    sync.Mutex
+	lines []string
+	drawn int // Number of lines currently drawn on screen
+}
+
+// NewProgressBoard creates a progress board with the given number of slots.
+func NewProgressBoard(slots int) *ProgressBoard {
+	return &ProgressBoard{lines: make([]string, slots)}
+}
+
+// Set updates the line of a slot and redraws the board.
+// An empty line hides the slot.
+func (b *ProgressBoard) Set(slot int, line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if slot < 0 || slot >= len(b.lines) {
+		return
+	}
+	b.lines[slot] = strings.ReplaceAll(line, "\n", " ")
+	b.redraw()
+}
+
+// Println prints a permanent line above the board.
+func (b *ProgressBoard) Println(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.clear()
+	fmt.Println(line)
+	b.redraw()
+}
+
+// Close removes the board from the screen.
+func (b *ProgressBoard) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.clear()
+}
+
+// clear erases the drawn lines and leaves the cursor where the board started.
+// Note: Caller must hold the lock
+func (b *ProgressBoard) clear() {
+	if b.drawn == 0 {
+		return
+	}
+	fmt.Printf("\033[%dA", b.drawn) // Move cursor up to the first board line
+	for i := 0; i < b.drawn; i++ {
+		fmt.Print("\r\033[K\n")
+	}
+	fmt.Printf("\033[%dA", b.drawn)
+	b.drawn = 0
+}
+
+// redraw draws the non-empty slot lines.
+// Note: Caller must hold the lock
+func (b *ProgressBoard) redraw() {
+	b.clear()
+	for _, line := range b.lines {
+		if line == "" {
+			continue
+		}
+		fmt.Printf("\r\033[K%s\n", line)
+		b.drawn++
+	}
+}
+
+// FormatBytes converts a byte count to a short human readable string (e.g. "3.4MiB").
+func FormatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", n, units[i])
+}
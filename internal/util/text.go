@@ -0,0 +1,73 @@
+// internal/util/text.go
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// FitWidth truncates or pads s to exactly width terminal cells,
+// counting wide characters such as CJK as two cells.
+func FitWidth(s string, width int) string {
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
+
+// Wrap breaks s into lines of at most width terminal cells, at spaces where possible.
+// Existing line breaks are kept.
+func Wrap(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			// Split words that don't fit on a line of their own
+			for runewidth.StringWidth(word) > width {
+				head := runewidth.Truncate(word, width, "")
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, head)
+				word = strings.TrimPrefix(word, head)
+			}
+			switch {
+			case line == "":
+				line = word
+			case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatCount formats a count compactly, e.g. 1234 as "1.2K" and 1700000000 as "1.7B".
+func FormatCount(n int64) string {
+	switch {
+	case n >= 1_000_000_000:
+		return fmt.Sprintf("%.1fB", float64(n)/1_000_000_000)
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// FormatUploadDate formats a YYYYMMDD date as YYYY-MM-DD, or returns "" if it isn't one.
+func FormatUploadDate(date string) string {
+	if len(date) != 8 {
+		return ""
+	}
+	return date[:4] + "-" + date[4:6] + "-" + date[6:]
+}
@@ -0,0 +1,81 @@
+// internal/yt/audio.go
+package yt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	config "ytpl/internal/config" // Alias for internal/config
+)
+
+// AudioExtensions are the extensions of the audio files yt-dlp produces for the supported
+// audio formats, e.g. ".opus" for "opus" and "best" with a WebM source, ".ogg" for "vorbis".
+var AudioExtensions = []string{".mp3", ".opus", ".m4a", ".ogg", ".flac", ".wav", ".aac"}
+
+// IsAudioFile reports whether name has the extension of a supported audio format.
+func IsAudioFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, audioExt := range AudioExtensions {
+		if ext == audioExt {
+			return true
+		}
+	}
+	return false
+}
+
+// preferredExtension returns the extension of files downloaded with the configured audio format,
+// or "" if it depends on the source.
+func preferredExtension(cfg *config.Config) string {
+	switch cfg.AudioFormat {
+	case "mp3", "opus", "m4a", "flac", "wav":
+		return "." + cfg.AudioFormat
+	case "aac", "alac":
+		return ".m4a"
+	case "vorbis":
+		return ".ogg"
+	default:
+		return ""
+	}
+}
+
+// canEmbedThumbnail reports whether yt-dlp can embed a thumbnail into the files of an audio format.
+// wav files and raw aac streams can't hold one, and yt-dlp fails the download when asked to.
+func canEmbedThumbnail(format string) bool {
+	return format != "wav" && format != "aac"
+}
+
+// ResolveTrackPath returns the path of the audio file of a stocked track and whether it exists.
+// storedPath is the path recorded in the library, if any. If that file is gone, the download
+// directory is searched for a file of the track in any supported format, so that tracks
+// downloaded before the path was recorded, or moved along with the download directory, are found.
+// If no file exists, the path the track would be downloaded to is returned.
+func ResolveTrackPath(cfg *config.Config, trackID, storedPath string) (string, bool) {
+	if storedPath != "" {
+		if _, err := os.Stat(storedPath); err == nil {
+			return storedPath, true
+		}
+		// The download directory may have been moved
+		candidate := filepath.Join(cfg.DownloadDir, filepath.Base(storedPath))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+
+	extensions := AudioExtensions
+	if ext := preferredExtension(cfg); ext != "" {
+		extensions = append([]string{ext}, AudioExtensions...)
+	}
+	for _, ext := range extensions {
+		candidate := filepath.Join(cfg.DownloadDir, FileStem(trackID)+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+
+	ext := preferredExtension(cfg)
+	if ext == "" {
+		ext = ".opus"
+	}
+	return filepath.Join(cfg.DownloadDir, FileStem(trackID)+ext), false
+}
@@ -0,0 +1,221 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrorKind is the cause of a yt-dlp failure.
+type ErrorKind string
+
+const (
+	KindUnknown           ErrorKind = ""                   // Not recognized
+	KindUnavailable       ErrorKind = "unavailable"        // Video removed or never existed
+	KindPrivate           ErrorKind = "private"            // Video made private by the uploader
+	KindAgeRestricted     ErrorKind = "age_restricted"     // Video requires a signed-in account
+	KindGeoBlocked        ErrorKind = "geo_blocked"        // Video not available in the user's country
+	KindRateLimited       ErrorKind = "rate_limited"       // Too many requests (HTTP 429)
+	KindNetwork           ErrorKind = "network"            // Connection failed or timed out
+	KindExtractorOutdated ErrorKind = "extractor_outdated" // yt-dlp can't parse YouTube's pages anymore
+)
+
+// Transient reports whether a failure of this kind may succeed when retried.
+func (k ErrorKind) Transient() bool {
+	return k == KindRateLimited || k == KindNetwork
+}
+
+// errorMarkers maps yt-dlp error messages to their kind.
+// They are checked in order, as some messages contain the markers of a more generic kind
+// (e.g. geo-blocked videos are reported as "Video unavailable. The uploader has not made...").
+var errorMarkers = []struct {
+	kind    ErrorKind
+	markers []string
+}{
+	{KindPrivate, []string{
+		"Private video",
+		"This video is private",
+	}},
+	{KindAgeRestricted, []string{
+		"Sign in to confirm your age",
+		"age-restricted",
+		"inappropriate for some users",
+	}},
+	{KindGeoBlocked, []string{
+		"not available in your country",
+		"not made this video available in your country",
+		"geo restriction",
+		"geo-restricted",
+	}},
+	{KindUnavailable, []string{
+		"Video unavailable",
+		"This video is unavailable",
+		"This video is no longer available",
+		"This video has been removed",
+		"account associated with this video has been terminated",
+		"HTTP Error 404",
+	}},
+	{KindRateLimited, []string{
+		"HTTP Error 429",
+		"Too Many Requests",
+		"rate-limited",
+		"rate limit",
+	}},
+	{KindNetwork, []string{
+		"Temporary failure in name resolution",
+		"Name or service not known",
+		"getaddrinfo failed",
+		"Network is unreachable",
+		"No route to host",
+		"Connection refused",
+		"Connection reset",
+		"timed out",
+		"IncompleteRead",
+		"HTTP Error 500",
+		"HTTP Error 502",
+		"HTTP Error 503",
+		"HTTP Error 504",
+		"Unable to download webpage",
+	}},
+	{KindExtractorOutdated, []string{
+		"Unable to extract",
+		"nsig extraction failed",
+		"Signature extraction failed",
+		"please report this issue",
+		"Confirm you are on the latest version",
+		"Make sure you are using the latest version",
+	}},
+}
+
+// Error is a yt-dlp failure classified by its cause.
+type Error struct {
+	Kind    ErrorKind
+	Message string // Short description including yt-dlp's error line
+	Stderr  string // Full output of yt-dlp on stderr, if any
+	Err     error  // Underlying error, if any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError classifies a failed yt-dlp run from its stderr output.
+// action describes what was attempted, e.g. "failed to download ID xxx".
+func newError(action string, err error, stderr string) *Error {
+	line := errorLine(stderr)
+	message := action
+	if line != "" {
+		message += ": " + line
+	} else if err != nil {
+		message += ": " + err.Error()
+	}
+	return &Error{
+		Kind:    classify(stderr),
+		Message: message,
+		Stderr:  stderr,
+		Err:     err,
+	}
+}
+
+// errorLine returns the last "ERROR:" line printed by yt-dlp.
+func errorLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if idx := strings.Index(lines[i], "ERROR:"); idx >= 0 {
+			return strings.TrimSpace(lines[i][idx:])
+		}
+	}
+	return ""
+}
+
+// classify returns the kind of failure described by yt-dlp's stderr output.
+// Only error lines are considered if there are any, as warnings may mention unrelated problems.
+func classify(stderr string) ErrorKind {
+	var errorLines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.Contains(line, "ERROR:") {
+			errorLines = append(errorLines, line)
+		}
+	}
+	text := stderr
+	if len(errorLines) > 0 {
+		text = strings.Join(errorLines, "\n")
+	}
+
+	for _, entry := range errorMarkers {
+		if containsAny(text, entry.markers) {
+			return entry.kind
+		}
+	}
+	return KindUnknown
+}
+
+// ErrorKindOf returns the kind of a yt-dlp failure, or KindUnknown if err isn't one.
+func ErrorKindOf(err error) ErrorKind {
+	var ytErr *Error
+	if errors.As(err, &ytErr) {
+		return ytErr.Kind
+	}
+	return KindUnknown
+}
+
+// IsTransientError reports whether err is a failure that may succeed when retried.
+func IsTransientError(err error) bool {
+	return ErrorKindOf(err).Transient()
+}
+
+// Retry settings for transient failures. The delay doubles after each failed attempt.
+var (
+	retryAttempts  = 4
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// retryWait waits for delay before the next attempt and returns false if ctx is done first.
+// It is replaced in tests.
+var retryWait = func(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error, or the attempts run out.
+func withRetry(ctx context.Context, fn func() error) error {
+	delay := retryBaseDelay
+	var err error
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		if attempt > 1 {
+			if !retryWait(ctx, delay) {
+				return err
+			}
+			delay *= 2
+			if delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+		}
+
+		err = fn()
+		if err == nil || ctx.Err() != nil || !IsTransientError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// containsAny reports whether s contains any of the given substrings.
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
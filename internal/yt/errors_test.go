@@ -0,0 +1,129 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		stderr string
+		want   ErrorKind
+	}{
+		{"removed video", "ERROR: [youtube] xxxxxxxxxxx: Video unavailable. This video has been removed by the uploader\n",
+			KindUnavailable},
+		{"terminated account", "ERROR: [youtube] xxxxxxxxxxx: Video unavailable. This video is no longer available because the YouTube account associated with this video has been terminated.\n",
+			KindUnavailable},
+		{"private video", "ERROR: [youtube] xxxxxxxxxxx: Private video. Sign in if you've been granted access to this video\n",
+			KindPrivate},
+		{"age restriction", "ERROR: [youtube] xxxxxxxxxxx: Sign in to confirm your age. This video may be inappropriate for some users.\n",
+			KindAgeRestricted},
+		{"geo-blocked before unavailable", "ERROR: [youtube] xxxxxxxxxxx: Video unavailable. The uploader has not made this video available in your country\n",
+			KindGeoBlocked},
+		{"rate limit before network", "WARNING: [youtube] Unable to download webpage: HTTP Error 429: Too Many Requests\nERROR: [youtube] xxxxxxxxxxx: Unable to download webpage: HTTP Error 429: Too Many Requests (caused by <HTTPError 429: Too Many Requests>)\n",
+			KindRateLimited},
+		{"name resolution", "ERROR: [youtube] xxxxxxxxxxx: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution> (caused by TransportError('<urlopen error [Errno -3] Temporary failure in name resolution>'))\n",
+			KindNetwork},
+		{"server error", "ERROR: unable to download video data: HTTP Error 503: Service Unavailable\n",
+			KindNetwork},
+		{"timeout", "ERROR: [download] Got error: The read operation timed out\n",
+			KindNetwork},
+		{"outdated extractor", "WARNING: [youtube] xxxxxxxxxxx: nsig extraction failed: Some formats may be missing\nERROR: [youtube] xxxxxxxxxxx: Unable to extract uploader id; please report this issue on  https://github.com/yt-dlp/yt-dlp/issues?q= , filling out the appropriate issue template. Confirm you are on the latest version using  yt-dlp -U\n",
+			KindExtractorOutdated},
+		{"warnings are ignored when there are errors", "WARNING: [youtube] Unable to download webpage: HTTP Error 429: Too Many Requests\nERROR: [youtube] xxxxxxxxxxx: Video unavailable\n",
+			KindUnavailable},
+		{"warnings are used without errors", "WARNING: [youtube] xxxxxxxxxxx: Private video\n",
+			KindPrivate},
+		{"unknown failure", "ERROR: Postprocessing: Conversion failed!\n",
+			KindUnknown},
+		{"no output", "", KindUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, classify(tc.stderr))
+		})
+	}
+}
+
+func TestNewError(t *testing.T) {
+	err := newError("failed to download ID xxxxxxxxxxx", errors.New("exit status 1"),
+		"[youtube] Extracting URL\nERROR: [youtube] xxxxxxxxxxx: Video unavailable\n")
+	assert.Equal(t, "failed to download ID xxxxxxxxxxx: ERROR: [youtube] xxxxxxxxxxx: Video unavailable", err.Error())
+	assert.Equal(t, KindUnavailable, ErrorKindOf(err))
+	assert.False(t, IsTransientError(err))
+
+	err = newError("failed to execute yt-dlp search", errors.New("exit status 2"), "")
+	assert.Equal(t, "failed to execute yt-dlp search: exit status 2", err.Error())
+	assert.Equal(t, KindUnknown, ErrorKindOf(err))
+}
+
+func TestWithRetry(t *testing.T) {
+	var delays []time.Duration
+	previous := retryWait
+	retryWait = func(ctx context.Context, delay time.Duration) bool {
+		delays = append(delays, delay)
+		return ctx.Err() == nil
+	}
+	t.Cleanup(func() { retryWait = previous })
+
+	transient := &Error{Kind: KindRateLimited, Message: "HTTP Error 429"}
+	failing := func(errs ...error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("retries transient failures with growing delays", func(t *testing.T) {
+		delays = nil
+		fn, calls := failing(transient, &Error{Kind: KindNetwork})
+		assert.NoError(t, withRetry(context.Background(), fn))
+		assert.Equal(t, 3, *calls)
+		assert.Equal(t, []time.Duration{retryBaseDelay, 2 * retryBaseDelay}, delays)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		delays = nil
+		fn, calls := failing(transient, transient, transient, transient, transient)
+		assert.Equal(t, transient, withRetry(context.Background(), fn))
+		assert.Equal(t, retryAttempts, *calls)
+		assert.Len(t, delays, retryAttempts-1)
+		for _, delay := range delays {
+			assert.LessOrEqual(t, delay, retryMaxDelay)
+		}
+	})
+
+	for _, kind := range []ErrorKind{KindUnavailable, KindPrivate, KindAgeRestricted, KindGeoBlocked, KindExtractorOutdated, KindUnknown} {
+		t.Run("doesn't retry "+string(kind)+" failures", func(t *testing.T) {
+			delays = nil
+			permanent := &Error{Kind: kind}
+			fn, calls := failing(permanent)
+			assert.Equal(t, permanent, withRetry(context.Background(), fn))
+			assert.Equal(t, 1, *calls)
+			assert.Empty(t, delays)
+		})
+	}
+
+	t.Run("doesn't retry other errors", func(t *testing.T) {
+		other := errors.New("failed to parse output")
+		fn, calls := failing(other)
+		assert.Equal(t, other, withRetry(context.Background(), fn))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		fn, calls := failing(transient, transient)
+		assert.Equal(t, transient, withRetry(ctx, fn))
+		assert.Equal(t, 1, *calls)
+	})
+}
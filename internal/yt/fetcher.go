@@ -0,0 +1,202 @@
+// internal/yt/fetcher.go
+package yt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	config "ytpl/internal/config" // Alias for internal/config
+)
+
+// Fetcher retrieves search results, audio files and metadata from YouTube and the other sources.
+// The package uses YtDlp by default; tests replace it with a fake serving fixtures.
+type Fetcher interface {
+	// Search returns one --dump-json object per line for the results of query on page opts.Page,
+	// count results per page. Filters and sorting it can't apply are left to the caller.
+	Search(ctx context.Context, cfg *config.Config, query string, count int, opts SearchOptions) ([]byte, error)
+	// Download saves the audio of a track as <stem>.<ext> in cfg.DownloadDir along with
+	// <stem>.info.json, where stem is FileStem(trackID), and returns the --print-json output
+	// of the download.
+	Download(ctx context.Context, cfg *config.Config, trackID string, onProgress func(DownloadProgress)) ([]byte, error)
+	// Metadata returns the --dump-json output of a track without downloading it.
+	Metadata(ctx context.Context, cfg *config.Config, trackID string) ([]byte, error)
+	// Playlist returns one --flat-playlist --dump-json object per line for the entries
+	// of a playlist or channel URL, in playlist order.
+	Playlist(ctx context.Context, cfg *config.Config, url string) ([]byte, error)
+}
+
+// fetcher is the Fetcher used by the package functions.
+var fetcher Fetcher = YtDlp{}
+
+// SetFetcher replaces the Fetcher used by the package and returns the previous one.
+func SetFetcher(f Fetcher) Fetcher {
+	previous := fetcher
+	fetcher = f
+	return previous
+}
+
+// YtDlp is a Fetcher running the yt-dlp executable at cfg.YtDlpPath.
+// Failures are returned as *Error.
+type YtDlp struct{}
+
+// Search implements Fetcher.
+func (YtDlp) Search(ctx context.Context, cfg *config.Config, query string, count int, opts SearchOptions) ([]byte, error) {
+	cmdArgs := []string{
+		"--dump-json",
+		"--print-json",                      // Print one JSON object per line
+		"--no-warnings",                     // Ignore warnings
+		"--ignore-errors",                   // Continue on download errors, e.g. skip unavailable videos
+		"--match-filter", matchFilter(opts), // Filters yt-dlp applies itself
+		"--playlist-items", playlistItems(count, opts), // Only the requested page
+	}
+	if opts.After != "" {
+		cmdArgs = append(cmdArgs, "--dateafter", opts.After)
+	}
+	if !needsFullMetadata(opts) {
+		cmdArgs = append(cmdArgs, "--flat-playlist") // Fast search with minimal metadata
+	}
+	cmdArgs = append(cmdArgs, searchTerm(query, count, opts))
+
+	cmd := exec.CommandContext(ctx, cfg.YtDlpPath, cmdArgs...)
+
+	// Redirect stderr to a buffer to prevent yt-dlp messages from interfering with TUI.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, newError("failed to execute yt-dlp search", err, stderr.String())
+	}
+	return output, nil
+}
+
+// Download implements Fetcher.
+func (YtDlp) Download(ctx context.Context, cfg *config.Config, trackID string, onProgress func(DownloadProgress)) ([]byte, error) {
+	cmdArgs := downloadArgs(cfg, trackID, onProgress != nil)
+
+	cmd := exec.CommandContext(ctx, cfg.YtDlpPath, cmdArgs...)
+	var stdout, stderr bytes.Buffer
+	// Progress lines may be written to either stream depending on yt-dlp's quiet mode,
+	// so both are filtered before being buffered
+	stdoutWriter := &progressWriter{out: &stdout, onProgress: onProgress}
+	stderrWriter := &progressWriter{out: &stderr, onProgress: onProgress}
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter // Redirect yt-dlp's stderr to a buffer
+
+	err := cmd.Run() // Execute the command and capture stdout
+	stdoutWriter.Flush()
+	stderrWriter.Flush()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("download of ID %s cancelled: %w", trackID, ctx.Err())
+	}
+	if err != nil {
+		return nil, newError(fmt.Sprintf("failed to download ID %s", trackID), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// downloadArgs returns the yt-dlp arguments downloading the audio of a track,
+// reporting progress as machine readable lines if progress is true.
+func downloadArgs(cfg *config.Config, trackID string, progress bool) []string {
+	// Files are named after the track ID rather than the extractor's ID
+	outputTemplate := filepath.Join(cfg.DownloadDir, FileStem(trackID)+".%(ext)s")
+
+	cmdArgs := []string{
+		"-o", outputTemplate, // Output file template
+		"--extract-audio",                 // Extract audio
+		"--audio-format", cfg.AudioFormat, // Convert unless the format is "best" or matches the source
+		"--audio-quality", cfg.AudioQuality, // Quality used when re-encoding
+		"--restrict-filenames", // Restrict filenames to ASCII and numeric
+		"--no-simulate",        // Ensure actual download occurs
+		"--print-json",         // Print final info JSON to stdout after download
+		"--write-info-json",    // Save metadata to a .info.json file alongside the audio
+		"--embed-metadata",     // Explicitly embed metadata into the audio file
+	}
+	if canEmbedThumbnail(cfg.AudioFormat) {
+		cmdArgs = append(cmdArgs, "--embed-thumbnail") // Embed thumbnail into the audio file (optional, makes it larger)
+	}
+
+	if progress {
+		// Report progress as machine readable lines instead of the progress bar
+		cmdArgs = append(cmdArgs, "--progress", "--newline", "--progress-template", "download:"+progressTemplate)
+	} else {
+		cmdArgs = append(cmdArgs, "--no-progress") // Suppress download progress bar for cleaner output
+	}
+
+	cmdArgs = append(cmdArgs, cookieArgs(cfg)...)
+
+	// URL argument must be passed after '--' for safety
+	return append(cmdArgs, "--", TrackURL(trackID))
+}
+
+// Metadata implements Fetcher.
+func (YtDlp) Metadata(ctx context.Context, cfg *config.Config, trackID string) ([]byte, error) {
+	cmdArgs := []string{
+		"--dump-json",
+		"--skip-download", // Only fetch the metadata
+		"--no-warnings",
+	}
+	cmdArgs = append(cmdArgs, cookieArgs(cfg)...)
+	cmdArgs = append(cmdArgs, "--", TrackURL(trackID))
+
+	cmd := exec.CommandContext(ctx, cfg.YtDlpPath, cmdArgs...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, newError(fmt.Sprintf("failed to fetch metadata of ID %s", trackID), err, stderr.String())
+	}
+	return output, nil
+}
+
+// Playlist implements Fetcher.
+func (YtDlp) Playlist(ctx context.Context, cfg *config.Config, url string) ([]byte, error) {
+	cmdArgs := []string{
+		"--flat-playlist", // List the entries without resolving each video
+		"--dump-json",
+		"--no-warnings",
+		"--ignore-errors", // Skip entries that can't be listed
+	}
+	cmdArgs = append(cmdArgs, cookieArgs(cfg)...)
+	cmdArgs = append(cmdArgs, "--", url)
+
+	cmd := exec.CommandContext(ctx, cfg.YtDlpPath, cmdArgs...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil && len(output) == 0 {
+		return nil, newError("failed to list playlist "+url, err, stderr.String())
+	}
+	// With --ignore-errors, yt-dlp exits with an error if some entries failed,
+	// but the listed entries are still usable
+	return output, nil
+}
+
+// cookieArgs returns the yt-dlp options loading cookies from the configured browser,
+// needed for age-restricted content.
+func cookieArgs(cfg *config.Config) []string {
+	if cfg.CookieBrowser == "" {
+		return nil
+	}
+	cookieArg := fmt.Sprintf("--cookies-from-browser=%s", cfg.CookieBrowser)
+	if cfg.CookieProfile != "" {
+		cookieArg = fmt.Sprintf("--cookies-from-browser=%s:%s", cfg.CookieBrowser, cfg.CookieProfile)
+	}
+	return []string{cookieArg}
+}
+
+// WatchURL returns the YouTube watch page URL of a video.
+func WatchURL(trackID string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", trackID)
+}
+
+// MixURL returns the URL of the mix YouTube generates for a video, the "RD" playlist
+// of videos related to it.
+func MixURL(trackID string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s&list=RD%s", trackID, trackID)
+}
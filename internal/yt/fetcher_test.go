@@ -0,0 +1,36 @@
+package yt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ytpl/internal/config"
+)
+
+func TestDownloadArgs(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		embed  bool
+	}{
+		{"mp3", true},
+		{"best", true},
+		{"opus", true},
+		{"flac", true},
+		{"alac", true},
+		{"wav", false},
+		{"aac", false},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			cfg := &config.Config{DownloadDir: t.TempDir(), AudioFormat: tc.format, AudioQuality: "0"}
+			args := downloadArgs(cfg, "dQw4w9WgXcQ", false)
+
+			assert.Contains(t, args, tc.format)
+			if tc.embed {
+				assert.Contains(t, args, "--embed-thumbnail")
+			} else {
+				assert.NotContains(t, args, "--embed-thumbnail", "yt-dlp fails to embed a thumbnail into %s files", tc.format)
+			}
+			assert.Equal(t, []string{"--", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, args[len(args)-2:])
+		})
+	}
+}
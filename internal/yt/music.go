@@ -0,0 +1,139 @@
+// internal/yt/music.go
+package yt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	config "ytpl/internal/config" // Alias for internal/config
+)
+
+// Sections of YouTube Music search results, which yt-dlp's music search extractor takes
+// as the fragment of the search URL.
+const (
+	MusicSongs   = "songs"
+	MusicAlbums  = "albums"
+	MusicArtists = "artists"
+)
+
+// MusicSections are the sections of YouTube Music search results, in the order they are shown.
+var MusicSections = []string{MusicSongs, MusicAlbums, MusicArtists}
+
+// MusicResult is a song, album or artist found on YouTube Music.
+type MusicResult struct {
+	Section  string  // MusicSongs, MusicAlbums or MusicArtists
+	ID       string  // Video ID of songs, browse or playlist ID of albums, channel ID of artists
+	Title    string  // Title of songs and albums, name of artists
+	Artist   string  // Artist of songs
+	Duration float64 // Duration of songs in seconds
+	URL      string  // YouTube Music page of albums and artists
+}
+
+// musicEntry is a --flat-playlist entry of YouTube Music search results.
+type musicEntry struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	URL      string   `json:"url"`
+	Duration float64  `json:"duration"`
+	Channel  string   `json:"channel"`
+	Uploader string   `json:"uploader"`
+	Artists  []string `json:"artists"`
+}
+
+// MusicSearchURL returns the URL of a section of YouTube Music's search results for query.
+func MusicSearchURL(query, section string) string {
+	return "https://music.youtube.com/search?q=" + url.QueryEscape(query) + "#" + section
+}
+
+// Track returns the song of a result as a track.
+func (r MusicResult) Track() TrackInfo {
+	return TrackInfo{
+		ID:         r.ID,
+		Title:      r.Title,
+		WebpageURL: WatchURL(r.ID),
+		Duration:   r.Duration,
+		Uploader:   r.Artist,
+		Artist:     r.Artist,
+	}
+}
+
+// SearchMusic searches YouTube Music and returns up to cfg.MaxSearchResults results of each
+// of the given sections, or of all sections if none are given, grouped by section.
+func SearchMusic(cfg *config.Config, query string, sections ...string) ([]MusicResult, error) {
+	if len(sections) == 0 {
+		sections = MusicSections
+	}
+
+	var results []MusicResult
+	for _, section := range sections {
+		var output []byte
+		err := withRetry(context.Background(), func() error {
+			var err error
+			output, err = fetcher.Search(context.Background(), cfg, query, cfg.MaxSearchResults, SearchOptions{Music: section})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			var entry musicEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to unmarshal yt-dlp json line: %v\nline: %s\n", err, line)
+				continue
+			}
+			if result, ok := entry.result(section); ok {
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// result returns the search result of an entry, if it is a playable song or has a page.
+func (e musicEntry) result(section string) (MusicResult, bool) {
+	result := MusicResult{Section: section, ID: e.ID, Title: e.Title, URL: e.URL}
+	if section == MusicSongs {
+		result.Duration = e.Duration
+		result.Artist = strings.Join(e.Artists, ", ")
+		if result.Artist == "" {
+			result.Artist = strings.TrimSuffix(firstNonEmpty(e.Channel, e.Uploader), " - Topic")
+		}
+		return result, videoIDPattern.MatchString(e.ID)
+	}
+	return result, e.URL != "" && e.Title != ""
+}
+
+// FetchAlbum lists the tracks of an album found on YouTube Music in album order,
+// with the album name, artist and track numbers filled in.
+func FetchAlbum(cfg *config.Config, album MusicResult) ([]TrackInfo, error) {
+	tracks, err := FetchPlaylist(cfg, album.URL)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tracks {
+		tracks[i].Album = album.Title
+		tracks[i].TrackNumber = i + 1
+		if tracks[i].Artist == "" {
+			tracks[i].Artist = strings.TrimSuffix(firstNonEmpty(tracks[i].Uploader, tracks[i].Creator), " - Topic")
+		}
+	}
+	return tracks, nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
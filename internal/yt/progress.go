@@ -0,0 +1,119 @@
+package yt
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// progressMarker prefixes the progress lines printed through progressTemplate.
+const progressMarker = "[ytpl-progress]"
+
+// progressTemplate is passed to yt-dlp's --progress-template.
+// Raw numeric fields are used so that the output doesn't depend on yt-dlp's formatting or colors.
+const progressTemplate = progressMarker +
+	" %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s" +
+	" %(progress.speed)s %(progress.eta)s"
+
+// DownloadProgress is a progress update reported by yt-dlp while downloading a track.
+type DownloadProgress struct {
+	Percent         float64 // 0-100, or -1 if the total size is unknown
+	DownloadedBytes int64
+	TotalBytes      int64   // 0 if unknown
+	Speed           float64 // Bytes per second, 0 if unknown
+	ETA             int     // Seconds, -1 if unknown
+}
+
+// parseProgressLine parses a line printed through progressTemplate.
+// The second return value is false if the line is not a progress line.
+func parseProgressLine(line string) (DownloadProgress, bool) {
+	idx := strings.Index(line, progressMarker)
+	if idx < 0 {
+		return DownloadProgress{}, false
+	}
+	fields := strings.Fields(line[idx+len(progressMarker):])
+	if len(fields) != 5 {
+		return DownloadProgress{}, false
+	}
+
+	// yt-dlp prints "NA" for missing values
+	number := func(s string) float64 {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return -1
+		}
+		return v
+	}
+
+	p := DownloadProgress{Percent: -1, ETA: -1}
+	if downloaded := number(fields[0]); downloaded > 0 {
+		p.DownloadedBytes = int64(downloaded)
+	}
+	total := number(fields[1])
+	if total <= 0 {
+		total = number(fields[2])
+	}
+	if total > 0 {
+		p.TotalBytes = int64(total)
+		p.Percent = float64(p.DownloadedBytes) / total * 100
+		if p.Percent > 100 {
+			p.Percent = 100
+		}
+	}
+	if speed := number(fields[3]); speed > 0 {
+		p.Speed = speed
+	}
+	if eta := number(fields[4]); eta >= 0 {
+		p.ETA = int(eta)
+	}
+	return p, true
+}
+
+// progressWriter passes yt-dlp output through to out, except for progress lines
+// which are parsed and reported to onProgress.
+type progressWriter struct {
+	mu         sync.Mutex
+	out        io.Writer
+	onProgress func(DownloadProgress)
+	buf        []byte
+}
+
+// Write implements io.Writer.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		// Progress lines may end with '\r' as well as '\n'
+		idx := bytes.IndexAny(w.buf, "\r\n")
+		if idx < 0 {
+			break
+		}
+		line := w.buf[:idx+1]
+		if progress, ok := parseProgressLine(string(line)); ok {
+			if w.onProgress != nil {
+				w.onProgress(progress)
+			}
+		} else if _, err := w.out.Write(line); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any remaining partial line to the underlying writer.
+func (w *progressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		if _, ok := parseProgressLine(string(w.buf)); !ok {
+			_, _ = w.out.Write(w.buf)
+		}
+		w.buf = nil
+	}
+}
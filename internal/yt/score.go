@@ -0,0 +1,158 @@
+// internal/yt/score.go
+package yt
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MatchQuery describes the track a search looks for, to score search results against.
+type MatchQuery struct {
+	Query    string  // Search query, typically "Artist - Title"
+	Duration float64 // Expected duration in seconds, 0 if unknown
+}
+
+// Score weights. A result whose title and channel contain every query word scores
+// scoreTokens; markers of the original recording and a close duration add to it.
+const (
+	scoreTokens        = 60
+	scoreOfficialAudio = 15
+	scoreOfficialVideo = 8
+	scoreTopicChannel  = 15
+	scoreArtistChannel = 5
+	scoreDuration      = 15
+	penaltyVariant     = 25
+	penaltyExtraWord   = 2
+	maxExtraWordCost   = 10
+)
+
+var (
+	officialAudioPattern = regexp.MustCompile(`\bofficial\s+(audio|lyric video)\b`)
+	officialVideoPattern = regexp.MustCompile(`\bofficial\s+(music\s+)?video\b|\bofficial\s+mv\b`)
+
+	// variantPatterns match titles of versions other than the original recording.
+	// A pattern doesn't count against a result if the query matches it too.
+	variantPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\blive\b|\bin concert\b`),
+		regexp.MustCompile(`\bcover(ed)?\b`),
+		regexp.MustCompile(`\bloop(ed)?\b|\b\d+\s*hours?\b`),
+		regexp.MustCompile(`\bslowed\b|\breverb\b|\bsped\s+up\b|\bnightcore\b|\b8d\b`),
+		regexp.MustCompile(`\bkaraoke\b|\binstrumental\b|\boff vocal\b`),
+		regexp.MustCompile(`\bremix\b`),
+		regexp.MustCompile(`\breaction\b`),
+	}
+
+	// noiseWords are title words that say nothing about which song a video is.
+	noiseWords = map[string]bool{
+		"official": true, "audio": true, "video": true, "music": true, "mv": true, "pv": true,
+		"lyric": true, "lyrics": true, "hd": true, "hq": true, "4k": true, "remastered": true,
+		"remaster": true, "version": true, "ver": true, "full": true, "ft": true, "feat": true,
+		"topic": true,
+	}
+)
+
+// ScoreMatch returns how confidently track is the song q looks for, from 0 to 100.
+// It rewards query words found in the title or channel name, "official audio" titles,
+// auto-generated "Topic" channels and a duration close to the expected one, and penalizes
+// live versions, covers, loops, slowed or sped up edits and remixes the query doesn't ask for.
+func ScoreMatch(track TrackInfo, q MatchQuery) int {
+	query := strings.ToLower(q.Query)
+	title := strings.ToLower(track.Title)
+	uploader := strings.ToLower(track.Uploader)
+
+	queryWords := words(query)
+	haystack := append(words(title), words(uploader)...)
+	score := 0.0
+	if len(queryWords) > 0 {
+		found := 0
+		for _, word := range queryWords {
+			if containsWord(haystack, word) {
+				found++
+			}
+		}
+		score += scoreTokens * float64(found) / float64(len(queryWords))
+	}
+
+	// Title words beyond the query suggest a different song or version
+	extra := 0.0
+	for _, word := range words(title) {
+		if !noiseWords[word] && !containsWord(queryWords, word) {
+			extra += penaltyExtraWord
+		}
+	}
+	score -= math.Min(extra, maxExtraWordCost)
+
+	switch {
+	case officialAudioPattern.MatchString(title):
+		score += scoreOfficialAudio
+	case officialVideoPattern.MatchString(title):
+		score += scoreOfficialVideo
+	}
+	artist := uploader
+	if strings.HasSuffix(artist, " - topic") {
+		score += scoreTopicChannel
+		artist = strings.TrimSuffix(artist, " - topic")
+	}
+	// The channel is named after an artist in the query, like "Artist" or "ArtistVEVO"
+	artistWords := words(strings.TrimSuffix(artist, "vevo"))
+	if len(artistWords) > 0 && strings.Contains(" "+strings.Join(queryWords, " ")+" ", " "+strings.Join(artistWords, " ")+" ") {
+		score += scoreArtistChannel
+	}
+
+	for _, pattern := range variantPatterns {
+		if pattern.MatchString(title) && !pattern.MatchString(query) {
+			score -= penaltyVariant
+		}
+	}
+
+	if q.Duration > 0 && track.Duration > 0 {
+		// Full points within a few seconds, nothing at 30 seconds off, a penalty beyond
+		diff := math.Abs(track.Duration - q.Duration)
+		score += math.Max(scoreDuration-diff/2, -penaltyVariant)
+	}
+
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
+
+// BestMatch returns the index and score of the track scoring highest against q,
+// preferring the earlier track on a tie, or -1 if tracks is empty.
+func BestMatch(tracks []TrackInfo, q MatchQuery) (int, int) {
+	best, bestScore := -1, -1
+	for i, track := range tracks {
+		if score := ScoreMatch(track, q); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, bestScore
+}
+
+// words splits s into words of letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// containsWord reports whether word is one of haystack, or part of one of them for words
+// in scripts written without spaces, such as Japanese.
+func containsWord(haystack []string, word string) bool {
+	spaced := isSpacedScript(word)
+	for _, w := range haystack {
+		if w == word || (!spaced && strings.Contains(w, word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpacedScript reports whether word is written in a script separating words with spaces.
+func isSpacedScript(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+// internal/yt/search.go
+package yt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Sort orders of search results.
+const (
+	SortRelevance = "relevance"
+	SortDate      = "date"
+	SortViews     = "views"
+)
+
+// SearchSorts are the supported sort orders of search results.
+var SearchSorts = []string{SortRelevance, SortDate, SortViews}
+
+// SearchOptions narrows down and orders search results.
+// Filters yt-dlp supports are passed to it as a --match-filter, and all filters are
+// applied again to the results, so that they also hold for fetchers ignoring them.
+type SearchOptions struct {
+	MinDuration float64        // In seconds, 0 for no limit
+	MaxDuration float64        // In seconds, 0 for no limit
+	Channel     string         // Case-insensitive part of the uploader name
+	Exclude     *regexp.Regexp // Titles matching it are dropped
+	After       string         // Earliest upload date in YYYYMMDD format
+	Sort        string         // SortRelevance (default), SortDate or SortViews
+	Page        int            // 0 for the first page of results
+	Music       string         // Section of YouTube Music results to search, "" to search YouTube videos
+}
+
+// searchTerm returns the yt-dlp search URL for the first count*(page+1) results of query.
+// YouTube can sort by upload date itself; sorting by views is done after the fetch.
+// YouTube Music searches are URLs, paged by --playlist-items alone.
+func searchTerm(query string, count int, opts SearchOptions) string {
+	if opts.Music != "" {
+		return MusicSearchURL(query, opts.Music)
+	}
+	prefix := "ytsearch"
+	if opts.Sort == SortDate {
+		prefix = "ytsearchdate"
+	}
+	return fmt.Sprintf("%s%d:%s", prefix, count*(opts.Page+1), query)
+}
+
+// playlistItems returns the --playlist-items range of the requested page of results.
+func playlistItems(count int, opts SearchOptions) string {
+	return fmt.Sprintf("%d:%d", count*opts.Page+1, count*(opts.Page+1))
+}
+
+// matchFilter returns the yt-dlp --match-filter expression for the filters yt-dlp can apply.
+// The title exclusion is left out, as its regular expression uses Go rather than Python syntax,
+// and the upload date is passed as --dateafter.
+func matchFilter(opts SearchOptions) string {
+	conditions := []string{"!is_live", "!is_upcoming"} // Filter out live and upcoming videos
+	if opts.MinDuration > 0 {
+		conditions = append(conditions, fmt.Sprintf("duration >= %d", int(opts.MinDuration)))
+	}
+	if opts.MaxDuration > 0 {
+		conditions = append(conditions, fmt.Sprintf("duration <= %d", int(opts.MaxDuration)))
+	}
+	if opts.Channel != "" {
+		pattern := strings.ReplaceAll("(?i)"+regexp.QuoteMeta(opts.Channel), "'", `\'`)
+		conditions = append(conditions, fmt.Sprintf("uploader ~= '%s'", pattern))
+	}
+	return strings.Join(conditions, " & ")
+}
+
+// needsFullMetadata reports whether the search must fetch the full metadata of each result,
+// which is slower: upload dates are missing from flat search results.
+func needsFullMetadata(opts SearchOptions) bool {
+	return opts.After != ""
+}
+
+// matches reports whether a search result passes the filters.
+func (opts SearchOptions) matches(track TrackInfo) bool {
+	if opts.MinDuration > 0 && track.Duration < opts.MinDuration {
+		return false
+	}
+	if opts.MaxDuration > 0 && track.Duration > opts.MaxDuration {
+		return false
+	}
+	if opts.Channel != "" && !strings.Contains(strings.ToLower(track.Uploader), strings.ToLower(opts.Channel)) {
+		return false
+	}
+	if opts.Exclude != nil && opts.Exclude.MatchString(track.Title) {
+		return false
+	}
+	if opts.After != "" && track.UploadDate < opts.After {
+		return false
+	}
+	return true
+}
+
+// filterAndSort drops the results not passing the filters and orders the rest.
+func (opts SearchOptions) filterAndSort(tracks []TrackInfo) []TrackInfo {
+	filtered := tracks[:0]
+	for _, track := range tracks {
+		if opts.matches(track) {
+			filtered = append(filtered, track)
+		}
+	}
+
+	switch opts.Sort {
+	case SortViews:
+		sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].ViewCount > filtered[j].ViewCount })
+	case SortDate:
+		sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].UploadDate > filtered[j].UploadDate })
+	}
+	return filtered
+}
+
+// IsSearchSort reports whether s is a supported sort order.
+func IsSearchSort(s string) bool {
+	for _, order := range SearchSorts {
+		if s == order {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+// internal/yt/source.go
+package yt
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Track IDs of YouTube videos are bare video IDs, as they always were. Tracks from other
+// sites yt-dlp supports have source-qualified IDs "<prefix>:<path>", e.g. "sc:artist/track"
+// for SoundCloud, from which the URL of the track is built without any lookup.
+
+// Source is a site tracks can be downloaded from, other than YouTube.
+type Source struct {
+	Prefix    string // Prefix of the source's track IDs
+	Name      string // Name shown to the user
+	Extractor string // yt-dlp extractor of the source's URLs
+	// host reports whether a URL host belongs to the source.
+	host func(host string) bool
+	// parse returns the path identifying a track in a URL of the source, if it is a track URL.
+	parse func(u *url.URL) (string, bool)
+	// url builds the URL of a track from its path.
+	url func(path string) string
+}
+
+// pathSegmentPattern matches a URL path segment that can be part of a file name.
+var pathSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+
+// Sources are the supported sites besides YouTube. The generic source accepts the URL of
+// any other site; yt-dlp decides whether it can download it.
+var Sources = []Source{
+	{
+		Prefix:    "sc",
+		Name:      "SoundCloud",
+		Extractor: "soundcloud",
+		host:      func(host string) bool { return host == "soundcloud.com" || host == "m.soundcloud.com" },
+		parse: func(u *url.URL) (string, bool) {
+			// soundcloud.com/<artist>/<track>; other paths are sets, likes and profiles
+			segments := pathSegments(u)
+			if len(segments) != 2 || segments[1] == "sets" || segments[1] == "likes" || segments[1] == "tracks" {
+				return "", false
+			}
+			return strings.Join(segments, "/"), true
+		},
+		url: func(path string) string { return "https://soundcloud.com/" + path },
+	},
+	{
+		Prefix:    "bc",
+		Name:      "Bandcamp",
+		Extractor: "Bandcamp",
+		host:      func(host string) bool { return strings.HasSuffix(host, ".bandcamp.com") },
+		parse: func(u *url.URL) (string, bool) {
+			// <artist>.bandcamp.com/track/<track>
+			artist := strings.TrimSuffix(hostOf(u), ".bandcamp.com")
+			segments := pathSegments(u)
+			if !pathSegmentPattern.MatchString(artist) || len(segments) != 2 || segments[0] != "track" {
+				return "", false
+			}
+			return artist + "/" + segments[1], true
+		},
+		url: func(path string) string {
+			artist, track, _ := strings.Cut(path, "/")
+			return "https://" + artist + ".bandcamp.com/track/" + track
+		},
+	},
+	{
+		Prefix:    "vm",
+		Name:      "Vimeo",
+		Extractor: "vimeo",
+		host:      func(host string) bool { return host == "vimeo.com" },
+		parse: func(u *url.URL) (string, bool) {
+			// vimeo.com/<numeric id>
+			segments := pathSegments(u)
+			if len(segments) != 1 || strings.Trim(segments[0], "0123456789") != "" {
+				return "", false
+			}
+			return segments[0], true
+		},
+		url: func(path string) string { return "https://vimeo.com/" + path },
+	},
+	{
+		Prefix:    "url",
+		Name:      "Web",
+		Extractor: "generic",
+		host:      func(host string) bool { return true },
+		parse: func(u *url.URL) (string, bool) {
+			// Any other site, by host and path; the query string can't be kept
+			segments := pathSegments(u)
+			if u.RawQuery != "" || len(segments) == 0 || !pathSegmentPattern.MatchString(hostOf(u)) {
+				return "", false
+			}
+			return hostOf(u) + "/" + strings.Join(segments, "/"), true
+		},
+		url: func(path string) string { return "https://" + path },
+	},
+}
+
+// ParseTrackRef returns the track ID of a URL, a video ID or a track ID.
+// YouTube URLs and IDs give bare video IDs, and URLs of other sites source-qualified IDs.
+// The second return value is false if s is neither a supported URL nor an ID.
+func ParseTrackRef(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if id, ok := ParseVideoID(s); ok {
+		return id, true
+	}
+
+	if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		if strings.HasSuffix(hostOf(u), "youtube.com") || hostOf(u) == "youtu.be" {
+			return "", false // A YouTube URL that isn't a video
+		}
+		// The first source the host belongs to decides, so that albums and profiles
+		// of known sites aren't taken for generic tracks
+		for _, source := range Sources {
+			if !source.host(hostOf(u)) {
+				continue
+			}
+			if path, ok := source.parse(u); ok && validTrackPath(path) {
+				return source.Prefix + ":" + path, true
+			}
+			return "", false
+		}
+		return "", false
+	}
+
+	// An ID from a playlist or the library
+	prefix, path, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", false
+	}
+	if prefix == "yt" {
+		return ParseVideoID(path)
+	}
+	if sourceOf(prefix) != nil && validTrackPath(path) {
+		return s, true
+	}
+	return "", false
+}
+
+// TrackURL returns the URL of a track, which yt-dlp downloads it from.
+func TrackURL(trackID string) string {
+	if prefix, path, ok := strings.Cut(trackID, ":"); ok {
+		if source := sourceOf(prefix); source != nil {
+			return source.url(path)
+		}
+	}
+	return WatchURL(trackID)
+}
+
+// SourceName returns the name of the site a track comes from.
+func SourceName(trackID string) string {
+	if source := sourceOfTrack(trackID); source != nil {
+		return source.Name
+	}
+	return "YouTube"
+}
+
+// TrackExtractor returns the yt-dlp extractor of a track's URL, for tracks whose metadata
+// doesn't name it.
+func TrackExtractor(trackID string) string {
+	if source := sourceOfTrack(trackID); source != nil {
+		return source.Extractor
+	}
+	return "youtube"
+}
+
+// FileStem returns the name of a track's files without their extension. It is the ID itself
+// for YouTube videos; source-qualified IDs use "+" for ":" and "/", which never occur in
+// video IDs or in the paths of track IDs.
+func FileStem(trackID string) string {
+	return strings.NewReplacer(":", "+", "/", "+").Replace(trackID)
+}
+
+// TrackIDFromFileName returns the ID of the track an audio or .info.json file belongs to.
+func TrackIDFromFileName(name string) string {
+	stem := filepath.Base(name)
+	if trimmed, ok := strings.CutSuffix(stem, ".info.json"); ok {
+		stem = trimmed
+	} else {
+		stem = strings.TrimSuffix(stem, filepath.Ext(stem))
+	}
+
+	prefix, path, ok := strings.Cut(stem, "+")
+	if !ok || sourceOf(prefix) == nil {
+		return stem
+	}
+	return prefix + ":" + strings.ReplaceAll(path, "+", "/")
+}
+
+// validTrackPath reports whether every segment of a track path can be part of a file name.
+func validTrackPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if !pathSegmentPattern.MatchString(segment) || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceOf returns the source with the given ID prefix, or nil.
+func sourceOf(prefix string) *Source {
+	for i := range Sources {
+		if Sources[i].Prefix == prefix {
+			return &Sources[i]
+		}
+	}
+	return nil
+}
+
+// sourceOfTrack returns the source of a source-qualified track ID, or nil for YouTube videos.
+func sourceOfTrack(trackID string) *Source {
+	prefix, _, ok := strings.Cut(trackID, ":")
+	if !ok {
+		return nil
+	}
+	return sourceOf(prefix)
+}
+
+// hostOf returns the lower-cased host of u without port and "www.".
+func hostOf(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// pathSegments returns the non-empty segments of the path of u.
+func pathSegments(u *url.URL) []string {
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
@@ -2,19 +2,23 @@
 package yt
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 
 	config "ytpl/internal/config" // Alias for internal/config
 )
 
+// videoIDPattern matches a bare YouTube video ID.
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
 // TrackInfo represents metadata for a YouTube video or downloaded track.
 // Fields correspond to yt-dlp's --dump-json output.
 type TrackInfo struct {
@@ -27,42 +31,58 @@ type TrackInfo struct {
 	Uploader    string `json:"uploader"`      // The channel name (often the artist)
 	Creator     string `json:"creator"`       // Sometimes more specific artist info
 	Album       string `json:"album"`         // Album name from metadata
+	Artist      string `json:"artist,omitempty"`       // Artist from YouTube Music metadata
+	TrackNumber int    `json:"track_number,omitempty"` // Position of the track on its album
+	Thumbnail   string `json:"thumbnail,omitempty"`    // URL of the video thumbnail, used as album cover
 	ReleaseYear int    `json:"release_year"`  // Year of release from metadata
 	ViewCount   int64  `json:"view_count"`    // Number of views
 	UploadDate  string `json:"upload_date"`   // Upload date in YYYYMMDD format
+	Description string `json:"description,omitempty"` // Video description, shown in the search preview
+	FilePath    string `json:"file_path,omitempty"` // Path of the downloaded audio file, recorded in the library
+	Extractor   string `json:"extractor,omitempty"`    // yt-dlp extractor the track was downloaded with, e.g. "soundcloud"
+	OriginalURL string `json:"original_url,omitempty"` // URL the track was downloaded from
+	Track       string    `json:"track,omitempty"`      // Song title from YouTube Music metadata
+	Genre       string    `json:"genre,omitempty"`      // Genre from metadata
+	Comment     string    `json:"comment,omitempty"`    // Free-text comment set with 'ytpl edit'
+	Labels      []string  `json:"labels,omitempty"`     // Tags set by the user, unlike the uploader's Tags
+	Channel     string    `json:"channel,omitempty"`    // Name of the channel that uploaded the video
+	ChannelID   string    `json:"channel_id,omitempty"` // ID of the channel that uploaded the video
+	Tags        []string  `json:"tags,omitempty"`       // Tags set by the uploader
+	Categories  []string  `json:"categories,omitempty"` // YouTube categories, e.g. "Music"
+	Chapters    []Chapter `json:"chapters,omitempty"`   // Chapters of the video, e.g. the tracks of a full album upload
 	// Add more fields from yt-dlp's --dump-json output as needed, e.g.,
-	// Channel        string `json:"channel"`
-	// ChannelURL     string `json:"channel_url"`
 	// LikeCount      int    `json:"like_count"`
-	// Thumbnail      string `json:"thumbnail"`
+}
+
+// Chapter is a chapter of a video, as listed by yt-dlp.
+type Chapter struct {
+	StartTime float64 `json:"start_time"` // In seconds
+	EndTime   float64 `json:"end_time"`   // In seconds
+	Title     string  `json:"title"`
 }
 
 // SearchYouTube searches YouTube using yt-dlp and returns a list of TrackInfo.
 // This function is optimized for speed and only retrieves essential metadata.
 func SearchYouTube(cfg *config.Config, query string) ([]TrackInfo, error) {
+	return SearchYouTubeWithOptions(cfg, query, SearchOptions{})
+}
+
+// SearchYouTubeWithOptions searches YouTube like SearchYouTube, returning the page opts.Page
+// of the results with the filters and sort order of opts applied.
+// Filtered pages may have fewer than cfg.MaxSearchResults results.
+func SearchYouTubeWithOptions(cfg *config.Config, query string, opts SearchOptions) ([]TrackInfo, error) {
 	// Use cfg.MaxSearchResults for the number of results.
 	// config.LoadConfig ensures it's at least 1 and defaults to 10 if not set.
 	numResults := cfg.MaxSearchResults
 
-	cmdArgs := []string{
-		"--dump-json",
-		"--flat-playlist", // Fast search with minimal metadata
-		"--print-json",    // Print one JSON object per line
-		"--no-warnings",   // Ignore warnings
-		"--ignore-errors", // Continue on download errors, e.g. skip unavailable videos
-		"--match-filter", "!is_live & !is_upcoming", // Filter out live and upcoming videos
-		fmt.Sprintf("ytsearch%d:%s", numResults, query),
-	}
-
-	cmd := exec.Command(cfg.YtDlpPath, cmdArgs...)
-
-	// Redirect stderr to a buffer to prevent yt-dlp messages from interfering with TUI.
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	output, err := cmd.Output()
+	var output []byte
+	err := withRetry(context.Background(), func() error {
+		var err error
+		output, err = fetcher.Search(context.Background(), cfg, query, numResults, opts)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute yt-dlp search: %w\nStderr: %s", err, stderr.String())
+		return nil, err
 	}
 
 	var tracks []TrackInfo
@@ -85,54 +105,36 @@ func SearchYouTube(cfg *config.Config, query string) ([]TrackInfo, error) {
 		tracks = append(tracks, track)
 	}
 
-	return tracks, nil
+	return opts.filterAndSort(tracks), nil
 }
 
 // DownloadTrack downloads a YouTube video as an audio file.
 // It returns the path to the downloaded file and its full metadata.
 func DownloadTrack(cfg *config.Config, trackID string) (string, *TrackInfo, error) {
-	outputTemplate := filepath.Join(cfg.DownloadDir, "%(id)s.%(ext)s")
-
-	cmdArgs := []string{
-		"-o", outputTemplate,         // Output file template
-		"--extract-audio",            // Extract audio
-		"--audio-format", "mp3",      // Convert to MP3
-		"--audio-quality", "0",       // Best audio quality
-		"--restrict-filenames",       // Restrict filenames to ASCII and numeric
-		"--no-simulate",              // Ensure actual download occurs
-		"--print-json",               // Print final info JSON to stdout after download
-		"--no-progress",              // Suppress download progress bar for cleaner output
-		"--write-info-json",          // Save metadata to a .info.json file alongside the audio
-		"--embed-metadata",           // Explicitly embed metadata into the audio file
-		"--embed-thumbnail",          // Embed thumbnail into the audio file (optional, makes it larger)
-	}
-
-	// Add cookie options if configured, for age-restricted content
-	if cfg.CookieBrowser != "" {
-		cookieArg := fmt.Sprintf("--cookies-from-browser=%s", cfg.CookieBrowser)
-		if cfg.CookieProfile != "" {
-			cookieArg = fmt.Sprintf("--cookies-from-browser=%s:%s", cfg.CookieBrowser, cfg.CookieProfile)
-		}
-		cmdArgs = append(cmdArgs, cookieArg)
-	}
-
-	// URL argument must be passed after '--' for safety
-	cmdArgs = append(cmdArgs, "--", fmt.Sprintf("https://www.youtube.com/watch?v=%s", trackID))
-
-	cmd := exec.Command(cfg.YtDlpPath, cmdArgs...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr // Redirect yt-dlp's stderr to a buffer
+	return DownloadTrackWithProgress(context.Background(), cfg, trackID, nil)
+}
 
-	output, err := cmd.Output() // Execute the command and capture stdout
+// DownloadTrackWithProgress downloads a YouTube video as an audio file like DownloadTrack,
+// calling onProgress with yt-dlp's progress while the download runs.
+// If onProgress is nil, yt-dlp's progress output is disabled.
+// Cancelling ctx kills the running yt-dlp process.
+func DownloadTrackWithProgress(ctx context.Context, cfg *config.Config, trackID string, onProgress func(DownloadProgress)) (string, *TrackInfo, error) {
+	var output []byte
+	// Transient failures like rate limiting or network errors are retried with a growing delay
+	err := withRetry(ctx, func() error {
+		var err error
+		output, err = fetcher.Download(ctx, cfg, trackID, onProgress)
+		return err
+	})
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to execute yt-dlp download for ID %s: %w\nStderr: %s", trackID, err, stderr.String())
+		return "", nil, err
 	}
 
 	var downloadedTrackInfo TrackInfo
 	if err := json.Unmarshal(output, &downloadedTrackInfo); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to unmarshal yt-dlp download info json from stdout: %v\noutput: %s\n", err, string(output))
 		// Fallback: try to load from local .info.json if JSON output from yt-dlp was malformed
-		infoPath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.info.json", trackID))
+		infoPath := filepath.Join(cfg.DownloadDir, FileStem(trackID)+".info.json")
 		if data, readErr := os.ReadFile(infoPath); readErr == nil {
 			if json.Unmarshal(data, &downloadedTrackInfo) == nil {
 				fmt.Fprintf(os.Stderr, "successfully loaded info from local file: %s\n", infoPath)
@@ -142,17 +144,23 @@ func DownloadTrack(cfg *config.Config, trackID string) (string, *TrackInfo, erro
 
 	// Ensure basic info is populated even if JSON parsing fails or is incomplete
 	if downloadedTrackInfo.ID == "" {
-		downloadedTrackInfo.ID = trackID
 		downloadedTrackInfo.Title = fmt.Sprintf("Unknown Title (ID: %s)", trackID)
 	}
+	// yt-dlp reports the extractor's own ID, which differs from source-qualified track IDs
+	downloadedTrackInfo.ID = trackID
+	if downloadedTrackInfo.Extractor == "" {
+		downloadedTrackInfo.Extractor = TrackExtractor(trackID)
+	}
+	if downloadedTrackInfo.OriginalURL == "" {
+		downloadedTrackInfo.OriginalURL = TrackURL(trackID)
+	}
 
-	// Construct the likely downloaded file path (assuming mp3 extension based on --audio-format)
-	downloadedFilePath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.mp3", trackID))
-
-	// Verify the downloaded file exists on disk
-	if _, err := os.Stat(downloadedFilePath); os.IsNotExist(err) {
-		return "", nil, fmt.Errorf("downloaded file not found at expected path: %s", downloadedFilePath)
+	// Find the downloaded file, whose extension depends on the audio format and the source
+	downloadedFilePath, found := ResolveTrackPath(cfg, trackID, "")
+	if !found {
+		return "", nil, fmt.Errorf("downloaded file not found in %s for ID %s", cfg.DownloadDir, trackID)
 	}
+	downloadedTrackInfo.FilePath = downloadedFilePath
 
 	// Optimize the info.json file to remove unnecessary fields
 	if err := OptimizeInfoJSON(cfg, trackID); err != nil {
@@ -163,6 +171,147 @@ func DownloadTrack(cfg *config.Config, trackID string) (string, *TrackInfo, erro
 	return downloadedFilePath, &downloadedTrackInfo, nil
 }
 
+// FetchTrackInfo fetches the full metadata of a track without downloading it.
+func FetchTrackInfo(cfg *config.Config, trackID string) (*TrackInfo, error) {
+	var output []byte
+	err := withRetry(context.Background(), func() error {
+		var err error
+		output, err = fetcher.Metadata(context.Background(), cfg, trackID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var track TrackInfo
+	if err := json.Unmarshal(output, &track); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata of ID %s: %w", trackID, err)
+	}
+	track.ID = trackID
+	return &track, nil
+}
+
+// RefetchInfoJSON fetches the metadata of a stocked track again without downloading it and
+// replaces its .info.json, restoring fields that an earlier OptimizeInfoJSON removed.
+// The new .info.json is optimized again with the fields of cfg.MetadataFields.
+func RefetchInfoJSON(cfg *config.Config, trackID string) error {
+	var output []byte
+	err := withRetry(context.Background(), func() error {
+		var err error
+		output, err = fetcher.Metadata(context.Background(), cfg, trackID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !json.Valid(output) {
+		return fmt.Errorf("failed to parse metadata of ID %s", trackID)
+	}
+
+	infoPath := filepath.Join(cfg.DownloadDir, FileStem(trackID)+".info.json")
+	tempPath := infoPath + ".tmp"
+	if err := os.WriteFile(tempPath, output, 0644); err != nil {
+		return fmt.Errorf("failed to write info.json of ID %s: %w", trackID, err)
+	}
+	if err := os.Rename(tempPath, infoPath); err != nil {
+		return fmt.Errorf("failed to replace info.json of ID %s: %w", trackID, err)
+	}
+	return OptimizeInfoJSON(cfg, trackID)
+}
+
+// unavailableEntryTitles are the titles YouTube shows for playlist entries that can't be played.
+var unavailableEntryTitles = []string{"[Private video]", "[Deleted video]", "[Unavailable video]"}
+
+// FetchPlaylist lists the videos of a YouTube playlist or channel URL in playlist order.
+// Entries that are private or deleted upstream are skipped.
+// A channel URL without a tab lists the channel's uploads.
+func FetchPlaylist(cfg *config.Config, playlistURL string) ([]TrackInfo, error) {
+	listURL := playlistURL
+	if isChannelURL(playlistURL) {
+		listURL = strings.TrimSuffix(playlistURL, "/") + "/videos"
+	}
+
+	var output []byte
+	err := withRetry(context.Background(), func() error {
+		var err error
+		output, err = fetcher.Playlist(context.Background(), cfg, listURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TrackInfo
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry TrackInfo
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to unmarshal yt-dlp json line: %v\nline: %s\n", err, line)
+			continue
+		}
+		// Skip nested playlists and channel tabs, unplayable and duplicated entries
+		if !videoIDPattern.MatchString(entry.ID) || seen[entry.ID] || containsAny(entry.Title, unavailableEntryTitles) {
+			continue
+		}
+		seen[entry.ID] = true
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// FetchMix lists the videos of the mix YouTube generates for a video, starting with the video itself.
+func FetchMix(cfg *config.Config, trackID string) ([]TrackInfo, error) {
+	return FetchPlaylist(cfg, MixURL(trackID))
+}
+
+// isChannelURL reports whether u is the URL of a YouTube channel's home page, e.g.
+// https://www.youtube.com/@name or https://www.youtube.com/channel/UC...
+func isChannelURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || !strings.HasSuffix(strings.ToLower(parsed.Host), "youtube.com") {
+		return false
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && strings.HasPrefix(parts[0], "@"):
+		return true
+	case len(parts) == 2 && (parts[0] == "channel" || parts[0] == "c" || parts[0] == "user"):
+		return true
+	}
+	return false
+}
+
+// ParseVideoID extracts a YouTube video ID from a watch/short/youtu.be URL or a bare video ID.
+// The second return value is false if s is neither.
+func ParseVideoID(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+		switch {
+		case host == "youtu.be":
+			return strings.Trim(u.Path, "/"), strings.Trim(u.Path, "/") != ""
+		case strings.HasSuffix(host, "youtube.com"):
+			if id := u.Query().Get("v"); id != "" {
+				return id, true
+			}
+			for _, prefix := range []string{"/shorts/", "/embed/", "/live/"} {
+				if strings.HasPrefix(u.Path, prefix) {
+					id := strings.Trim(strings.TrimPrefix(u.Path, prefix), "/")
+					return id, id != ""
+				}
+			}
+		}
+		return "", false
+	}
+	if videoIDPattern.MatchString(s) {
+		return s, true
+	}
+	return "", false
+}
+
 // ListLocalTracks returns a list of all locally downloaded tracks, sorted by title.
 func ListLocalTracks(cfg *config.Config) ([]*TrackInfo, error) {
 	files, err := filepath.Glob(filepath.Join(cfg.DownloadDir, "*.info.json"))
@@ -172,7 +321,7 @@ func ListLocalTracks(cfg *config.Config) ([]*TrackInfo, error) {
 
 	var tracks []*TrackInfo
 	for _, file := range files {
-		trackID := strings.TrimSuffix(filepath.Base(file), ".info.json")
+		trackID := TrackIDFromFileName(file)
 		track, err := GetLocalTrackInfo(cfg, trackID)
 		if err != nil {
 			log.Printf("warning: failed to get info for %s: %v", trackID, err)
@@ -189,9 +338,22 @@ func ListLocalTracks(cfg *config.Config) ([]*TrackInfo, error) {
 	return tracks, nil
 }
 
-// OptimizeInfoJSON optimizes the info.json file by keeping only necessary fields
+// requiredMetadataFields are kept in .info.json files whatever cfg.MetadataFields says, as
+// the library can't do without them.
+var requiredMetadataFields = []string{"id", "title", "duration", "webpage_url", "extractor", "original_url"}
+
+// metadataFields returns the .info.json fields OptimizeInfoJSON keeps.
+func metadataFields(cfg *config.Config) []string {
+	fields := cfg.MetadataFields
+	if len(fields) == 0 {
+		fields = config.DefaultMetadataFields
+	}
+	return append(append([]string(nil), requiredMetadataFields...), fields...)
+}
+
+// OptimizeInfoJSON optimizes the info.json file by keeping only the fields of cfg.MetadataFields
 func OptimizeInfoJSON(cfg *config.Config, trackID string) error {
-	infoPath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.info.json", trackID))
+	infoPath := filepath.Join(cfg.DownloadDir, FileStem(trackID)+".info.json")
 	
 	// Read the existing info.json
 	data, err := os.ReadFile(infoPath)
@@ -205,30 +367,22 @@ func OptimizeInfoJSON(cfg *config.Config, trackID string) error {
 		return fmt.Errorf("failed to parse info.json: %w", err)
 	}
 
-	// Define the fields we want to keep
-	keepFields := []string{
-		"id",
-		"title",
-		"uploader",
-		"creator",
-		"duration",
-		"release_year",
-		"upload_date",
-		"webpage_url",
-	}
-
-	// Create a new map with only the fields we want to keep
+	// Keep the configured fields, or all of them for "*"
+	keepFields := metadataFields(cfg)
 	optimized := make(map[string]interface{})
 	for _, field := range keepFields {
+		if field == "*" {
+			optimized = infoMap
+			break
+		}
 		if value, exists := infoMap[field]; exists {
 			optimized[field] = value
 		}
 	}
 
-	// Add some essential fields if they're missing but we can derive them
-	if _, exists := optimized["id"]; !exists {
-		optimized["id"] = trackID
-	}
+	// Record the track ID rather than the extractor's own ID, which differs for
+	// source-qualified IDs
+	optimized["id"] = trackID
 
 	// Write the optimized JSON back to the file
 	optimizedJSON, err := json.MarshalIndent(optimized, "", "  ")
@@ -253,7 +407,7 @@ func OptimizeInfoJSON(cfg *config.Config, trackID string) error {
 // GetLocalTrackInfo reads metadata for a local track from its .info.json file.
 // It tries to populate as much information as possible from the .info.json.
 func GetLocalTrackInfo(cfg *config.Config, trackID string) (*TrackInfo, error) {
-	infoPath := filepath.Join(cfg.DownloadDir, fmt.Sprintf("%s.info.json", trackID))
+	infoPath := filepath.Join(cfg.DownloadDir, FileStem(trackID)+".info.json")
 	data, err := os.ReadFile(infoPath)
 	if err != nil {
 		// Return error but indicate it's a "soft" error, allowing caller to fallback
@@ -264,6 +418,7 @@ func GetLocalTrackInfo(cfg *config.Config, trackID string) (*TrackInfo, error) {
 	if err := json.Unmarshal(data, &track); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal info json for track %s: %w", trackID, err)
 	}
+	track.ID = trackID
 
 	// Populate missing fields if they are empty
 	if track.Title == "" {
@@ -0,0 +1,405 @@
+package yt_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ytpl/internal/config"
+	"ytpl/internal/yt"
+	"ytpl/internal/yt/ytfake"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	return &config.Config{
+		DownloadDir:      t.TempDir(),
+		MaxSearchResults: 10,
+	}
+}
+
+func TestSearchYouTube(t *testing.T) {
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	require.NoError(t, fake.AddSearchFixture("never gonna", filepath.Join("testdata", "search.jsonl")))
+	cfg := testConfig(t)
+
+	t.Run("parses results and skips channels", func(t *testing.T) {
+		tracks, err := yt.SearchYouTube(cfg, "never gonna")
+		require.NoError(t, err)
+		require.Len(t, tracks, 2, "the channel entry should be filtered out")
+
+		assert.Equal(t, "dQw4w9WgXcQ", tracks[0].ID)
+		assert.Equal(t, "Rick Astley - Never Gonna Give You Up (Official Music Video)", tracks[0].Title)
+		assert.Equal(t, 213.0, tracks[0].Duration)
+		assert.Equal(t, "Rick Astley", tracks[0].Uploader)
+		assert.Equal(t, int64(1700000000), tracks[0].ViewCount)
+		assert.Equal(t, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", tracks[0].WebpageURL)
+		assert.Equal(t, "yPYZpwSpKmA", tracks[1].ID)
+	})
+
+	t.Run("skips malformed lines", func(t *testing.T) {
+		fake.AddSearchLines("broken", `{"id": "aaaaaaaaaaa", "title": "ok", "duration": 60}`, `not json`)
+		tracks, err := yt.SearchYouTube(cfg, "broken")
+		require.NoError(t, err)
+		require.Len(t, tracks, 1)
+		assert.Equal(t, "ok", tracks[0].Title)
+	})
+
+	t.Run("limits results to max_search_results", func(t *testing.T) {
+		limited := *cfg
+		limited.MaxSearchResults = 1
+		tracks, err := yt.SearchYouTube(&limited, "never gonna")
+		require.NoError(t, err)
+		assert.Len(t, tracks, 1)
+	})
+
+	t.Run("applies filters and sorting", func(t *testing.T) {
+		fake.AddSearchResults("mix",
+			yt.TrackInfo{ID: "aaaaaaaaaaa", Title: "Short", Duration: 30, Uploader: "Artist - Topic", ViewCount: 10},
+			yt.TrackInfo{ID: "bbbbbbbbbbb", Title: "Song", Duration: 200, Uploader: "Artist - Topic", ViewCount: 20},
+			yt.TrackInfo{ID: "ccccccccccc", Title: "Song (Live)", Duration: 300, Uploader: "Artist - Topic", ViewCount: 30},
+			yt.TrackInfo{ID: "ddddddddddd", Title: "Song Cover", Duration: 210, Uploader: "Someone", ViewCount: 40},
+			yt.TrackInfo{ID: "eeeeeeeeeee", Title: "Song Remix", Duration: 220, Uploader: "artist - topic", ViewCount: 50},
+		)
+		tracks, err := yt.SearchYouTubeWithOptions(cfg, "mix", yt.SearchOptions{
+			MinDuration: 60,
+			MaxDuration: 250,
+			Channel:     "ARTIST",
+			Exclude:     regexp.MustCompile(`(?i)live`),
+			Sort:        yt.SortViews,
+		})
+		require.NoError(t, err)
+		require.Len(t, tracks, 2)
+		assert.Equal(t, "eeeeeeeeeee", tracks[0].ID, "results should be sorted by views")
+		assert.Equal(t, "bbbbbbbbbbb", tracks[1].ID)
+	})
+
+	t.Run("returns the requested page", func(t *testing.T) {
+		paged := *cfg
+		paged.MaxSearchResults = 2
+		tracks, err := yt.SearchYouTubeWithOptions(&paged, "mix", yt.SearchOptions{Page: 1})
+		require.NoError(t, err)
+		require.Len(t, tracks, 2)
+		assert.Equal(t, "ccccccccccc", tracks[0].ID)
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		tracks, err := yt.SearchYouTube(cfg, "nothing")
+		require.NoError(t, err)
+		assert.Empty(t, tracks)
+	})
+}
+
+func TestDownloadTrack(t *testing.T) {
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	cfg := testConfig(t)
+
+	info := yt.TrackInfo{
+		ID:         "dQw4w9WgXcQ",
+		Title:      "Never Gonna Give You Up",
+		Uploader:   "Rick Astley",
+		Duration:   3,
+		WebpageURL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+	}
+	fake.AddVideo(ytfake.Video{Info: info})
+
+	t.Run("downloads audio and metadata", func(t *testing.T) {
+		var progress []yt.DownloadProgress
+		path, track, err := yt.DownloadTrackWithProgress(t.Context(), cfg, info.ID, func(p yt.DownloadProgress) {
+			progress = append(progress, p)
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(cfg.DownloadDir, info.ID+".mp3"), path)
+		assert.FileExists(t, path)
+		assert.FileExists(t, filepath.Join(cfg.DownloadDir, info.ID+".info.json"))
+		assert.Equal(t, info.Title, track.Title)
+		assert.Equal(t, info.Uploader, track.Uploader)
+		require.NotEmpty(t, progress)
+		assert.Equal(t, 100.0, progress[len(progress)-1].Percent)
+	})
+
+	t.Run("falls back to info.json on malformed output", func(t *testing.T) {
+		broken := info
+		broken.ID = "yPYZpwSpKmA"
+		broken.Title = "Together Forever"
+		fake.AddVideo(ytfake.Video{Info: broken, PrintJSON: "not json"})
+
+		_, track, err := yt.DownloadTrack(cfg, broken.ID)
+		require.NoError(t, err)
+		assert.Equal(t, broken.ID, track.ID)
+		assert.Equal(t, "Together Forever", track.Title)
+	})
+
+	t.Run("unavailable video", func(t *testing.T) {
+		_, _, err := yt.DownloadTrack(cfg, "xxxxxxxxxxx")
+		require.Error(t, err)
+		assert.Equal(t, yt.KindUnavailable, yt.ErrorKindOf(err))
+		assert.False(t, yt.IsTransientError(err))
+		assert.NoFileExists(t, filepath.Join(cfg.DownloadDir, "xxxxxxxxxxx.mp3"))
+	})
+}
+
+func TestOptimizeInfoJSON(t *testing.T) {
+	cfg := testConfig(t)
+	infoPath := filepath.Join(cfg.DownloadDir, "dQw4w9WgXcQ.info.json")
+	full := map[string]interface{}{
+		"id":           "dQw4w9WgXcQ",
+		"title":        "Never Gonna Give You Up",
+		"uploader":     "Rick Astley",
+		"duration":     213,
+		"upload_date":  "20091025",
+		"webpage_url":  "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"formats":      []interface{}{map[string]interface{}{"format_id": "251"}},
+		"thumbnails":   []interface{}{map[string]interface{}{"url": "https://i.ytimg.com/vi/dQw4w9WgXcQ/hq720.jpg"}},
+		"description":  "The official video",
+		"album":        "Whenever You Need Somebody",
+		"track_number": 1,
+		"channel_id":   "UCuAXFkgsw1L7xaCfnd5JJOw",
+		"chapters":     []interface{}{map[string]interface{}{"start_time": 0, "end_time": 213, "title": "Intro"}},
+	}
+	optimize := func(t *testing.T) map[string]interface{} {
+		data, err := json.Marshal(full)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(infoPath, data, 0644))
+
+		require.NoError(t, yt.OptimizeInfoJSON(cfg, "dQw4w9WgXcQ"))
+
+		data, err = os.ReadFile(infoPath)
+		require.NoError(t, err)
+		var optimized map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &optimized))
+		return optimized
+	}
+
+	t.Run("keeps the default fields", func(t *testing.T) {
+		optimized := optimize(t)
+		assert.Equal(t, "Never Gonna Give You Up", optimized["title"])
+		assert.Equal(t, "20091025", optimized["upload_date"])
+		assert.NotContains(t, optimized, "formats")
+		assert.NotContains(t, optimized, "thumbnails")
+		assert.Equal(t, "The official video", optimized["description"])
+		assert.Equal(t, "Whenever You Need Somebody", optimized["album"])
+		assert.Equal(t, "UCuAXFkgsw1L7xaCfnd5JJOw", optimized["channel_id"])
+
+		track, err := yt.GetLocalTrackInfo(cfg, "dQw4w9WgXcQ")
+		require.NoError(t, err)
+		assert.Equal(t, "Rick Astley", track.Uploader)
+		assert.Equal(t, 213.0, track.Duration)
+		assert.Equal(t, []yt.Chapter{{StartTime: 0, EndTime: 213, Title: "Intro"}}, track.Chapters)
+	})
+
+	t.Run("keeps the configured and required fields", func(t *testing.T) {
+		cfg.MetadataFields = []string{"uploader"}
+		t.Cleanup(func() { cfg.MetadataFields = nil })
+
+		optimized := optimize(t)
+		assert.ElementsMatch(t, []string{"id", "title", "duration", "webpage_url", "uploader"}, keys(optimized))
+	})
+
+	t.Run("keeps every field for *", func(t *testing.T) {
+		cfg.MetadataFields = []string{"*"}
+		t.Cleanup(func() { cfg.MetadataFields = nil })
+
+		optimized := optimize(t)
+		assert.Len(t, optimized, len(full))
+	})
+}
+
+// keys returns the keys of a map in any order.
+func keys(m map[string]interface{}) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
+
+func TestRefetchInfoJSON(t *testing.T) {
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	cfg := testConfig(t)
+	fake.AddVideo(ytfake.Video{
+		Info: yt.TrackInfo{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up", Uploader: "Rick Astley", Duration: 213},
+		Extra: map[string]interface{}{
+			"formats": []interface{}{map[string]interface{}{"format_id": "251"}},
+			"tags":    []interface{}{"rick astley", "80s"},
+		},
+	})
+	infoPath := filepath.Join(cfg.DownloadDir, "dQw4w9WgXcQ.info.json")
+	require.NoError(t, os.WriteFile(infoPath, []byte(`{"id": "dQw4w9WgXcQ", "title": "Never Gonna Give You Up"}`), 0644))
+
+	require.NoError(t, yt.RefetchInfoJSON(cfg, "dQw4w9WgXcQ"))
+
+	track, err := yt.GetLocalTrackInfo(cfg, "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, "Rick Astley", track.Uploader)
+	assert.Equal(t, []string{"rick astley", "80s"}, track.Tags)
+	data, err := os.ReadFile(infoPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "formats")
+	assert.NoFileExists(t, filepath.Join(cfg.DownloadDir, "dQw4w9WgXcQ.mp3"), "refetching must not download")
+
+	t.Run("keeps the existing metadata on failure", func(t *testing.T) {
+		before, err := os.ReadFile(infoPath)
+		require.NoError(t, err)
+		fake.AddVideo(ytfake.Video{Info: yt.TrackInfo{ID: "dQw4w9WgXcQ"}, Err: &yt.Error{Kind: yt.KindUnavailable, Message: "gone"}})
+
+		assert.Error(t, yt.RefetchInfoJSON(cfg, "dQw4w9WgXcQ"))
+		after, err := os.ReadFile(infoPath)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
+
+func TestFetchTrackInfo(t *testing.T) {
+	fake := ytfake.New()
+	ytfake.Use(t, fake)
+	cfg := testConfig(t)
+	fake.AddVideo(ytfake.Video{Info: yt.TrackInfo{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up", Duration: 213}})
+
+	track, err := yt.FetchTrackInfo(cfg, "dQw4w9WgXcQ")
+	require.NoError(t, err)
+	assert.Equal(t, "Never Gonna Give You Up", track.Title)
+	assert.NoFileExists(t, filepath.Join(cfg.DownloadDir, "dQw4w9WgXcQ.mp3"), "metadata fetching must not download")
+}
+
+func TestResolveTrackPath(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AudioFormat = "opus"
+	touch := func(name string) string {
+		path := filepath.Join(cfg.DownloadDir, name)
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+		return path
+	}
+
+	t.Run("finds a file of any supported format", func(t *testing.T) {
+		m4a := touch("aaaaaaaaaaa.m4a")
+		path, found := yt.ResolveTrackPath(cfg, "aaaaaaaaaaa", "")
+		assert.True(t, found)
+		assert.Equal(t, m4a, path)
+	})
+
+	t.Run("prefers the configured format", func(t *testing.T) {
+		touch("bbbbbbbbbbb.mp3")
+		opus := touch("bbbbbbbbbbb.opus")
+		path, found := yt.ResolveTrackPath(cfg, "bbbbbbbbbbb", "")
+		assert.True(t, found)
+		assert.Equal(t, opus, path)
+	})
+
+	t.Run("uses the stored path, also after the download directory moved", func(t *testing.T) {
+		mp3 := touch("ccccccccccc.mp3")
+		touch("ccccccccccc.opus")
+		path, found := yt.ResolveTrackPath(cfg, "ccccccccccc", mp3)
+		assert.True(t, found)
+		assert.Equal(t, mp3, path)
+
+		path, found = yt.ResolveTrackPath(cfg, "ccccccccccc", "/old/stock/ccccccccccc.mp3")
+		assert.True(t, found)
+		assert.Equal(t, mp3, path)
+	})
+
+	t.Run("missing track", func(t *testing.T) {
+		path, found := yt.ResolveTrackPath(cfg, "ddddddddddd", "")
+		assert.False(t, found)
+		assert.Equal(t, filepath.Join(cfg.DownloadDir, "ddddddddddd.opus"), path)
+	})
+}
+
+func TestScoreMatch(t *testing.T) {
+	query := yt.MatchQuery{Query: "Rick Astley - Never Gonna Give You Up", Duration: 213}
+	original := yt.TrackInfo{ID: "a", Title: "Never Gonna Give You Up", Uploader: "Rick Astley - Topic", Duration: 214}
+	video := yt.TrackInfo{ID: "b", Title: "Rick Astley - Never Gonna Give You Up (Official Music Video)", Uploader: "Rick Astley", Duration: 213}
+	live := yt.TrackInfo{ID: "c", Title: "Rick Astley - Never Gonna Give You Up (Live)", Uploader: "Rick Astley", Duration: 260}
+	cover := yt.TrackInfo{ID: "d", Title: "Never Gonna Give You Up (cover)", Uploader: "Some Band", Duration: 210}
+	loop := yt.TrackInfo{ID: "e", Title: "Never Gonna Give You Up 1 hour loop", Uploader: "Loops", Duration: 3600}
+	slowed := yt.TrackInfo{ID: "f", Title: "never gonna give you up (slowed + reverb)", Uploader: "edits", Duration: 250}
+	other := yt.TrackInfo{ID: "g", Title: "Rick Astley - Together Forever", Uploader: "Rick Astley", Duration: 205}
+
+	t.Run("ranks the original recording above variants", func(t *testing.T) {
+		scores := make(map[string]int)
+		for _, track := range []yt.TrackInfo{original, video, live, cover, loop, slowed, other} {
+			scores[track.ID] = yt.ScoreMatch(track, query)
+			assert.GreaterOrEqual(t, scores[track.ID], 0)
+			assert.LessOrEqual(t, scores[track.ID], 100)
+		}
+		assert.Greater(t, scores["a"], scores["b"], "topic channel over music video")
+		for _, id := range []string{"c", "d", "e", "f", "g"} {
+			assert.Greater(t, scores["b"], scores[id], "music video over %s", id)
+		}
+	})
+
+	t.Run("doesn't penalize variants the query asks for", func(t *testing.T) {
+		liveQuery := yt.MatchQuery{Query: "Rick Astley Never Gonna Give You Up live"}
+		assert.Greater(t, yt.ScoreMatch(live, liveQuery), yt.ScoreMatch(live, yt.MatchQuery{Query: "Rick Astley Never Gonna Give You Up"}))
+	})
+
+	t.Run("rewards a close duration", func(t *testing.T) {
+		near := yt.ScoreMatch(video, query)
+		far := yt.ScoreMatch(video, yt.MatchQuery{Query: query.Query, Duration: 400})
+		assert.Greater(t, near, far)
+	})
+
+	t.Run("matches words in scripts without spaces", func(t *testing.T) {
+		track := yt.TrackInfo{Title: "米津玄師 MV「Lemon」", Uploader: "米津玄師"}
+		assert.Greater(t, yt.ScoreMatch(track, yt.MatchQuery{Query: "米津玄師 lemon"}), 60)
+	})
+
+	t.Run("best match", func(t *testing.T) {
+		idx, score := yt.BestMatch([]yt.TrackInfo{live, video, original}, query)
+		assert.Equal(t, 2, idx)
+		assert.Equal(t, yt.ScoreMatch(original, query), score)
+
+		idx, _ = yt.BestMatch(nil, query)
+		assert.Equal(t, -1, idx)
+	})
+}
+
+func TestParseTrackRef(t *testing.T) {
+	for _, tc := range []struct {
+		ref string
+		id  string
+		url string
+	}{
+		{"dQw4w9WgXcQ", "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"yt:dQw4w9WgXcQ", "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://soundcloud.com/some-artist/a-track?in=x", "sc:some-artist/a-track", "https://soundcloud.com/some-artist/a-track"},
+		{"sc:some-artist/a-track", "sc:some-artist/a-track", "https://soundcloud.com/some-artist/a-track"},
+		{"https://someband.bandcamp.com/track/song-title", "bc:someband/song-title", "https://someband.bandcamp.com/track/song-title"},
+		{"https://vimeo.com/123456789", "vm:123456789", "https://vimeo.com/123456789"},
+		{"https://example.com/music/song.mp3", "url:example.com/music/song.mp3", "https://example.com/music/song.mp3"},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			id, ok := yt.ParseTrackRef(tc.ref)
+			require.True(t, ok)
+			assert.Equal(t, tc.id, id)
+			assert.Equal(t, tc.url, yt.TrackURL(id))
+
+			// Files are named so that the ID can be recovered
+			assert.NotContains(t, yt.FileStem(id), "/")
+			assert.NotContains(t, yt.FileStem(id), ":")
+			assert.Equal(t, id, yt.TrackIDFromFileName(yt.FileStem(id)+".opus"))
+			assert.Equal(t, id, yt.TrackIDFromFileName(yt.FileStem(id)+".info.json"))
+		})
+	}
+
+	for _, ref := range []string{
+		"not an id",
+		"https://www.youtube.com/playlist?list=PL123",
+		"https://soundcloud.com/some-artist/sets/an-album",
+		"https://someband.bandcamp.com/album/an-album",
+		"https://example.com/watch?id=1",
+		"sc:../../etc",
+		"xx:something",
+	} {
+		_, ok := yt.ParseTrackRef(ref)
+		assert.False(t, ok, ref)
+	}
+}
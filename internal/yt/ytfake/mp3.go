@@ -0,0 +1,26 @@
+// internal/yt/ytfake/mp3.go
+package ytfake
+
+import "bytes"
+
+const (
+	// mp3FrameHeader is the header of an MPEG-1 Layer III frame at 128 kbit/s and 44.1 kHz, joint stereo.
+	mp3FrameHeader = "\xff\xfb\x90\x44"
+	// mp3FrameSize is the size of such a frame without padding: 144 * 128000 / 44100.
+	mp3FrameSize = 417
+	// mp3FramesPerSecond is the number of frames per second, each frame holding 1152 samples.
+	mp3FramesPerSecond = 44100.0 / 1152
+)
+
+// SilentMP3 returns a valid mp3 stream of silence lasting about the given number of seconds.
+// Frames with zeroed side information and main data decode to silence.
+func SilentMP3(seconds float64) []byte {
+	frames := int(seconds * mp3FramesPerSecond)
+	if frames < 1 {
+		frames = 1
+	}
+
+	frame := make([]byte, mp3FrameSize)
+	copy(frame, mp3FrameHeader)
+	return bytes.Repeat(frame, frames)
+}
@@ -0,0 +1,243 @@
+// internal/yt/ytfake/ytfake.go
+
+// Package ytfake provides a yt.Fetcher serving fixtures instead of calling YouTube,
+// so that tests run without network access or yt-dlp.
+package ytfake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	config "ytpl/internal/config" // Alias for internal/config
+	"ytpl/internal/yt"
+)
+
+// Video is a video served by the fake.
+type Video struct {
+	Info yt.TrackInfo
+	// Extra holds additional .info.json fields, e.g. to check that they are optimized away.
+	Extra map[string]interface{}
+	// PrintJSON replaces the --print-json output of the download if not empty,
+	// e.g. to simulate malformed output.
+	PrintJSON string
+	// Err is returned by Download and Metadata instead of serving the video, if set.
+	Err error
+}
+
+// Fetcher is a yt.Fetcher serving fixtures. It is safe for concurrent use.
+type Fetcher struct {
+	mu            sync.Mutex
+	searchResults map[string][]string // Query -> --dump-json lines
+	videos        map[string]Video    // Track ID -> video
+	playlists     map[string][]string // Playlist URL -> --dump-json lines
+	calls         []string
+}
+
+// New returns a Fetcher without any fixtures.
+// Searches without results return no lines, and unknown videos fail as unavailable.
+func New() *Fetcher {
+	return &Fetcher{
+		searchResults: make(map[string][]string),
+		videos:        make(map[string]Video),
+		playlists:     make(map[string][]string),
+	}
+}
+
+// Use makes f the Fetcher of the yt package until the test finishes.
+func Use(t testing.TB, f *Fetcher) {
+	t.Helper()
+	previous := yt.SetFetcher(f)
+	t.Cleanup(func() { yt.SetFetcher(previous) })
+}
+
+// AddSearchLines adds raw --dump-json lines to the results of query.
+// YouTube Music results are added to the query yt.MusicSearchURL(query, section).
+func (f *Fetcher) AddSearchLines(query string, lines ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searchResults[query] = append(f.searchResults[query], lines...)
+}
+
+// AddSearchResults adds tracks to the results of query.
+func (f *Fetcher) AddSearchResults(query string, tracks ...yt.TrackInfo) {
+	for _, track := range tracks {
+		data, err := json.Marshal(track)
+		if err != nil {
+			panic(fmt.Sprintf("ytfake: failed to marshal search result: %v", err))
+		}
+		f.AddSearchLines(query, string(data))
+	}
+}
+
+// AddSearchFixture adds the lines of a .jsonl fixture file to the results of query.
+func (f *Fetcher) AddSearchFixture(query, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read search fixture: %w", err)
+	}
+	f.AddSearchLines(query, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+	return nil
+}
+
+// AddVideo makes a video available for download and metadata fetching.
+func (f *Fetcher) AddVideo(video Video) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.videos[video.Info.ID] = video
+}
+
+// SetPlaylist sets the entries of a playlist URL, replacing the previous ones.
+// Use the URL requested by the yt package, e.g. with "/videos" appended for channels.
+func (f *Fetcher) SetPlaylist(url string, tracks ...yt.TrackInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		data, err := json.Marshal(track)
+		if err != nil {
+			panic(fmt.Sprintf("ytfake: failed to marshal playlist entry: %v", err))
+		}
+		lines = append(lines, string(data))
+	}
+	f.playlists[url] = lines
+}
+
+// Calls returns the calls made so far, as "search:<query>" ("search:<query> (page N)" after
+// the first page), "download:<id>", "metadata:<id>" or "playlist:<url>".
+func (f *Fetcher) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Search implements yt.Fetcher.
+// Only the requested page of results is returned; filters and sorting are left to the caller.
+func (f *Fetcher) Search(ctx context.Context, cfg *config.Config, query string, count int, opts yt.SearchOptions) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if opts.Music != "" {
+		query = yt.MusicSearchURL(query, opts.Music)
+	}
+	call := "search:" + query
+	if opts.Page > 0 {
+		call += fmt.Sprintf(" (page %d)", opts.Page+1)
+	}
+	f.calls = append(f.calls, call)
+
+	lines := f.searchResults[query]
+	start := min(count*opts.Page, len(lines))
+	lines = lines[start:min(start+count, len(lines))]
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// Download implements yt.Fetcher.
+// It writes a silent mp3 matching the video's duration and its .info.json to cfg.DownloadDir,
+// named after yt.FileStem(trackID).
+func (f *Fetcher) Download(ctx context.Context, cfg *config.Config, trackID string, onProgress func(yt.DownloadProgress)) ([]byte, error) {
+	video, err := f.video("download", trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	audio := SilentMP3(video.Info.Duration)
+	if onProgress != nil {
+		total := int64(len(audio))
+		onProgress(yt.DownloadProgress{Percent: 0, TotalBytes: total, ETA: -1})
+		onProgress(yt.DownloadProgress{Percent: 100, DownloadedBytes: total, TotalBytes: total, ETA: 0})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("download of ID %s cancelled: %w", trackID, err)
+	}
+
+	if err := os.MkdirAll(cfg.DownloadDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(cfg.DownloadDir, yt.FileStem(trackID)+".mp3"), audio, 0644); err != nil {
+		return nil, err
+	}
+	infoJSON, err := video.infoJSON()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(cfg.DownloadDir, yt.FileStem(trackID)+".info.json"), infoJSON, 0644); err != nil {
+		return nil, err
+	}
+
+	if video.PrintJSON != "" {
+		return []byte(video.PrintJSON), nil
+	}
+	return infoJSON, nil
+}
+
+// Metadata implements yt.Fetcher.
+func (f *Fetcher) Metadata(ctx context.Context, cfg *config.Config, trackID string) ([]byte, error) {
+	video, err := f.video("metadata", trackID)
+	if err != nil {
+		return nil, err
+	}
+	return video.infoJSON()
+}
+
+// Playlist implements yt.Fetcher.
+// Unknown playlists fail as unavailable.
+func (f *Fetcher) Playlist(ctx context.Context, cfg *config.Config, url string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "playlist:"+url)
+
+	lines, ok := f.playlists[url]
+	if !ok {
+		return nil, &yt.Error{
+			Kind:    yt.KindUnavailable,
+			Message: fmt.Sprintf("failed to list playlist %s: ERROR: [youtube:tab] The playlist does not exist.", url),
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// video records a call and returns the video served for it.
+func (f *Fetcher) video(call, trackID string) (Video, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call+":"+trackID)
+
+	video, ok := f.videos[trackID]
+	if !ok {
+		return Video{}, &yt.Error{
+			Kind:    yt.KindUnavailable,
+			Message: fmt.Sprintf("failed to %s ID %s: ERROR: [youtube] %s: Video unavailable", call, trackID, trackID),
+		}
+	}
+	if video.Err != nil {
+		return Video{}, video.Err
+	}
+	return video, nil
+}
+
+// infoJSON returns the full metadata of the video, as written by yt-dlp's --write-info-json.
+func (v Video) infoJSON() ([]byte, error) {
+	data, err := json.Marshal(v.Info)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range v.Extra {
+		fields[key] = value
+	}
+	return json.Marshal(fields)
+}